@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dev-in-black/connector-http/internal/cli"
+)
+
+// runLint checks settings offline and prints the effective settings
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	var (
+		configPath  = flags.String("config", "", "Conduit pipeline file or YAML map of settings of the HTTP destination (required)")
+		connectorID = flags.String("connector", "", "ID of the HTTP destination in a pipeline file with several")
+		quiet       = flags.Bool("quiet", false, "Only print problems, not the effective settings")
+	)
+	_ = flags.Parse(args)
+	if *configPath == "" {
+		return fmt.Errorf("-config is required")
+	}
+
+	// Validation logs are reported as problems instead
+	ctx, stop, err := cli.NewContext("disabled")
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	settings, err := cli.LoadSettings(*configPath, *connectorID)
+	if err != nil {
+		return err
+	}
+
+	var errs int
+	for _, finding := range cli.Lint(ctx, settings) {
+		level := "error"
+		if finding.Warning {
+			level = "warning"
+		} else {
+			errs++
+		}
+		if finding.Param != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", level, finding.Param, finding.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", level, finding.Message)
+		}
+	}
+
+	if !*quiet {
+		effective := cli.EffectiveSettings(settings)
+		keys := make([]string, 0, len(effective))
+		for key := range effective {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("%s: %s\n", key, effective[key])
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%d problems found", errs)
+	}
+	return nil
+}
+
+// runSpec prints the parameters of the destination
+func runSpec(args []string) error {
+	flags := flag.NewFlagSet("spec", flag.ExitOnError)
+	format := flags.String("format", "text", "Output format: text or json")
+	_ = flags.Parse(args)
+
+	params := cli.Params()
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(params)
+	case "text":
+	default:
+		return fmt.Errorf("invalid format: %s (must be text or json)", *format)
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		param := params[name]
+		fmt.Printf("%s (%s)\n", name, param.Type)
+		if param.Default != "" {
+			fmt.Printf("  default: %s\n", param.Default)
+		}
+		for _, v := range param.Validations {
+			if value := v.Value(); value != "" {
+				fmt.Printf("  %s: %s\n", v.Type(), value)
+			} else {
+				fmt.Printf("  %s\n", v.Type())
+			}
+		}
+		if param.Description != "" {
+			fmt.Printf("  %s\n", strings.ReplaceAll(param.Description, "\n", "\n  "))
+		}
+	}
+	return nil
+}
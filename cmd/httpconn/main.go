@@ -0,0 +1,219 @@
+// Command httpconn tests settings of the HTTP destination before deploying a
+// pipeline. send, the default, sends a sample record through the destination,
+// with the authentication, templates, validation and retries of its settings,
+// and prints the requests sent and the responses received. lint checks the
+// settings offline and prints the effective settings, spec prints the
+// parameters of the destination.
+//
+//	httpconn -config pipeline.yaml -record record.json
+//	echo '{"name":"Jane"}' | httpconn send -config settings.yaml -metadata opencdc.collection=users
+//	httpconn lint -config pipeline.yaml
+//	httpconn spec -format json
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+
+	"github.com/dev-in-black/connector-http/internal/cli"
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "httpconn:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "send":
+			return runSend(args[1:])
+		case "lint":
+			return runLint(args[1:])
+		case "spec":
+			return runSpec(args[1:])
+		}
+	}
+	return runSend(args)
+}
+
+// runSend sends a sample record and prints its requests and responses
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ExitOnError)
+	metadata := opencdc.Metadata{}
+	var (
+		configPath  = flags.String("config", "", "Conduit pipeline file or YAML map of settings of the HTTP destination (required)")
+		connectorID = flags.String("connector", "", "ID of the HTTP destination in a pipeline file with several")
+		recordPath  = flags.String("record", "-", "File of the record, - for stdin: the OpenCDC JSON of a record, or its payload")
+		logLevel    = flags.String("log-level", "warn", "Log level of the destination: debug, info, warn or error")
+	)
+	flags.Func("metadata", "Metadata of the record as key=value, repeatable", func(entry string) error {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("expected key=value")
+		}
+		metadata[key] = value
+		return nil
+	})
+	_ = flags.Parse(args)
+	if *configPath == "" {
+		return fmt.Errorf("-config is required")
+	}
+
+	ctx, stop, err := cli.NewContext(*logLevel)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	record, err := readRecord(*recordPath)
+	if err != nil {
+		return err
+	}
+	if record.Metadata == nil {
+		record.Metadata = opencdc.Metadata{}
+	}
+	for key, value := range metadata {
+		record.Metadata[key] = value
+	}
+
+	settings, err := cli.LoadSettings(*configPath, *connectorID)
+	if err != nil {
+		return err
+	}
+
+	// Every attempt is captured, with the redaction of the settings
+	dir, err := os.MkdirTemp("", "httpconn")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	capturePath := filepath.Join(dir, "capture.jsonl")
+	settings["capture.sampleRate"] = "1"
+	settings["capture.path"] = capturePath
+	if interceptors := settings["interceptors"]; interceptors != "" && !strings.Contains(interceptors, http.InterceptorCapture) {
+		settings["interceptors"] = interceptors + "," + http.InterceptorCapture
+	}
+
+	dst, err := cli.OpenDestination(ctx, settings)
+	if err != nil {
+		return err
+	}
+	_, writeErr := dst.Write(ctx, []opencdc.Record{record})
+	_ = dst.Teardown(context.WithoutCancel(ctx))
+
+	if err := printExchanges(os.Stdout, capturePath); err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return fmt.Errorf("record failed: %w", writeErr)
+	}
+	fmt.Println("record delivered")
+	return nil
+}
+
+// readRecord reads the record at path, - for stdin. Input that isn't the
+// OpenCDC JSON of a record is the payload of a created record.
+func readRecord(path string) (opencdc.Record, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return opencdc.Record{}, fmt.Errorf("failed to read record: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return opencdc.Record{Operation: opencdc.OperationCreate, Payload: opencdc.Change{After: opencdc.RawData(data)}}, nil
+	}
+	if _, ok := fields["payload"]; ok {
+		return cli.DecodeRecord(data)
+	}
+	var payload opencdc.StructuredData
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return opencdc.Record{}, fmt.Errorf("invalid record: %w", err)
+	}
+	return opencdc.Record{Operation: opencdc.OperationCreate, Payload: opencdc.Change{After: payload}}, nil
+}
+
+// printExchanges prints the captured exchanges, requests prefixed with > and
+// responses with <
+func printExchanges(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read captured requests: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	for attempt := 1; scanner.Scan(); attempt++ {
+		var ex http.Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return fmt.Errorf("invalid captured request: %w", err)
+		}
+
+		fmt.Fprintf(w, "* Attempt %d at %s\n", attempt, ex.Time.Format("15:04:05.000"))
+		fmt.Fprintf(w, "> %s %s\n", ex.Request.Method, ex.Request.URL)
+		printHeaders(w, ">", ex.Request.Headers)
+		printBody(w, ex.Request.Body, ex.Request.BodyEncoding, ex.Request.BodyTruncated)
+		switch {
+		case ex.Response != nil:
+			fmt.Fprintf(w, "< %d\n", ex.Response.Status)
+			printHeaders(w, "<", ex.Response.Headers)
+			printBody(w, ex.Response.Body, ex.Response.BodyEncoding, ex.Response.BodyTruncated)
+		case ex.Error != "":
+			fmt.Fprintf(w, "! %s\n\n", ex.Error)
+		}
+	}
+	return scanner.Err()
+}
+
+// printHeaders prints the headers in the order of their names
+func printHeaders(w io.Writer, prefix string, headers map[string][]string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, name, value)
+		}
+	}
+}
+
+// printBody prints a body after an empty line, binary bodies base64-encoded
+func printBody(w io.Writer, body, encoding string, truncated bool) {
+	fmt.Fprintln(w)
+	if body != "" {
+		fmt.Fprintln(w, body)
+		if encoding != "" {
+			fmt.Fprintf(w, "[%s]\n", encoding)
+		}
+		if truncated {
+			fmt.Fprintln(w, "[truncated]")
+		}
+		fmt.Fprintln(w)
+	}
+}
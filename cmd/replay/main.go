@@ -0,0 +1,111 @@
+// Command replay re-sends the records of a dead-letter queue through the HTTP
+// destination, with the client, retries and authentication of its settings.
+// Replayed entries are marked in a state file and skipped by later runs.
+//
+//	replay -config pipeline.yaml -input dlq.jsonl
+//	replay -config pipeline.yaml -brokers localhost:9092 -topic http-dlq
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dev-in-black/connector-http/internal/cli"
+	"github.com/dev-in-black/connector-http/internal/kafka"
+)
+
+// saslPasswordEnv is the environment variable holding the SASL password of the
+// dead-letter topic, kept off the command line
+const saslPasswordEnv = "KAFKA_SASL_PASSWORD"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		configPath    = flag.String("config", "", "Conduit pipeline file or YAML map of settings of the HTTP destination (required)")
+		connectorID   = flag.String("connector", "", "ID of the HTTP destination in a pipeline file with several")
+		input         = flag.String("input", "", "NDJSON file of dead-letter records")
+		brokers       = flag.String("brokers", "", "Comma-separated Kafka brokers of the dead-letter topic")
+		topic         = flag.String("topic", "", "Kafka dead-letter topic")
+		saslMechanism = flag.String("sasl-mechanism", "", "SASL mechanism of the brokers: PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, the password is read from "+saslPasswordEnv)
+		saslUsername  = flag.String("sasl-username", "", "SASL username of the brokers")
+		tlsEnabled    = flag.Bool("tls", false, "Connect to the brokers with TLS")
+		statePath     = flag.String("state", "", "File replayed entries are marked in (default <input>.replayed or <topic>.replayed)")
+		logLevel      = flag.String("log-level", "info", "Log level of the destination: debug, info, warn or error")
+	)
+	flag.Parse()
+
+	switch {
+	case *configPath == "":
+		return fmt.Errorf("-config is required")
+	case (*input == "") == (*topic == ""):
+		return fmt.Errorf("either -input or -topic is required")
+	case *topic != "" && *brokers == "":
+		return fmt.Errorf("-topic requires -brokers")
+	}
+	if *statePath == "" {
+		*statePath = *input + ".replayed"
+		if *topic != "" {
+			*statePath = *topic + ".replayed"
+		}
+	}
+
+	ctx, stop, err := cli.NewContext(*logLevel)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	settings, err := cli.LoadSettings(*configPath, *connectorID)
+	if err != nil {
+		return err
+	}
+	state, err := openState(*statePath)
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+
+	dst, err := cli.OpenDestination(ctx, settings)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dst.Teardown(context.WithoutCancel(ctx)) }()
+
+	r := &replayer{dst: dst, state: state}
+	if *input != "" {
+		err = readFile(*input, func(id string, data []byte) error {
+			return r.replay(ctx, id, data)
+		})
+	} else {
+		err = kafka.ReadTopic(ctx, kafka.Config{
+			Brokers:       strings.Split(*brokers, ","),
+			Topic:         *topic,
+			ClientID:      "conduit-http-replay",
+			SASLEnabled:   *saslMechanism != "",
+			SASLMechanism: *saslMechanism,
+			SASLUsername:  *saslUsername,
+			SASLPassword:  os.Getenv(saslPasswordEnv),
+			TLSEnabled:    *tlsEnabled,
+		}, func(msg kafka.Message) error {
+			return r.replay(ctx, fmt.Sprintf("%d/%d", msg.Partition, msg.Offset), msg.Value)
+		})
+	}
+
+	fmt.Printf("replayed %d, skipped %d already replayed, failed %d\n", r.replayed, r.skipped, r.failed)
+	if err != nil {
+		return err
+	}
+	if r.failed > 0 {
+		return fmt.Errorf("%d entries failed, run again to retry them", r.failed)
+	}
+	return nil
+}
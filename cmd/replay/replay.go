@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/cli"
+	"github.com/dev-in-black/connector-http/internal/jsonl"
+)
+
+// maxEntrySize bounds the length of a line of the input file
+const maxEntrySize = 64 << 20
+
+// replayer writes the records of dead-letter entries to the destination
+type replayer struct {
+	dst   sdk.Destination
+	state *state
+
+	replayed, skipped, failed int
+}
+
+// replay writes the record of an entry to the destination, unless it was
+// replayed before, and marks it replayed. Entries that fail are counted and
+// left for the next run, only failures to mark them stop the replay.
+func (r *replayer) replay(ctx context.Context, id string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.state.replayed[id] {
+		r.skipped++
+		return nil
+	}
+	logger := sdk.Logger(ctx).With().Str("entry", id).Logger()
+
+	record, err := decodeEntry(data)
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid dead-letter entry")
+		r.failed++
+		return nil
+	}
+	if _, err := r.dst.Write(ctx, []opencdc.Record{record}); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Error().Err(err).Msg("Failed to replay record")
+		r.failed++
+		return nil
+	}
+	if err := r.state.mark(id); err != nil {
+		return err
+	}
+	logger.Info().Msg("Record replayed")
+	r.replayed++
+	return nil
+}
+
+// decodeEntry returns the record of a dead-letter entry, the OpenCDC JSON of
+// a record or a Conduit DLQ record wrapping it
+func decodeEntry(data []byte) (opencdc.Record, error) {
+	record, err := cli.DecodeRecord(data)
+	if err != nil {
+		return opencdc.Record{}, err
+	}
+	if _, ok := record.Metadata[opencdc.MetadataConduitDLQNackError]; !ok {
+		return record, nil
+	}
+
+	// Conduit DLQ records hold the failed record as structured payload
+	failed, ok := record.Payload.After.(opencdc.StructuredData)
+	if !ok {
+		return opencdc.Record{}, fmt.Errorf("DLQ record has no structured payload holding the failed record")
+	}
+	data, err = json.Marshal(failed)
+	if err != nil {
+		return opencdc.Record{}, fmt.Errorf("failed to encode failed record: %w", err)
+	}
+	return cli.DecodeRecord(data)
+}
+
+// readFile calls fn with the non-empty lines of the NDJSON file at path,
+// identified by their line number
+func readFile(path string, fn func(id string, data []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open input: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn("line "+strconv.Itoa(line), scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return nil
+}
+
+// mark is an entry of the state file, a replayed entry
+type mark struct {
+	Entry string    `json:"entry"`
+	Time  time.Time `json:"time"`
+}
+
+// state is the file replayed entries are marked in
+type state struct {
+	file     *jsonl.File
+	replayed map[string]bool
+}
+
+// openState opens the state file at path, creating it if needed, and reads
+// the entries replayed before
+func openState(path string) (*state, error) {
+	// Marks are synced before the next entry is replayed
+	file, _, err := jsonl.Open(path, jsonl.SyncPolicy{Lines: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state: %w", err)
+	}
+	s := &state{file: file, replayed: make(map[string]bool)}
+
+	dec := json.NewDecoder(file.Reader())
+	for {
+		var m mark
+		if err := dec.Decode(&m); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("invalid state %s: %w", path, err)
+		}
+		s.replayed[m.Entry] = true
+	}
+	return s, nil
+}
+
+// mark marks an entry replayed
+func (s *state) mark(id string) error {
+	data, err := json.Marshal(mark{Entry: id, Time: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := s.file.WriteLine(data); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	s.replayed[id] = true
+	return nil
+}
+
+// Close closes the state file
+func (s *state) Close() error {
+	return s.file.Close()
+}
@@ -1,28 +1,24 @@
+//go:generate conn-sdk-cli specgen
+
 package http
 
 import (
+	_ "embed"
+
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/dev-in-black/connector-http/destination"
+	"github.com/dev-in-black/connector-http/internal/version"
 )
 
+// specs is the connector specification, its parameters are generated from
+// the destination Config
+//
+//go:embed connector.yaml
+var specs string
+
 // Connector is the main entry point for the HTTP connector
 var Connector = sdk.Connector{
-	NewSpecification: Specification,
+	NewSpecification: sdk.YAMLSpecification(specs, version.Version),
 	NewSource:        nil, // HTTP destination only - responses published to Kafka
 	NewDestination:   destination.NewDestination,
 }
-
-// Specification returns the connector specification
-func Specification() sdk.Specification {
-	return sdk.Specification{
-		Name:    "http",
-		Summary: "HTTP destination connector with Kafka response publishing",
-		Description: "The HTTP connector sends records to HTTP endpoints with enterprise-grade features. " +
-			"Supports multiple authentication methods (Basic, Bearer, OAuth2), custom headers, " +
-			"configurable retry logic, and connection pooling. " +
-			"HTTP responses can be published to Kafka topics for downstream processing, " +
-			"enabling event-driven architectures and response analytics.",
-		Version: "v0.3.0",
-		Author:  "Conduit",
-	}
-}
@@ -0,0 +1,11 @@
+package destination_test
+
+import (
+	"testing"
+
+	"github.com/dev-in-black/connector-http/internal/harness"
+)
+
+func TestAcceptance(t *testing.T) {
+	harness.AcceptanceTest(t, nil)
+}
@@ -0,0 +1,45 @@
+package destination
+
+import (
+	"context"
+	stdhttp "net/http"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/dev-in-black/connector-http/internal/audit"
+)
+
+// auditRequest appends a request passing the audit interceptor to the audit
+// log. Requests are sent even if they can't be audited.
+func (d *Destination) auditRequest(ctx context.Context, req *stdhttp.Request, body []byte, resp *stdhttp.Response, err error) {
+	if d.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Time:       time.Now(),
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		BodySHA256: audit.BodyHash(body),
+		BodySize:   len(body),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+	}
+	if err := d.auditLog.Append(entry); err != nil {
+		sdk.Logger(ctx).Error().Err(err).Str("url", entry.URL).Msg("Failed to audit request")
+	}
+}
+
+// closeAudit closes the audit log
+func (d *Destination) closeAudit(ctx context.Context) {
+	if d.auditLog == nil {
+		return
+	}
+	if err := d.auditLog.Close(); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to close audit log")
+	}
+	d.auditLog = nil
+}
@@ -0,0 +1,133 @@
+package destination
+
+import (
+	"fmt"
+	stdhttp "net/http"
+	"strings"
+	"text/template"
+
+	"github.com/dev-in-black/connector-http/internal/auth"
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// newAuthManager creates the authentication manager for the configured auth
+// type and profiles. Session auth stores its cookies in jar.
+func newAuthManager(cfg Config, jar stdhttp.CookieJar) (auth.Manager, error) {
+	authConfig := auth.Config{
+		Type:          strings.Join(cfg.GetAuthTypes(), ","),
+		BasicUsername: cfg.Auth.Basic.Username,
+		BasicPassword: cfg.Auth.Basic.Password,
+		BearerToken:   cfg.Auth.Bearer.Token,
+		APIKeyHeader:  cfg.Auth.APIKey.Header,
+		APIKey:        cfg.Auth.APIKey.value(),
+
+		DigestUsername: cfg.Auth.Digest.Username,
+		DigestPassword: cfg.Auth.Digest.Password,
+
+		NTLMUsername: cfg.Auth.NTLM.Username,
+		NTLMPassword: cfg.Auth.NTLM.Password,
+
+		AzureAccountName: cfg.Auth.Azure.AccountName,
+		AzureAccountKey:  cfg.Auth.Azure.AccountKey,
+
+		GCPAudience:                  cfg.GetGCPAudience(),
+		GCPImpersonateServiceAccount: cfg.Auth.GCP.ImpersonateServiceAccount,
+	}
+
+	if cfg.hasAuthType("oauth2") {
+		authConfig.OAuth2Config = &auth.OAuth2Config{
+			ClientID:     cfg.Auth.OAuth2.ClientID,
+			ClientSecret: cfg.Auth.OAuth2.ClientSecret,
+			TokenURL:     cfg.Auth.OAuth2.TokenURL,
+			Scopes:       cfg.Auth.OAuth2.Scopes,
+
+			RefreshMargin: cfg.Auth.OAuth2.RefreshMargin,
+		}
+	}
+
+	if cfg.hasAuthType("negotiate") {
+		authConfig.KerberosConfig = &auth.KerberosConfig{
+			Krb5ConfPath: cfg.Auth.Kerberos.ConfigPath,
+			KeytabPath:   cfg.Auth.Kerberos.KeytabPath,
+			Username:     cfg.Auth.Kerberos.Username,
+			Realm:        cfg.Auth.Kerberos.Realm,
+			CCachePath:   cfg.Auth.Kerberos.CCachePath,
+			SPN:          cfg.Auth.Kerberos.SPN,
+		}
+	}
+
+	if cfg.hasAuthType("session") {
+		var err error
+		authConfig.SessionConfig, err = sessionConfig(cfg, jar)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manager, err := auth.NewManager(authConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth manager: %w", err)
+	}
+
+	// Records may select a named auth profile instead of the default
+	if len(cfg.Auth.Profiles) > 0 {
+		profiles := make(map[string]auth.Manager, len(cfg.Auth.Profiles))
+		for name, profile := range cfg.Auth.Profiles {
+			profiles[name], err = auth.NewManager(profile.authConfig())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create auth manager for profile %s: %w", name, err)
+			}
+		}
+		manager = auth.NewProfileAuth(manager, profiles)
+	}
+
+	return manager, nil
+}
+
+// sessionLoginData is the data the session login body template is executed with
+type sessionLoginData struct {
+	Username string
+	Password string
+}
+
+// parseSessionBody compiles the session login body template
+func parseSessionBody(cfg *Config) (*template.Template, error) {
+	return template.New("auth.session.body").Funcs(cfg.templateFuncs()).Parse(cfg.Auth.Session.Body)
+}
+
+// sessionConfig builds the session login config, rendering the login body
+// with the credentials
+func sessionConfig(cfg Config, jar stdhttp.CookieJar) (*auth.SessionConfig, error) {
+	tmpl, err := parseSessionBody(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse auth.session.body: %w", err)
+	}
+	var body strings.Builder
+	data := sessionLoginData{Username: cfg.Auth.Session.Username, Password: cfg.Auth.Session.Password}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render auth.session.body: %w", err)
+	}
+
+	predicate, err := http.NewBodyPredicate(cfg.loginPredicateConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth.session.success predicate: %w", err)
+	}
+
+	return &auth.SessionConfig{
+		LoginURL:    cfg.Auth.Session.LoginURL,
+		Method:      cfg.Auth.Session.Method,
+		Body:        []byte(body.String()),
+		ContentType: cfg.Auth.Session.ContentType,
+		Jar:         jar,
+		Timeout:     cfg.Timeout,
+		Validate: func(status int, body []byte) error {
+			if status < 200 || status >= 300 {
+				return fmt.Errorf("status %d", status)
+			}
+			if predicate != nil && !predicate.Matches(body) {
+				return fmt.Errorf("response did not match auth.session.success")
+			}
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,45 @@
+package destination
+
+import (
+	"context"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// logEndpointStats logs the counters of each endpoint records are distributed
+// across
+func logEndpointStats(ctx context.Context, client *http.Client) {
+	for _, stats := range client.EndpointStats() {
+		var avgLatency time.Duration
+		if stats.Requests > 0 {
+			avgLatency = stats.Latency / time.Duration(stats.Requests)
+		}
+		sdk.Logger(ctx).Info().
+			Str("endpoint", stats.URL).
+			Int64("requests", stats.Requests).
+			Int64("failures", stats.Failures).
+			Dur("avgLatency", avgLatency).
+			Bool("breakerOpen", stats.Open).
+			Int64("breakerOpened", stats.Opened).
+			Msg("Endpoint stats")
+	}
+}
+
+// logEndpointStatsPeriodically logs the endpoint counters every interval
+// until ctx is done
+func logEndpointStatsPeriodically(ctx context.Context, client *http.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logEndpointStats(ctx, client)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	connerrors "github.com/dev-in-black/connector-http/internal/errors"
+)
+
+// writeBatch sends the records of a batch that aren't skipped as a single
+// request, or several with batchBody.maxBytes. A _bulk request is acked up to
+// the first record the endpoint rejected, other requests succeed or fail as a
+// whole.
+func (d *Destination) writeBatch(ctx context.Context, records []opencdc.Record) (int, error) {
+	var rows []opencdc.Record
+	var indexes []int // Index of each row in records
+	var keys []string // Dedup key of each row, empty without dedup
+	for i, record := range records {
+		if d.delivered(ctx, record) {
+			continue
+		}
+		key, ok, err := d.admitRecord(ctx, record)
+		if err != nil {
+			return 0, d.recordError(err)
+		}
+		if !ok {
+			continue
+		}
+		rows = append(rows, record)
+		indexes = append(indexes, i)
+		keys = append(keys, key)
+	}
+	if len(rows) == 0 {
+		return len(records), nil
+	}
+
+	if d.config.RecordTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.RecordTimeout)
+		defer cancel()
+	}
+
+	// Split the batch into requests of at most batchBody.maxBytes. The sum of
+	// the bodies of single records bounds the body of several, as each repeats
+	// the framing.
+	maxBytes := d.config.BatchBody.MaxBytes
+	first, size := 0, int64(0)
+	for i := 0; maxBytes > 0 && i < len(rows); i++ {
+		single, err := d.encodeBatch(ctx, rows[i:i+1])
+		if err != nil {
+			return indexes[i], d.recordError(connerrors.WithCategory(err, connerrors.ErrValidation))
+		}
+		n := int64(len(single))
+		if n > maxBytes || (size+n > maxBytes && i > first) {
+			if i > first {
+				acked, err := d.sendBatch(ctx, rows[first:i], keys[first:i])
+				if err != nil {
+					return indexes[first+acked], err
+				}
+			}
+			first, size = i, 0
+		}
+		if n > maxBytes {
+			err := fmt.Errorf("batch body of the record alone is %d bytes, exceeding batchBody.maxBytes of %d bytes", n, maxBytes)
+			return indexes[i], d.recordError(connerrors.WithCategory(err, connerrors.ErrValidation))
+		}
+		size += n
+	}
+	acked, err := d.sendBatch(ctx, rows[first:], keys[first:])
+	if err != nil {
+		return indexes[first+acked], err
+	}
+	return len(records), nil
+}
+
+// sendBatch sends rows as a single request and returns how many of them were
+// acked, keys are their dedup keys
+func (d *Destination) sendBatch(ctx context.Context, rows []opencdc.Record, keys []string) (int, error) {
+	body, err := d.encodeBatch(ctx, rows)
+	if err != nil {
+		return 0, d.recordError(connerrors.WithCategory(err, connerrors.ErrValidation))
+	}
+
+	sdk.Logger(ctx).Debug().
+		Int("records", len(rows)).
+		Int("bytes", len(body)).
+		Str("format", d.config.BatchBody.Format).
+		Msg("Sending batch")
+	respBody, err := d.send(ctx, d.config.URL, body, nil)
+	if err != nil {
+		return 0, d.recordError(err)
+	}
+
+	if d.items != nil && respBody != nil {
+		return d.ackItems(ctx, rows, keys, respBody)
+	}
+
+	acked, itemErr := len(rows), error(nil)
+	if d.esBulk != nil && respBody != nil {
+		acked, itemErr = parseESBulkResponse(respBody, len(rows))
+	}
+
+	for i, record := range rows[:acked] {
+		d.markDelivered(record, keys[i])
+	}
+	if itemErr != nil {
+		return acked, d.recordError(itemErr)
+	}
+	return acked, nil
+}
+
+// markDelivered adds a record of a batch to the dedup cache and the delivery
+// log, key is its dedup key
+func (d *Destination) markDelivered(record opencdc.Record, key string) {
+	if key != "" {
+		d.dedup.Add(key)
+	}
+	if d.deliveryLog != nil && record.Position != nil {
+		d.deliveryLog.Add(record.Position)
+	}
+}
+
+// encodeBatch renders the request body of a batch in the batchBody format
+func (d *Destination) encodeBatch(ctx context.Context, rows []opencdc.Record) ([]byte, error) {
+	if d.esBulk != nil {
+		body, err := d.esBulk.Encode(rows, d.config.templateOptions(), func(record opencdc.Record) ([]byte, error) {
+			return d.recordBody(ctx, record)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render bulk body: %w", err)
+		}
+		return body, nil
+	}
+
+	if d.envelope != nil {
+		body, err := d.envelope.Encode(rows, func(record opencdc.Record) ([]byte, error) {
+			return d.recordBody(ctx, record)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s body: %w", d.envelope.format, err)
+		}
+		return body, nil
+	}
+
+	body, err := d.csvEncoder.Encode(rows, d.config.UsePayloadAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render CSV body: %w", err)
+	}
+	return body, nil
+}
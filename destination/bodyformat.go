@@ -0,0 +1,56 @@
+package destination
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// Request body formats of records without body template or transform
+const (
+	bodyFormatPayload   = "payload"
+	bodyFormatOpenCDC   = "opencdc"
+	bodyFormatFlattened = "flattened"
+)
+
+// FlattenConfig configures the flattened body format
+type FlattenConfig struct {
+	// Metadata are the metadata keys added to flattened bodies, as fields
+	// named like the keys.
+	Metadata []string `json:"metadata"`
+	// KeyField is the field holding keys that aren't JSON objects, object
+	// keys are merged into the body.
+	KeyField string `json:"keyField" default:"key"`
+}
+
+// flattenRecord merges the key, payload and selected metadata of a record
+// into a single JSON object. Payload fields take precedence over key fields
+// and metadata.
+func flattenRecord(record opencdc.Record, opts templateOptions, cfg FlattenConfig) ([]byte, error) {
+	data := newTemplateData(record, opts)
+	payload, ok := data.Payload.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("payload is not a JSON object")
+	}
+
+	body := make(map[string]any, len(payload)+len(cfg.Metadata)+1)
+	for _, key := range cfg.Metadata {
+		if value, ok := record.Metadata[key]; ok {
+			body[key] = value
+		}
+	}
+	if record.Key != nil {
+		if key, ok := decodePayload(record.Key, true).(map[string]any); ok {
+			for name, value := range key {
+				body[name] = value
+			}
+		} else {
+			body[cfg.KeyField] = data.Key
+		}
+	}
+	for name, value := range payload {
+		body[name] = value
+	}
+	return json.Marshal(body)
+}
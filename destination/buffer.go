@@ -0,0 +1,458 @@
+package destination
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	bolt "go.etcd.io/bbolt"
+)
+
+// errEndpointUnavailable marks failed records that are buffered instead of failing
+var errEndpointUnavailable = errors.New("endpoint unavailable")
+
+// reloadPollInterval is how often the drain of the buffer retries to deliver
+// while a config update holds reloadMu
+const reloadPollInterval = 10 * time.Millisecond
+
+// bufferBucket is the BoltDB bucket holding spilled records by sequence number
+var bufferBucket = []byte("records")
+
+// bufferedRecord is a record in the buffer with its position in the queue
+type bufferedRecord struct {
+	seq       uint64
+	record    opencdc.Record
+	persisted bool // Also stored in the spill file
+}
+
+// recordBuffer queues records accepted while the endpoint is unavailable until
+// they are delivered. The oldest records are held in memory, newer ones spill
+// to a BoltDB file once memorySize is reached, which keeps them across restarts.
+type recordBuffer struct {
+	memorySize int
+	maxSize    int
+	db         *bolt.DB // nil without spill file
+
+	mu      sync.Mutex
+	memory  []bufferedRecord // Oldest first
+	spilled int              // Records only in the spill file, all newer than memory
+	next    uint64           // Sequence number of the next record
+	changed chan struct{}    // Closed and replaced when records are added or removed
+	failed  error            // First buffered record that failed for good
+}
+
+// newRecordBuffer creates a record buffer, loading records left in the spill
+// file at path. Without path the buffer holds memorySize records.
+func newRecordBuffer(path string, memorySize, maxSize int) (*recordBuffer, error) {
+	b := &recordBuffer{
+		memorySize: memorySize,
+		maxSize:    maxSize,
+		changed:    make(chan struct{}),
+	}
+	if path == "" {
+		b.maxSize = memorySize
+		return b, nil
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open buffer file %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bufferBucket)
+		if err != nil {
+			return err
+		}
+		b.spilled = bucket.Stats().KeyN
+		if key, _ := bucket.Cursor().Last(); key != nil {
+			b.next = binary.BigEndian.Uint64(key) + 1
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load buffer file %s: %w", path, err)
+	}
+	b.db = db
+	return b, nil
+}
+
+// Len returns the number of buffered records
+func (b *recordBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.memory) + b.spilled
+}
+
+// Changed returns a channel that is closed once records are added or removed
+func (b *recordBuffer) Changed() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changed
+}
+
+// Push appends the record, reporting false if the buffer is full
+func (b *recordBuffer) Push(record opencdc.Record) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.memory)+b.spilled >= b.maxSize {
+		return false, nil
+	}
+
+	entry := bufferedRecord{seq: b.next, record: record}
+	if b.spilled == 0 && len(b.memory) < b.memorySize {
+		b.memory = append(b.memory, entry)
+	} else {
+		if err := b.persist(entry); err != nil {
+			return false, err
+		}
+		b.spilled++
+	}
+	b.next++
+	b.notify()
+	return true, nil
+}
+
+// Peek returns the oldest record, loading spilled records once memory is empty
+func (b *recordBuffer) Peek() (opencdc.Record, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.memory) == 0 && b.spilled > 0 {
+		if err := b.load(); err != nil {
+			return opencdc.Record{}, false, err
+		}
+	}
+	if len(b.memory) == 0 {
+		return opencdc.Record{}, false, nil
+	}
+	return b.memory[0].record, true, nil
+}
+
+// Pop removes the oldest record after it was delivered
+func (b *recordBuffer) Pop() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.memory) == 0 {
+		return nil
+	}
+	entry := b.memory[0]
+	if entry.persisted {
+		err := b.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(bufferBucket).Delete(seqKey(entry.seq))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove record from buffer file: %w", err)
+		}
+	}
+	b.memory[0] = bufferedRecord{}
+	b.memory = b.memory[1:]
+	b.notify()
+	return nil
+}
+
+// Fail records the error of a buffered record that can't be delivered, the
+// first one is kept
+func (b *recordBuffer) Fail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed == nil {
+		b.failed = err
+	}
+}
+
+// Err returns the error of the first buffered record that couldn't be delivered
+func (b *recordBuffer) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failed
+}
+
+// Close stores the records held only in memory in the spill file and closes
+// it. It returns the number of records lost because there is no spill file.
+func (b *recordBuffer) Close() (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.db == nil {
+		lost := len(b.memory)
+		b.memory = nil
+		return lost, nil
+	}
+
+	var errs []error
+	for _, entry := range b.memory {
+		if !entry.persisted {
+			errs = append(errs, b.persist(entry))
+		}
+	}
+	b.memory = nil
+	errs = append(errs, b.db.Close())
+	return 0, errors.Join(errs...)
+}
+
+// persist stores the record in the spill file, the lock must be held
+func (b *recordBuffer) persist(entry bufferedRecord) error {
+	if b.db == nil {
+		return fmt.Errorf("no buffer file configured")
+	}
+	data, err := json.Marshal(entry.record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bufferBucket).Put(seqKey(entry.seq), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write record to buffer file: %w", err)
+	}
+	return nil
+}
+
+// load reads the oldest spilled records into memory, the lock must be held.
+// They stay in the spill file until they are popped.
+func (b *recordBuffer) load() error {
+	n := max(b.memorySize, 1)
+	return b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bufferBucket).Cursor()
+		for key, value := c.First(); key != nil && len(b.memory) < n; key, value = c.Next() {
+			var record opencdc.Record
+			if err := record.UnmarshalJSON(value); err != nil {
+				return fmt.Errorf("failed to unmarshal buffered record: %w", err)
+			}
+			b.memory = append(b.memory, bufferedRecord{
+				seq:       binary.BigEndian.Uint64(key),
+				record:    record,
+				persisted: true,
+			})
+			b.spilled--
+		}
+		return nil
+	})
+}
+
+// notify wakes up goroutines waiting for a change, the lock must be held
+func (b *recordBuffer) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// seqKey encodes a sequence number as a key sorting in queue order
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// deliver sends the record, or buffers it while the endpoint is unavailable.
+// Records are buffered while earlier ones are, to keep them in order.
+func (d *Destination) deliver(ctx context.Context, record opencdc.Record) error {
+	if d.buffer == nil {
+		return d.writeRecord(ctx, record)
+	}
+
+	if d.buffer.Len() == 0 {
+		err := d.writeRecord(ctx, record)
+		if !errors.Is(err, errEndpointUnavailable) {
+			return err
+		}
+		sdk.Logger(ctx).Warn().Err(err).Msg("Endpoint unavailable, buffering records until it recovers")
+	}
+	return d.bufferRecord(ctx, record)
+}
+
+// bufferWaiters counts the Write calls waiting for room in the full buffer.
+// They hold reloadMu for reading, so the components can't change while they
+// wait and the drain delivers without taking reloadMu, which a pending config
+// update would block.
+type bufferWaiters struct {
+	mu       sync.Mutex
+	released *sync.Cond // Signaled when a delivery releases the waiting Write calls
+	n        int
+	pinned   int // Deliveries relying on the waiting Write calls
+}
+
+// add changes the number of waiting Write calls by delta. The last waiting
+// Write call stops waiting only once no delivery relies on it.
+func (w *bufferWaiters) add(delta int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.n+delta == 0 && w.pinned > 0 {
+		w.cond().Wait()
+	}
+	w.n += delta
+}
+
+// pin makes the waiting Write calls wait until the returned function is
+// called, it reports false if none is waiting
+func (w *bufferWaiters) pin() (unpin func(), ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.n == 0 {
+		return nil, false
+	}
+	w.pinned++
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.pinned--
+		w.cond().Broadcast()
+	}, true
+}
+
+// cond returns the condition of released deliveries, the lock must be held
+func (w *bufferWaiters) cond() *sync.Cond {
+	if w.released == nil {
+		w.released = sync.NewCond(&w.mu)
+	}
+	return w.released
+}
+
+// lockForDelivery holds the components of the destination for the delivery of
+// a buffered record, either by a waiting Write call, which can't return
+// until release is called, or by reloadMu. It reports false while a config
+// update holds or waits for reloadMu and no Write call is waiting.
+func (d *Destination) lockForDelivery() (release func(), ok bool) {
+	if unpin, ok := d.bufferWaiters.pin(); ok {
+		return unpin, true
+	}
+	if d.reloadMu.TryRLock() {
+		return d.reloadMu.RUnlock, true
+	}
+	return nil, false
+}
+
+// bufferRecord appends the record to the buffer, waiting for buffered records
+// to be delivered while it is full. The caller must hold reloadMu for reading,
+// it is kept while waiting so the components stay in place until the Write
+// call completes.
+func (d *Destination) bufferRecord(ctx context.Context, record opencdc.Record) error {
+	for {
+		if d.buffer == nil {
+			return fmt.Errorf("record buffer is closed")
+		}
+
+		changed := d.buffer.Changed()
+		ok, err := d.buffer.Push(record)
+		if err != nil {
+			return fmt.Errorf("failed to buffer record: %w", err)
+		}
+		if ok {
+			return nil
+		}
+
+		sdk.Logger(ctx).Debug().Msg("Record buffer full, waiting for buffered records to be delivered")
+		d.bufferWaiters.add(1)
+		select {
+		case <-changed:
+		case <-ctx.Done():
+		}
+		d.bufferWaiters.add(-1)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// drainBuffer delivers buffered records in order until ctx is done. While the
+// endpoint is still unavailable it waits retryInterval before trying again;
+// records failing for other reasons were already acked, their error fails the
+// next Write.
+func (d *Destination) drainBuffer(ctx context.Context, buf *recordBuffer, retryInterval time.Duration) {
+	logger := sdk.Logger(ctx)
+	for {
+		changed := buf.Changed()
+		record, ok, err := buf.Peek()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to read buffered record")
+			if !sleepContext(ctx, retryInterval) {
+				return
+			}
+			continue
+		}
+		if !ok {
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Deliver with the current components, config updates wait for it
+		release, ok := d.lockForDelivery()
+		if !ok {
+			if !sleepContext(ctx, reloadPollInterval) {
+				return
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			release()
+			return
+		}
+		err = d.writeRecord(ctx, record)
+		release()
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, errEndpointUnavailable):
+			logger.Debug().Err(err).Int("buffered", buf.Len()).Msg("Endpoint still unavailable")
+			if !sleepContext(ctx, retryInterval) {
+				return
+			}
+			continue
+		case err != nil:
+			logger.Error().Err(err).Msg("Buffered record failed and was dropped")
+			buf.Fail(fmt.Errorf("buffered record failed: %w", err))
+		}
+
+		if err := buf.Pop(); err != nil {
+			logger.Error().Err(err).Msg("Failed to remove delivered record from buffer")
+			if !sleepContext(ctx, retryInterval) {
+				return
+			}
+		}
+	}
+}
+
+// closeBuffer stops draining the buffer and closes it
+func (d *Destination) closeBuffer(ctx context.Context) {
+	if d.stopBufferDrain != nil {
+		d.stopBufferDrain()
+		d.stopBufferDrain = nil
+	}
+	if d.buffer == nil {
+		return
+	}
+
+	lost, err := d.buffer.Close()
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Failed to persist record buffer")
+	}
+	if lost > 0 {
+		sdk.Logger(ctx).Warn().Int("records", lost).Msg("Buffered records were not delivered and are lost, configure buffer.path to keep them")
+	}
+	d.buffer = nil
+}
+
+// sleepContext waits for d, reporting false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,160 @@
+package destination_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// bufferSettings buffers records of endpoint without retrying failed requests
+func bufferSettings(url string) map[string]string {
+	return map[string]string{
+		"url":                  url,
+		"retry.max":            "0",
+		"buffer.enabled":       "true",
+		"buffer.retryInterval": "10ms",
+	}
+}
+
+func TestBufferDeliversAfterOutage(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newTestEndpoint(t)
+	dest := openDestination(t, bufferSettings(endpoint.URL))
+
+	// Records failing during the outage are acked and buffered
+	endpoint.down.Store(true)
+	n, err := dest.Write(ctx, testRecords("1", "2", "3"))
+	is.NoErr(err)
+	is.Equal(n, 3)
+	time.Sleep(50 * time.Millisecond)
+	is.Equal(len(endpoint.Bodies()), 0)
+
+	// They are delivered in order once the endpoint recovers
+	endpoint.down.Store(false)
+	is.Equal(endpoint.waitForBodies(t, 3), testBodies("1", "2", "3"))
+
+	// Later records are sent directly
+	n, err = dest.Write(ctx, testRecords("4"))
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(endpoint.Bodies(), testBodies("1", "2", "3", "4"))
+}
+
+func TestBufferFullWriteWaitsForDrain(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newTestEndpoint(t)
+	settings := bufferSettings(endpoint.URL)
+	settings["buffer.memorySize"] = "2"
+	dest := openDestination(t, settings)
+
+	endpoint.down.Store(true)
+	n, err := dest.Write(ctx, testRecords("1", "2"))
+	is.NoErr(err)
+	is.Equal(n, 2)
+
+	// The buffer is full, the next Write waits while the buffer is drained
+	written := make(chan error, 1)
+	go func() {
+		_, err := dest.Write(ctx, testRecords("3"))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		t.Fatalf("Write returned while the buffer was full: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	endpoint.down.Store(false)
+	select {
+	case err := <-written:
+		is.NoErr(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write still waiting after the buffer was drained")
+	}
+	is.Equal(endpoint.waitForBodies(t, 3), testBodies("1", "2", "3"))
+}
+
+func TestBufferSpillFileKeepsRecordsAcrossRestarts(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	// The first destination only sees the endpoint down, so requests it
+	// cancels on Teardown can't reach the endpoint that is up
+	down := newTestEndpoint(t)
+	down.down.Store(true)
+	endpoint := newTestEndpoint(t)
+	settings := bufferSettings(down.URL)
+	settings["buffer.memorySize"] = "1"
+	settings["buffer.path"] = filepath.Join(t.TempDir(), "buffer.db")
+	settings["drainTimeout"] = "50ms" // The buffer can't drain to the endpoint that is down
+
+	dest := newDestination(t, settings)
+	n, err := dest.Write(ctx, testRecords("1", "2", "3"))
+	is.NoErr(err)
+	is.Equal(n, 3)
+	is.NoErr(dest.Teardown(ctx))
+	is.Equal(len(down.Bodies()), 0)
+
+	// The records left in the file are delivered once the destination reopens
+	settings["url"] = endpoint.URL
+	openDestination(t, settings)
+	is.Equal(endpoint.waitForBodies(t, 3), testBodies("1", "2", "3"))
+}
+
+func TestBufferWaitingWriteCanceledDuringDelivery(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	// The first request fails, the delivery of the buffered record then hangs
+	var requests atomic.Int32
+	release := make(chan struct{})
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(endpoint.Close)
+	t.Cleanup(func() { close(release) })
+	settings := bufferSettings(endpoint.URL)
+	settings["buffer.memorySize"] = "1"
+	settings["drainTimeout"] = "50ms"
+	dest := openDestination(t, settings)
+
+	n, err := dest.Write(ctx, testRecords("1"))
+	is.NoErr(err)
+	is.Equal(n, 1)
+
+	// A Write waits for room while the buffered record is being delivered
+	go func() { _, _ = dest.Write(ctx, testRecords("2")) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	is.Equal(requests.Load(), int32(2))
+
+	// Another waiting Write returns once canceled, without waiting for the delivery
+	cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	written := make(chan error, 1)
+	go func() {
+		_, err := dest.Write(cancelCtx, testRecords("3"))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		is.True(err != nil)
+	case <-time.After(2 * time.Second):
+		t.Fatal("canceled Write still waiting for the delivery")
+	}
+}
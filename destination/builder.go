@@ -0,0 +1,73 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	stdhttp "net/http"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// RequestBuilder builds the request the destination sends for a record
+// without sending it, to test templates or preview requests in processors.
+// Requests carry the method, URL, body and headers of the record. They are
+// built without connecting anywhere, so authentication, signing and the
+// request builder plugin, applied as requests are sent, aren't part of them,
+// and secret references aren't resolved.
+type RequestBuilder struct {
+	d *Destination
+}
+
+// NewRequestBuilder returns a builder of the requests of a destination with
+// cfg, parsed like the settings of the destination, e.g. with
+// sdk.Util.ParseConfig and the destination parameters of the connector
+func NewRequestBuilder(ctx context.Context, cfg Config) (*RequestBuilder, error) {
+	cfg.LoadEnvHeaders()
+	if err := cfg.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	// Batches and streams are sent by the request of several records
+	switch {
+	case cfg.BatchBody.Format != "none":
+		return nil, fmt.Errorf("request builder cannot be used with batchBody.format %s", cfg.BatchBody.Format)
+	case cfg.Stream.Enabled:
+		return nil, fmt.Errorf("request builder cannot be used with stream.enabled")
+	case cfg.GRPC.Method != "":
+		return nil, fmt.Errorf("request builder cannot be used with grpc.method")
+	}
+
+	userAgent, err := cfg.userAgent()
+	if err != nil {
+		return nil, err
+	}
+	d := &Destination{config: cfg}
+	d.httpClient = http.NewClient(
+		http.Config{
+			UserAgent:            userAgent,
+			Accept:               cfg.Accept,
+			DisableDecompression: !cfg.Response.Decompress,
+			UnixSocketPath:       cfg.GetUnixSocketPath(),
+		},
+		nil,
+		cfg.StaticHeaders,
+		cfg.LoadedEnvHeaders(),
+	)
+	if err := d.parseRequestTemplates(cfg.RedactHashKey); err != nil {
+		return nil, err
+	}
+	return &RequestBuilder{d: d}, nil
+}
+
+// Build returns the request of record, the first request of upserted records.
+// Records the destination would skip, e.g. by skipFilter or deleteStrategy
+// skip, are built too.
+func (b *RequestBuilder) Build(ctx context.Context, record opencdc.Record) (*stdhttp.Request, error) {
+	ctx, targetURL, body, err := b.d.recordRequest(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	return b.d.httpClient.NewRequest(ctx, b.d.requestMethod(ctx), targetURL, body)
+}
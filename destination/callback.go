@@ -0,0 +1,291 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// callbackIDMetadata is the record header of published callbacks holding the
+// ID that correlated them
+const callbackIDMetadata = "http.callback.id"
+
+// maxCallbackBodySize bounds callback bodies unless response.maxBodySize does
+const maxCallbackBodySize = 10 << 20
+
+// callbackGrace is how long a callback of an unknown ID waits for the response
+// of its request, the endpoint may call back before it responded
+const callbackGrace = 5 * time.Second
+
+// pendingCallback is a request whose result is awaited
+type pendingCallback struct {
+	url       string
+	method    string
+	requestID string
+	metadata  opencdc.Metadata
+	original  json.RawMessage // Embedded with kafka.includeOriginalRecord
+	record    recordRef
+	sent      time.Time
+}
+
+// callbackListener receives the results of requests the endpoint processes
+// asynchronously, correlated by an ID of the response, and publishes them
+type callbackListener struct {
+	config       CallbackConfig
+	idPath       *jsonpath.Path
+	callbackPath *jsonpath.Path
+	maxBodySize  int64
+	publish      func(ctx context.Context, p pendingCallback, id string, r *stdhttp.Request, body []byte) error
+
+	server *stdhttp.Server
+	done   chan struct{} // Closed once the server stopped
+
+	mu         sync.Mutex
+	pending    map[string]pendingCallback
+	registered chan struct{} // Closed and replaced when a request is awaited
+
+	stopExpiry context.CancelFunc
+}
+
+// newCallbackListener compiles the ID paths of the callback config
+func newCallbackListener(cfg CallbackConfig) (*callbackListener, error) {
+	l := &callbackListener{
+		config:      cfg,
+		maxBodySize: maxCallbackBodySize,
+		pending:     make(map[string]pendingCallback),
+		registered:  make(chan struct{}),
+	}
+	var err error
+	if l.idPath, err = jsonpath.Parse(cfg.IDPath); err != nil {
+		return nil, fmt.Errorf("invalid idPath: %w", err)
+	}
+	if l.callbackPath, err = jsonpath.Parse(cfg.CallbackIDPath); err != nil {
+		return nil, fmt.Errorf("invalid callbackIdPath: %w", err)
+	}
+	return l, nil
+}
+
+// Start listens on the configured address and expires callbacks that aren't
+// received in time, until Close
+func (l *callbackListener) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", l.config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for callbacks: %w", err)
+	}
+
+	// Callbacks are published with the logger of the connector but outlive
+	// the context of Open
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	l.stopExpiry = cancel
+	mux := stdhttp.NewServeMux()
+	mux.HandleFunc(l.config.Path, l.handle)
+	l.server = &stdhttp.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	l.done = make(chan struct{})
+	go func() {
+		defer close(l.done)
+		if err := l.server.Serve(listener); err != nil && !errors.Is(err, stdhttp.ErrServerClosed) {
+			sdk.Logger(ctx).Error().Err(err).Msg("Callback listener failed")
+		}
+	}()
+	go l.expirePeriodically(ctx)
+
+	sdk.Logger(ctx).Info().
+		Str("address", listener.Addr().String()).
+		Str("path", l.config.Path).
+		Msg("Callback listener started")
+	return nil
+}
+
+// Await registers a request for its callback, body is the response holding
+// the ID correlating them
+func (l *callbackListener) Await(ctx context.Context, body []byte, p pendingCallback) {
+	v, ok := l.idPath.Lookup(body)
+	if !ok || v == nil {
+		sdk.Logger(ctx).Warn().
+			Str("idPath", l.config.IDPath).
+			Msg("Response has no callback ID, its callback can't be correlated")
+		return
+	}
+	id := jsonpath.Stringify(v)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending[id] = p
+	close(l.registered)
+	l.registered = make(chan struct{})
+}
+
+// take removes the request awaiting the callback with the ID, waiting up to
+// callbackGrace for its response
+func (l *callbackListener) take(ctx context.Context, id string) (pendingCallback, bool) {
+	timer := time.NewTimer(callbackGrace)
+	defer timer.Stop()
+	for {
+		l.mu.Lock()
+		p, ok := l.pending[id]
+		delete(l.pending, id)
+		registered := l.registered
+		l.mu.Unlock()
+		if ok {
+			return p, true
+		}
+
+		select {
+		case <-registered:
+		case <-timer.C:
+			return pendingCallback{}, false
+		case <-ctx.Done():
+			return pendingCallback{}, false
+		}
+	}
+}
+
+// handle publishes a received callback. Callbacks of unknown requests are
+// rejected with 404, callbacks that failed to publish with 500 so the
+// endpoint sends them again.
+func (l *callbackListener) handle(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodPost && r.Method != stdhttp.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	body, err := io.ReadAll(io.LimitReader(r.Body, l.maxBodySize+1))
+	if err != nil {
+		stdhttp.Error(w, "failed to read body", stdhttp.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > l.maxBodySize {
+		stdhttp.Error(w, "body too large", stdhttp.StatusRequestEntityTooLarge)
+		return
+	}
+	v, ok := l.callbackPath.Lookup(body)
+	if !ok || v == nil {
+		stdhttp.Error(w, "no callback ID", stdhttp.StatusBadRequest)
+		return
+	}
+	id := jsonpath.Stringify(v)
+
+	p, ok := l.take(ctx, id)
+	if !ok {
+		sdk.Logger(ctx).Warn().Str("callbackId", id).Msg("Received callback of an unknown or expired request")
+		stdhttp.Error(w, "unknown callback ID", stdhttp.StatusNotFound)
+		return
+	}
+
+	if err := l.publish(ctx, p, id, r, body); err != nil {
+		sdk.Logger(ctx).Error().Err(err).Str("callbackId", id).Msg("Failed to publish callback")
+		l.mu.Lock()
+		l.pending[id] = p
+		l.mu.Unlock()
+		stdhttp.Error(w, "failed to publish callback", stdhttp.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+// expirePeriodically drops the requests whose callback wasn't received
+// within the timeout until ctx is done
+func (l *callbackListener) expirePeriodically(ctx context.Context) {
+	ticker := time.NewTicker(min(l.config.Timeout, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.mu.Lock()
+			var expired int
+			for id, p := range l.pending {
+				if now.Sub(p.sent) > l.config.Timeout {
+					delete(l.pending, id)
+					expired++
+				}
+			}
+			l.mu.Unlock()
+			if expired > 0 {
+				sdk.Logger(ctx).Warn().
+					Int("requests", expired).
+					Dur("timeout", l.config.Timeout).
+					Msg("Callbacks not received within callback.timeout")
+			}
+		}
+	}
+}
+
+// Pending returns the number of requests awaiting their callback
+func (l *callbackListener) Pending() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.pending)
+}
+
+// Close stops the listener, waiting for callbacks being published. Awaited
+// callbacks are dropped.
+func (l *callbackListener) Close(ctx context.Context) {
+	if l.server == nil {
+		return
+	}
+	if err := l.server.Shutdown(ctx); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to stop callback listener")
+	}
+	<-l.done
+	l.stopExpiry()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) > 0 {
+		sdk.Logger(ctx).Warn().Int("requests", len(l.pending)).Msg("Callback listener stopped before all callbacks were received")
+	}
+}
+
+// publishCallback publishes a callback to the response sinks like a response
+// of the request, with the callback ID as record header
+func (d *Destination) publishCallback(ctx context.Context, p pendingCallback, id string, r *stdhttp.Request, body []byte) error {
+	metadata := make(opencdc.Metadata, len(p.metadata)+1)
+	for k, v := range p.metadata {
+		metadata[k] = v
+	}
+	metadata[callbackIDMetadata] = id
+	latency := RequestLatency{TotalMs: millis(time.Since(p.sent))}
+
+	if len(d.publishers) > 0 {
+		msg := responseMessage{
+			statusCode:     stdhttp.StatusOK,
+			protocol:       r.Proto,
+			header:         r.Header,
+			body:           body,
+			url:            p.url,
+			method:         p.method,
+			requestID:      p.requestID,
+			attempts:       1,
+			latency:        latency.message(),
+			originalRecord: p.original,
+			record:         p.record,
+		}
+		if err := d.publishResponse(ctx, msg, metadata); err != nil {
+			return err
+		}
+	}
+	if d.config.ResponseSink != "none" {
+		resp := &stdhttp.Response{StatusCode: stdhttp.StatusOK, Header: r.Header}
+		d.sinkResponse(ctx, resp, body, p.method, p.url, p.requestID, metadata, 1, latency)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/textproto"
+	"regexp"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/jsonl"
+	"github.com/dev-in-black/connector-http/internal/kafka"
+)
+
+// redacted replaces redacted header values and body parts in captures
+const redacted = "[REDACTED]"
+
+// captureSink writes the requests and responses of sampled records to a file
+// and/or a Kafka topic, with credentials redacted
+type captureSink struct {
+	sampleRate    float64
+	redactHeaders []string // Canonical header names
+	patterns      []*regexp.Regexp
+
+	file *jsonl.File // nil without capture file
+
+	producer *kafka.Producer // nil without capture topic
+	topic    string
+}
+
+// newCaptureSink opens the capture file, removing a capture torn by a crash.
+// Headers in sensitiveHeaders are redacted besides the configured ones.
+func newCaptureSink(ctx context.Context, cfg CaptureConfig, policy jsonl.SyncPolicy, sensitiveHeaders []string, producer *kafka.Producer) (*captureSink, error) {
+	s := &captureSink{
+		sampleRate: cfg.SampleRate,
+		topic:      cfg.Topic,
+	}
+	for _, name := range append(cfg.RedactHeaders, sensitiveHeaders...) {
+		s.redactHeaders = append(s.redactHeaders, textproto.CanonicalMIMEHeaderKey(name))
+	}
+	for _, pattern := range cfg.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		s.patterns = append(s.patterns, re)
+	}
+
+	if cfg.Topic != "" {
+		if producer == nil {
+			return nil, fmt.Errorf("capture.topic requires kafka.enabled")
+		}
+		s.producer = producer
+	}
+	if cfg.Path != "" {
+		file, repaired, err := jsonl.Open(cfg.Path, policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open capture file: %w", err)
+		}
+		if repaired > 0 {
+			sdk.Logger(ctx).Warn().
+				Str("path", cfg.Path).
+				Int64("bytes", repaired).
+				Msg("Removed a capture torn by a crash")
+		}
+		s.file = file
+	}
+	return s, nil
+}
+
+// Sample reports whether the next request is captured
+func (s *captureSink) Sample() bool {
+	return rand.Float64() < s.sampleRate
+}
+
+// Capture redacts the exchange and writes it as a JSON line
+func (s *captureSink) Capture(ctx context.Context, ex *http.Exchange) {
+	s.redact(ex)
+	data, err := json.Marshal(ex)
+	if err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to marshal captured request")
+		return
+	}
+
+	if s.file != nil {
+		if err := s.file.WriteLine(data); err != nil {
+			sdk.Logger(ctx).Warn().Err(err).Msg("Failed to write captured request")
+		}
+	}
+	if s.producer != nil {
+		// The capture is published even if the record's request was canceled
+		if err := s.producer.PublishTo(context.WithoutCancel(ctx), s.topic, nil, data); err != nil {
+			sdk.Logger(ctx).Warn().Err(err).Msg("Failed to publish captured request")
+		}
+	}
+}
+
+// Close closes the capture file
+func (s *captureSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// redact replaces sensitive headers and the matches of the redact patterns
+func (s *captureSink) redact(ex *http.Exchange) {
+	for _, name := range s.redactHeaders {
+		if _, ok := ex.Request.Headers[name]; ok {
+			ex.Request.Headers[name] = []string{redacted}
+		}
+		if ex.Response != nil {
+			if _, ok := ex.Response.Headers[name]; ok {
+				ex.Response.Headers[name] = []string{redacted}
+			}
+		}
+	}
+
+	ex.Request.URL = s.redactText(ex.Request.URL)
+	// Binary bodies are base64-encoded, patterns don't apply to them
+	if ex.Request.BodyEncoding == "" {
+		ex.Request.Body = s.redactText(ex.Request.Body)
+	}
+	if ex.Response != nil && ex.Response.BodyEncoding == "" {
+		ex.Response.Body = s.redactText(ex.Response.Body)
+	}
+}
+
+// redactText replaces the matches of the redact patterns in text. If a
+// pattern has groups, only the groups are replaced, e.g. the value in
+// "password":"(.*?)".
+func (s *captureSink) redactText(text string) string {
+	for _, re := range s.patterns {
+		if re.NumSubexp() == 0 {
+			text = re.ReplaceAllString(text, redacted)
+			continue
+		}
+
+		var out []byte
+		last := 0
+		for _, match := range re.FindAllStringSubmatchIndex(text, -1) {
+			for g := 2; g < len(match); g += 2 {
+				start, end := match[g], match[g+1]
+				if start < last {
+					continue // Unmatched or nested in a group already replaced
+				}
+				out = append(out, text[last:start]...)
+				out = append(out, redacted...)
+				last = end
+			}
+		}
+		text = string(append(out, text[last:]...))
+	}
+	return text
+}
+
+// captureExchange passes a captured exchange to the capture sink
+func (d *Destination) captureExchange(ctx context.Context, ex *http.Exchange) {
+	if d.capture != nil {
+		d.capture.Capture(ctx, ex)
+	}
+}
+
+// closeCapture closes the capture sink
+func (d *Destination) closeCapture(ctx context.Context) {
+	if d.capture == nil {
+		return
+	}
+	if err := d.capture.Close(); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to close capture file")
+	}
+	d.capture = nil
+}
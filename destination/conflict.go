@@ -0,0 +1,82 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	stdhttp "net/http"
+	"net/url"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// Conflict actions
+const (
+	conflictFail      = "fail"
+	conflictRetry     = "retry"
+	conflictOverwrite = "overwrite"
+	conflictSkip      = "skip"
+	conflictDLQ       = "dlq"
+)
+
+// OverwriteConfig forces a write the endpoint rejected with 409 Conflict
+type OverwriteConfig struct {
+	// Headers are added to the resent request, e.g. X-Force: true.
+	Headers map[string]string `json:"headers"`
+	// QueryParams are added to the URL of the resent request, e.g. force=true.
+	QueryParams map[string]string `json:"queryParams"`
+}
+
+// conflictAction returns the status action of 409 Conflict responses for
+// onConflict, false if they are left to the default rules. Overwritten
+// requests that conflict again fail.
+func (c *Config) conflictAction() (http.Action, bool) {
+	switch c.OnConflict {
+	case conflictRetry:
+		return http.ActionRetry, true
+	case conflictSkip:
+		return http.ActionIgnore, true
+	case conflictDLQ:
+		return http.ActionDLQ, true
+	default:
+		return "", false
+	}
+}
+
+// overwrite resends a request the endpoint answered with 409 Conflict with the
+// headers and query parameters of overwrite
+func (d *Destination) overwrite(ctx context.Context, method, targetURL string, body []byte) (*stdhttp.Response, error) {
+	if params := d.config.Overwrite.QueryParams; len(params) > 0 {
+		u, err := url.Parse(targetURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url: %w", err)
+		}
+		query := u.Query()
+		for name, value := range params {
+			query.Set(name, value)
+		}
+		u.RawQuery = query.Encode()
+		targetURL = u.String()
+	}
+	if len(d.config.Overwrite.Headers) > 0 {
+		ctx = http.WithHeaders(ctx, d.config.Overwrite.Headers)
+	}
+	return d.httpClient.Do(ctx, method, targetURL, body)
+}
+
+// validateConflict checks that overwritten requests are forced
+func (c *Config) validateConflict() error {
+	switch c.OnConflict {
+	case conflictFail, conflictRetry, conflictSkip, conflictDLQ:
+		if len(c.Overwrite.Headers) > 0 || len(c.Overwrite.QueryParams) > 0 {
+			return fmt.Errorf("overwrite requires onConflict overwrite")
+		}
+		return nil
+	case conflictOverwrite:
+	default:
+		return fmt.Errorf("invalid onConflict: %s (must be fail, retry, overwrite, skip or dlq)", c.OnConflict)
+	}
+	if len(c.Overwrite.Headers) == 0 && len(c.Overwrite.QueryParams) == 0 {
+		return fmt.Errorf("onConflict overwrite requires overwrite.headers or overwrite.queryParams")
+	}
+	return nil
+}
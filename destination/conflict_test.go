@@ -0,0 +1,125 @@
+package destination_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+// conflictEndpoint answers 409 Conflict unless the request is forced with the
+// force query parameter or the X-Force header, it records the request URIs
+type conflictEndpoint struct {
+	*httptest.Server
+	alwaysConflict bool
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newConflictEndpoint(t *testing.T, alwaysConflict bool) *conflictEndpoint {
+	e := &conflictEndpoint{alwaysConflict: alwaysConflict}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		e.mu.Lock()
+		e.requests = append(e.requests, r.URL.RequestURI()+" "+r.Header.Get("X-Force"))
+		e.mu.Unlock()
+
+		forced := r.URL.Query().Get("force") == "true" || r.Header.Get("X-Force") == "true"
+		if e.alwaysConflict || !forced {
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// Requests returns the request URIs with their X-Force header in order
+func (e *conflictEndpoint) Requests() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.requests...)
+}
+
+func TestConflictOverwrite(t *testing.T) {
+	testCases := []struct {
+		name           string
+		settings       map[string]string
+		alwaysConflict bool
+		wantErr        bool
+		want           []string
+	}{{
+		name:     "query parameters",
+		settings: map[string]string{"overwrite.queryParams.force": "true"},
+		want:     []string{"/documents ", "/documents?force=true "},
+	}, {
+		name:     "headers",
+		settings: map[string]string{"overwrite.headers.X-Force": "true"},
+		want:     []string{"/documents ", "/documents true"},
+	}, {
+		name:           "forced request conflicts again",
+		settings:       map[string]string{"overwrite.queryParams.force": "true"},
+		alwaysConflict: true,
+		wantErr:        true,
+		want:           []string{"/documents ", "/documents?force=true "},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			ctx := context.Background()
+			endpoint := newConflictEndpoint(t, tc.alwaysConflict)
+			settings := map[string]string{
+				"url":        endpoint.URL + "/documents",
+				"retry.max":  "0",
+				"onConflict": "overwrite",
+			}
+			for k, v := range tc.settings {
+				settings[k] = v
+			}
+			dest := openDestination(t, settings)
+
+			// The conflicting request is resent forced, only the first request isn't
+			n, err := dest.Write(ctx, testRecords("1"))
+			is.Equal(err != nil, tc.wantErr)
+			if !tc.wantErr {
+				is.Equal(n, 1)
+			}
+			is.Equal(endpoint.Requests(), tc.want)
+		})
+	}
+}
+
+func TestConflictActions(t *testing.T) {
+	testCases := []struct {
+		onConflict string
+		wantErr    bool
+	}{
+		{onConflict: "fail", wantErr: true},
+		{onConflict: "dlq", wantErr: true},
+		{onConflict: "skip"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.onConflict, func(t *testing.T) {
+			is := is.New(t)
+			ctx := context.Background()
+			endpoint := newConflictEndpoint(t, true)
+			dest := openDestination(t, map[string]string{
+				"url":        endpoint.URL + "/documents",
+				"retry.max":  "3",
+				"onConflict": tc.onConflict,
+			})
+
+			// None of the actions retries the request
+			n, err := dest.Write(ctx, testRecords("1"))
+			is.Equal(err != nil, tc.wantErr)
+			is.Equal(n == 1, !tc.wantErr)
+			is.Equal(len(endpoint.Requests()), 1)
+		})
+	}
+}
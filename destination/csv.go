@@ -0,0 +1,108 @@
+package destination
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// csvColumn is a CSV column and the payload field it is filled from
+type csvColumn struct {
+	name string
+	path *jsonpath.Path
+}
+
+// csvEncoder renders a batch of records as CSV, one row per record
+type csvEncoder struct {
+	columns   []csvColumn
+	header    bool
+	delimiter rune
+	redactor  *fieldRedactor // nil without redactFields
+}
+
+// newCSVEncoder parses the column mapping. Columns are "name=$.path", or just
+// "name" to read the top-level field of the same name. Payloads are redacted
+// with redactor before the columns are read.
+func newCSVEncoder(cfg CSVConfig, redactor *fieldRedactor) (*csvEncoder, error) {
+	if len(cfg.Columns) == 0 {
+		return nil, fmt.Errorf("columns are required")
+	}
+
+	delimiter, size := utf8.DecodeRuneInString(cfg.Delimiter)
+	if size == 0 || size != len(cfg.Delimiter) || delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+		return nil, fmt.Errorf("invalid delimiter %q (must be a single character other than a quote or line break)", cfg.Delimiter)
+	}
+
+	e := &csvEncoder{header: cfg.Header, delimiter: delimiter, redactor: redactor}
+	for _, column := range cfg.Columns {
+		name, expr, ok := strings.Cut(column, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			expr = name
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid column %q: empty name", column)
+		}
+		path, err := jsonpath.Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid column %q: %w", column, err)
+		}
+		e.columns = append(e.columns, csvColumn{name: name, path: path})
+	}
+	return e, nil
+}
+
+// Encode renders the records as CSV. Payloads must be JSON objects, fields
+// missing from a payload are left empty and nested values are JSON encoded.
+func (e *csvEncoder) Encode(records []opencdc.Record, usePayloadAfter bool) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = e.delimiter
+
+	row := make([]string, len(e.columns))
+	if e.header {
+		for i, column := range e.columns {
+			row[i] = column.name
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	for n, record := range records {
+		payload := record.Payload.Before
+		if usePayloadAfter && record.Payload.After != nil {
+			payload = record.Payload.After
+		}
+		if payload == nil {
+			return nil, fmt.Errorf("record %d has no payload", n)
+		}
+
+		var doc any
+		if err := json.Unmarshal(payload.Bytes(), &doc); err != nil {
+			return nil, fmt.Errorf("record %d: payload is not JSON: %w", n, err)
+		}
+		if e.redactor != nil {
+			doc = e.redactor.apply(doc)
+		}
+		for i, column := range e.columns {
+			value, _ := column.path.Get(doc)
+			row[i] = jsonpath.Stringify(value)
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
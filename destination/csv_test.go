@@ -0,0 +1,97 @@
+package destination
+
+import (
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/matryer/is"
+)
+
+func TestCSVEncoderEncode(t *testing.T) {
+	records := []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.StructuredData{
+			"id":   1,
+			"name": "Jo, Jr.",
+			"user": map[string]any{"email": "jo@example.com", "roles": []any{"admin"}},
+		}},
+	}, {
+		Payload: opencdc.Change{After: opencdc.RawData(`{"id":2,"name":"say \"hi\"\nbye","ssn":"123"}`)},
+	}}
+
+	testCases := []struct {
+		name    string
+		config  CSVConfig
+		redact  []string
+		want    string
+		wantErr bool
+	}{{
+		name:   "header and paths",
+		config: CSVConfig{Columns: []string{"id", "name", "email=$.user.email"}, Header: true, Delimiter: ","},
+		want:   "id,name,email\n1,\"Jo, Jr.\",jo@example.com\n2,\"say \"\"hi\"\"\nbye\",\n",
+	}, {
+		name:   "no header",
+		config: CSVConfig{Columns: []string{"id"}, Delimiter: ","},
+		want:   "1\n2\n",
+	}, {
+		name:   "nested values as JSON",
+		config: CSVConfig{Columns: []string{"roles=$.user.roles"}, Delimiter: ";"},
+		want:   "\"[\"\"admin\"\"]\"\n\n",
+	}, {
+		name:   "multi-byte delimiter",
+		config: CSVConfig{Columns: []string{"id", "name"}, Delimiter: "¦"},
+		want:   "1¦Jo, Jr.\n2¦\"say \"\"hi\"\"\nbye\"\n",
+	}, {
+		name:   "redacted fields",
+		config: CSVConfig{Columns: []string{"id", "ssn"}, Delimiter: ","},
+		redact: []string{"$.ssn"},
+		want:   "1,\n2,***\n",
+	}, {
+		name:    "no columns",
+		config:  CSVConfig{Delimiter: ","},
+		wantErr: true,
+	}, {
+		name:    "quote delimiter",
+		config:  CSVConfig{Columns: []string{"id"}, Delimiter: `"`},
+		wantErr: true,
+	}, {
+		name:    "several characters",
+		config:  CSVConfig{Columns: []string{"id"}, Delimiter: ",;"},
+		wantErr: true,
+	}, {
+		name:    "empty column name",
+		config:  CSVConfig{Columns: []string{"=$.id"}, Delimiter: ","},
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			var redactor *fieldRedactor
+			if len(tc.redact) > 0 {
+				var err error
+				redactor, err = newFieldRedactor(tc.redact, "***", "")
+				is.NoErr(err)
+			}
+			e, err := newCSVEncoder(tc.config, redactor)
+			if tc.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			got, err := e.Encode(records, true)
+			is.NoErr(err)
+			is.Equal(string(got), tc.want)
+		})
+	}
+}
+
+func TestCSVEncoderEncodeInvalidPayload(t *testing.T) {
+	is := is.New(t)
+	e, err := newCSVEncoder(CSVConfig{Columns: []string{"id"}, Delimiter: ","}, nil)
+	is.NoErr(err)
+
+	_, err = e.Encode([]opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData("id=1")}}}, true)
+	is.True(err != nil)
+	_, err = e.Encode([]opencdc.Record{{}}, true)
+	is.True(err != nil)
+}
@@ -0,0 +1,142 @@
+package destination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// dedupEntry is a delivered key and when it leaves the dedup window
+type dedupEntry struct {
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires,omitzero"` // Zero if the key only expires by eviction
+}
+
+// dedupCache remembers the keys of recently delivered records so that records
+// redelivered upstream within the window are not sent again. It is optionally
+// persisted to a local file.
+type dedupCache struct {
+	path string // Empty to keep the keys in memory only
+	size int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	keys  map[string]struct{}
+	order []dedupEntry // Oldest first, all keys have the same TTL so this is also expiry order
+	dirty bool
+}
+
+// loadDedupCache creates a dedup cache, loading the keys still in the window
+// from path if it is set and the file exists
+func loadDedupCache(path string, size int, ttl time.Duration) (*dedupCache, error) {
+	c := &dedupCache{
+		path: path,
+		size: size,
+		ttl:  ttl,
+		keys: make(map[string]struct{}, size),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dedup state: %w", err)
+	}
+
+	var entries []dedupEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse dedup state %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		if _, ok := c.keys[entry.Key]; !ok {
+			c.keys[entry.Key] = struct{}{}
+			c.order = append(c.order, entry)
+		}
+	}
+	c.prune(time.Now())
+	c.evict()
+	c.dirty = false
+
+	return c, nil
+}
+
+// Seen reports whether a record with the key was delivered within the window
+func (c *dedupCache) Seen(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune(time.Now())
+	_, ok := c.keys[key]
+	return ok
+}
+
+// Add records the key as delivered
+func (c *dedupCache) Add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.prune(now)
+	if _, ok := c.keys[key]; ok {
+		return
+	}
+
+	entry := dedupEntry{Key: key}
+	if c.ttl > 0 {
+		entry.Expires = now.Add(c.ttl)
+	}
+	c.keys[key] = struct{}{}
+	c.order = append(c.order, entry)
+	c.evict()
+	c.dirty = true
+}
+
+// Flush persists the keys if they changed, replacing the file atomically
+func (c *dedupCache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.order)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dedup state: %w", err)
+	}
+	if err := writeFileAtomic(c.path, data); err != nil {
+		return fmt.Errorf("failed to write dedup state: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// prune drops the keys that left the window, the lock must be held
+func (c *dedupCache) prune(now time.Time) {
+	n := 0
+	for n < len(c.order) && !c.order[n].Expires.IsZero() && now.After(c.order[n].Expires) {
+		delete(c.keys, c.order[n].Key)
+		n++
+	}
+	if n > 0 {
+		c.order = c.order[n:]
+		c.dirty = true
+	}
+}
+
+// evict drops the oldest keys beyond the size, the lock must be held
+func (c *dedupCache) evict() {
+	for len(c.order) > c.size {
+		delete(c.keys, c.order[0].Key)
+		c.order = c.order[1:]
+		c.dirty = true
+	}
+}
@@ -0,0 +1,130 @@
+package destination_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/matryer/is"
+)
+
+func TestDedupKeys(t *testing.T) {
+	withMetadata := func(id, key string, metadata opencdc.Metadata) opencdc.Record {
+		record := testRecord(id)
+		record.Key = opencdc.RawData(key)
+		record.Metadata = metadata
+		return record
+	}
+
+	testCases := []struct {
+		name     string
+		settings map[string]string
+		records  []opencdc.Record
+		want     []string
+	}{{
+		name: "record key",
+		records: []opencdc.Record{
+			withMetadata("1", "a", nil),
+			withMetadata("2", "b", nil),
+			withMetadata("3", "a", nil),
+		},
+		want: testBodies("1", "2"),
+	}, {
+		name:     "idempotency key",
+		settings: map[string]string{"dedup.key": "{{.Metadata.idempotencyKey}}"},
+		records: []opencdc.Record{
+			withMetadata("1", "a", opencdc.Metadata{"idempotencyKey": "x"}),
+			withMetadata("2", "a", opencdc.Metadata{"idempotencyKey": "y"}),
+			withMetadata("3", "b", opencdc.Metadata{"idempotencyKey": "x"}),
+		},
+		want: testBodies("1", "2"),
+	}, {
+		name:     "empty keys are always sent",
+		settings: map[string]string{"dedup.key": "{{.Metadata.idempotencyKey}}"},
+		records: []opencdc.Record{
+			withMetadata("1", "a", nil),
+			withMetadata("2", "a", nil),
+		},
+		want: testBodies("1", "2"),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			endpoint := newTestEndpoint(t)
+			settings := map[string]string{"url": endpoint.URL, "dedup.enabled": "true"}
+			for key, value := range tc.settings {
+				settings[key] = value
+			}
+			dest := openDestination(t, settings)
+
+			// Records are acked whether they are sent or skipped
+			n, err := dest.Write(context.Background(), tc.records)
+			is.NoErr(err)
+			is.Equal(n, len(tc.records))
+			is.Equal(endpoint.Bodies(), tc.want)
+		})
+	}
+}
+
+func TestDedupWindowExpires(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newTestEndpoint(t)
+	dest := openDestination(t, map[string]string{
+		"url":           endpoint.URL,
+		"dedup.enabled": "true",
+		"dedup.ttl":     "100ms",
+	})
+
+	_, err := dest.Write(ctx, testRecords("1"))
+	is.NoErr(err)
+	_, err = dest.Write(ctx, testRecords("1"))
+	is.NoErr(err)
+	is.Equal(len(endpoint.Bodies()), 1)
+
+	// The key left the window, the record is sent again
+	time.Sleep(150 * time.Millisecond)
+	_, err = dest.Write(ctx, testRecords("1"))
+	is.NoErr(err)
+	is.Equal(len(endpoint.Bodies()), 2)
+}
+
+func TestDedupWindowEvictsOldestKeys(t *testing.T) {
+	is := is.New(t)
+	endpoint := newTestEndpoint(t)
+	dest := openDestination(t, map[string]string{
+		"url":           endpoint.URL,
+		"dedup.enabled": "true",
+		"dedup.size":    "2",
+	})
+
+	// 1 was evicted by 3 and is sent again, 3 is still remembered
+	_, err := dest.Write(context.Background(), testRecords("1", "2", "3", "3", "1"))
+	is.NoErr(err)
+	is.Equal(endpoint.Bodies(), testBodies("1", "2", "3", "1"))
+}
+
+func TestDedupStatePersisted(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newTestEndpoint(t)
+	settings := map[string]string{
+		"url":           endpoint.URL,
+		"dedup.enabled": "true",
+		"dedup.path":    filepath.Join(t.TempDir(), "dedup.json"),
+	}
+
+	dest := newDestination(t, settings)
+	_, err := dest.Write(ctx, testRecords("1", "2"))
+	is.NoErr(err)
+	is.NoErr(dest.Teardown(ctx))
+
+	// Keys delivered before the restart are still in the window
+	dest = openDestination(t, settings)
+	_, err = dest.Write(ctx, testRecords("2", "3"))
+	is.NoErr(err)
+	is.Equal(endpoint.Bodies(), testBodies("1", "2", "3"))
+}
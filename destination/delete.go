@@ -0,0 +1,73 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	stdhttp "net/http"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// Strategies for delete records
+const (
+	deleteStrategyPayload   = "payload"
+	deleteStrategyDelete    = "delete"
+	deleteStrategyTombstone = "tombstone"
+	deleteStrategySkip      = "skip"
+)
+
+// DeleteConfig configures the requests of delete records
+type DeleteConfig struct {
+	// URL is a template of the resource URL DELETE requests are sent to,
+	// e.g. https://api.example.com/users/{{.Key}}. Empty sends them to the
+	// URL of the record.
+	URL string `json:"url"`
+	// Tombstone is a template of the tombstone body. Empty sends
+	// {"key":<key>,"deleted":true}.
+	Tombstone string `json:"tombstone"`
+}
+
+// methodKey is the context key of a method replacing the configured one
+type methodKey struct{}
+
+// withMethod returns a context sending requests with the method
+func withMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodKey{}, method)
+}
+
+// isDelete reports whether the record is a delete handled by the delete
+// strategy
+func (d *Destination) isDelete(record opencdc.Record, strategy string) bool {
+	return record.Operation == opencdc.OperationDelete && d.config.DeleteStrategy == strategy
+}
+
+// tombstoneBody renders the tombstone body of a delete record
+func (d *Destination) tombstoneBody(record opencdc.Record) ([]byte, error) {
+	data := newTemplateData(record, d.config.templateOptions())
+	if d.tombstone != nil {
+		body, err := d.tombstone.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(body), nil
+	}
+	return json.Marshal(map[string]any{"key": data.Key, "deleted": true})
+}
+
+// deleteRequest returns the context and URL of the DELETE request of a
+// delete record, base is the URL of the record
+func (d *Destination) deleteRequest(ctx context.Context, record opencdc.Record, base string) (context.Context, string, error) {
+	ctx = withMethod(ctx, stdhttp.MethodDelete)
+	if d.deleteURL == nil {
+		return ctx, base, nil
+	}
+	target, err := d.deleteURL.Render(newTemplateData(record, d.config.templateOptions()))
+	if err != nil {
+		return ctx, "", err
+	}
+	if target == "" {
+		return ctx, "", fmt.Errorf("delete.url rendered an empty URL")
+	}
+	return ctx, target, nil
+}
@@ -0,0 +1,137 @@
+package destination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// deliveryLog remembers the positions of the most recently delivered records
+// so that records redelivered after a failed batch or a restart are not sent
+// again. It is persisted to a local file.
+type deliveryLog struct {
+	path      string
+	size      int
+	positions map[string]struct{}
+	order     []string // Oldest first, evicted once size is exceeded
+	dirty     bool
+}
+
+// loadDeliveryLog loads the delivery log from path, starting empty if the
+// file does not exist yet
+func loadDeliveryLog(path string, size int) (*deliveryLog, error) {
+	l := &deliveryLog{
+		path:      path,
+		size:      size,
+		positions: make(map[string]struct{}, size),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delivery state: %w", err)
+	}
+
+	var positions [][]byte
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("failed to parse delivery state %s: %w", path, err)
+	}
+	for _, pos := range positions {
+		l.Add(pos)
+	}
+	l.dirty = false
+
+	return l, nil
+}
+
+// Delivered reports whether the record at position was already delivered
+func (l *deliveryLog) Delivered(pos opencdc.Position) bool {
+	_, ok := l.positions[string(pos)]
+	return ok
+}
+
+// Add records the position as delivered
+func (l *deliveryLog) Add(pos opencdc.Position) {
+	key := string(pos)
+	if _, ok := l.positions[key]; ok {
+		return
+	}
+
+	l.positions[key] = struct{}{}
+	l.order = append(l.order, key)
+	for len(l.order) > l.size {
+		delete(l.positions, l.order[0])
+		l.order = l.order[1:]
+	}
+	l.dirty = true
+}
+
+// Flush persists the log if it changed, replacing the file atomically
+func (l *deliveryLog) Flush() error {
+	if !l.dirty {
+		return nil
+	}
+
+	positions := make([][]byte, len(l.order))
+	for i, key := range l.order {
+		positions[i] = []byte(key)
+	}
+	data, err := json.Marshal(positions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery state: %w", err)
+	}
+
+	if err := writeFileAtomic(l.path, data); err != nil {
+		return fmt.Errorf("failed to write delivery state: %w", err)
+	}
+
+	l.dirty = false
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data, writing a temporary
+// file first so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	// The data must be on disk before the rename replaces the previous file
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes the entries of a directory, so that a file renamed into it
+// is still there after a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
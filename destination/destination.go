@@ -1,19 +1,39 @@
 package destination
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	stdhttp "net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
 
 	"github.com/conduitio/conduit-commons/config"
 	"github.com/conduitio/conduit-commons/opencdc"
 	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/dev-in-black/connector-http/internal/audit"
 	"github.com/dev-in-black/connector-http/internal/auth"
+	"github.com/dev-in-black/connector-http/internal/chaos"
+	connerrors "github.com/dev-in-black/connector-http/internal/errors"
+	"github.com/dev-in-black/connector-http/internal/grpc"
 	"github.com/dev-in-black/connector-http/internal/http"
 	"github.com/dev-in-black/connector-http/internal/kafka"
+	"github.com/dev-in-black/connector-http/internal/metrics"
+	"github.com/dev-in-black/connector-http/internal/secrets"
+	"github.com/dev-in-black/connector-http/internal/sqlsink"
+	"github.com/dev-in-black/connector-http/internal/wasm"
 )
 
+// errPreconditionFailed marks records rejected because the resource changed
+// since the ETag they carry was read
+var errPreconditionFailed = errors.New("precondition failed, the resource was modified concurrently")
+
 // Destination implements the Conduit destination interface for HTTP endpoints
 type Destination struct {
 	sdk.UnimplementedDestination
@@ -21,8 +41,68 @@ type Destination struct {
 	config        Config
 	httpClient    *http.Client
 	authManager   auth.Manager
+	cookieJar     stdhttp.CookieJar // nil without cookie jar
 	retryEngine   *http.RetryEngine
-	kafkaProducer *kafka.Producer
+	kafkaProducer *kafka.Producer // Also in publishers, for captures
+	publishers    []*publisher
+	statusRules   http.StatusRules
+	bodyPredicate *http.BodyPredicate
+	hedger        *http.Hedger
+	queryParams   map[string]*recordTemplate
+	bodyTemplate  *recordTemplate
+	bodyJQ        *jqTransform
+	deleteURL     *recordTemplate   // Set to send DELETE requests to the rendered URL
+	routes        operationRoutes   // Method and path of the requests of each operation
+	tombstone     *recordTemplate   // Set to render tombstone bodies of deletes
+	csvEncoder    *csvEncoder       // Set to send batches as one CSV request
+	esBulk        *esBulkEncoder    // Set to send batches as one _bulk request
+	envelope      *envelopeEncoder  // Set to send batches as one JSON envelope
+	items         *itemMapper       // Set to ack batches per response item
+	poller        *poller           // Set to ack accepted operations once they complete
+	captures      []headerCapture   // Response headers published as metadata
+	callbacks     *callbackListener // Set if results are called back
+	redactor      *fieldRedactor    // nil without redactFields
+	skipFilter    *recordFilter
+	transformer   *wasm.Transformer
+	deliveryLog   *deliveryLog
+	capture       *captureSink    // Set if requests of sampled records are captured
+	shadow        *shadowSender   // Set if sampled requests are shadowed
+	auditLog      *audit.Log      // Set if requests are audited
+	responseDB    *sqlsink.Writer // Set with responseSink database
+	stream        *ndjsonStream   // Open stream of stream mode, nil until the first write
+	grpcTransport *grpc.Transport // Set if records are sent as gRPC calls
+
+	// Keys of delivered records, kept across config updates
+	dedup    *dedupCache
+	dedupKey *recordTemplate // nil to use the record key
+
+	// Records are buffered during endpoint outages and drained in the
+	// background, the buffer is kept across config updates
+	buffer          *recordBuffer
+	stopBufferDrain context.CancelFunc
+	bufferWaiters   bufferWaiters // Write calls waiting for room in the buffer
+
+	// Secret references are re-resolved on reauth and periodically
+	secrets           *secrets.Resolver
+	secretValues      map[string]string
+	authMu            sync.Mutex // Guards authManager and secretValues
+	stopSecretRefresh context.CancelFunc
+
+	// Endpoint counters of load balancing and the connection pool are
+	// logged periodically
+	stopEndpointStats context.CancelFunc
+	stopPoolStats     context.CancelFunc
+
+	// reloadMu is held for reading by Write and for writing while an updated
+	// config is applied, so in-flight requests drain first
+	reloadMu   sync.RWMutex
+	opened     bool
+	drainState *drainState // Records in flight, drained by Teardown
+
+	// Counters are kept across config updates, the endpoint serving them
+	// is restarted
+	metrics       *destinationMetrics
+	metricsServer *metrics.Server // Set if metrics are served
 }
 
 // NewDestination creates a new HTTP destination
@@ -41,10 +121,22 @@ func (d *Destination) LifecycleOnCreated(ctx context.Context, cfg config.Config)
 	return nil
 }
 
-// LifecycleOnUpdated is called when the connector configuration is updated
+// LifecycleOnUpdated is called when the connector configuration is updated.
+// Before Open the updated config is simply used by Open; an open destination
+// rebuilds its components once the writes in flight completed.
 func (d *Destination) LifecycleOnUpdated(ctx context.Context, configBefore, configAfter config.Config) error {
-	// No special handling needed for config updates
-	return nil
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	if !d.opened {
+		return nil
+	}
+
+	var cfg Config
+	if err := sdk.Util.ParseConfig(ctx, configAfter, &cfg, nil); err != nil {
+		return fmt.Errorf("failed to parse updated config: %w", err)
+	}
+	return d.reconfigure(ctx, cfg)
 }
 
 // LifecycleOnDeleted is called when the connector is deleted
@@ -57,48 +149,260 @@ func (d *Destination) LifecycleOnDeleted(ctx context.Context, cfg config.Config)
 func (d *Destination) Open(ctx context.Context) error {
 	sdk.Logger(ctx).Info().Msg("Opening HTTP destination")
 
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	if d.metrics == nil {
+		d.metrics = newDestinationMetrics(d)
+	}
+	if err := d.open(ctx); err != nil {
+		return err
+	}
+	d.opened = true
+	d.drainState = newDrainState()
+
+	sdk.Logger(ctx).Info().Msg("HTTP destination opened successfully")
+	return nil
+}
+
+// reconfigure replaces the components of the open destination with ones built
+// from cfg. If cfg can't be applied, the previous config is restored.
+func (d *Destination) reconfigure(ctx context.Context, cfg Config) error {
+	sdk.Logger(ctx).Info().Msg("Applying updated HTTP destination config")
+
+	// Wait for a running secret refresh
+	d.authMu.Lock()
+	defer d.authMu.Unlock()
+
+	// Buffered records would be lost, the buffer can't be replaced until it drained
+	if cfg.Buffer != d.config.Buffer && d.buffer != nil {
+		if n := d.buffer.Len(); n > 0 {
+			return fmt.Errorf("failed to apply updated config: buffer settings can't change while %d records are buffered", n)
+		}
+	}
+
+	prev := d.config
+	d.close(ctx)
+	if cfg.Buffer != prev.Buffer {
+		d.closeBuffer(ctx)
+	}
+	if cfg.Dedup != prev.Dedup {
+		d.closeDedup(ctx)
+	}
+	d.config = cfg
+	if err := d.open(ctx); err != nil {
+		d.close(ctx)
+		if cfg.Buffer != prev.Buffer {
+			d.closeBuffer(ctx)
+		}
+		if cfg.Dedup != prev.Dedup {
+			d.closeDedup(ctx)
+		}
+		d.config = prev
+		if reopenErr := d.open(ctx); reopenErr != nil {
+			d.opened = false
+			return fmt.Errorf("failed to apply updated config: %w (restoring previous config failed: %v)", err, reopenErr)
+		}
+		return fmt.Errorf("failed to apply updated config, keeping previous config: %w", err)
+	}
+
+	sdk.Logger(ctx).Info().Msg("Updated HTTP destination config applied")
+	return nil
+}
+
+// open builds the components of the destination from its config
+func (d *Destination) open(ctx context.Context) error {
 	// Load custom headers from environment
 	d.config.LoadEnvHeaders()
 
 	// Validate configuration
 	if err := d.config.Validate(ctx); err != nil {
-		return fmt.Errorf("config validation failed: %w", err)
+		return connerrors.WithCategory(fmt.Errorf("config validation failed: %w", err), connerrors.ErrValidation)
 	}
 
 	sdk.Logger(ctx).Info().
 		Str("url", d.config.URL).
 		Str("method", d.config.Method).
-		Str("authType", d.config.AuthType).
+		Strs("authType", d.config.Auth.Type).
 		Msg("HTTP destination configured")
 
-	// Initialize authentication manager
-	authConfig := auth.Config{
-		Type:          d.config.AuthType,
-		BasicUsername: d.config.BasicUsername,
-		BasicPassword: d.config.BasicPassword,
-		BearerToken:   d.config.BearerToken,
+	// Resolve credentials referencing Kubernetes or AWS secrets
+	credentials := d.config
+	if d.config.hasSecretReferences() {
+		d.secrets = secrets.NewResolver()
+		var err error
+		credentials, err = d.config.withSecrets(ctx, d.secrets)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secrets: %w", err)
+		}
 	}
+	d.secretValues = credentials.secretValues()
 
-	if d.config.AuthType == "oauth2" {
-		authConfig.OAuth2Config = &auth.OAuth2Config{
-			ClientID:     d.config.OAuth2ClientID,
-			ClientSecret: d.config.OAuth2ClientSecret,
-			TokenURL:     d.config.OAuth2TokenURL,
-			Scopes:       d.config.GetOAuth2Scopes(),
-		}
+	// Session cookies are kept in a jar shared by the login and the client
+	d.cookieJar = nil
+	if d.config.CookieJar || d.config.hasAuthType("session") {
+		d.cookieJar, _ = cookiejar.New(nil)
 	}
 
+	// Initialize authentication manager
 	var err error
-	d.authManager, err = auth.NewManager(authConfig)
+	d.authManager, err = newAuthManager(credentials, d.cookieJar)
 	if err != nil {
-		return fmt.Errorf("failed to create auth manager: %w", err)
+		return err
+	}
+
+	// Fetch credentials upfront so misconfigured auth fails at Open, session
+	// auth logs in before sending records
+	if d.config.Auth.Prefetch || d.config.hasAuthType("session") {
+		if err := d.authManager.Refresh(ctx); err != nil {
+			return fmt.Errorf("failed to prefetch credentials: %w", err)
+		}
 	}
 
 	// Initialize HTTP client
+	userAgent, err := d.config.userAgent()
+	if err != nil {
+		return err
+	}
+	hostAliases, err := d.config.hostAliases()
+	if err != nil {
+		return err
+	}
+	var localAddr net.IP
+	if d.config.LocalAddress != "" {
+		if localAddr, err = http.LocalIP(d.config.LocalAddress, d.config.IPFamily); err != nil {
+			return fmt.Errorf("invalid localAddress: %w", err)
+		}
+	}
 	httpConfig := http.Config{
-		Timeout:         d.config.Timeout,
-		MaxIdleConns:    d.config.MaxIdleConns,
-		MaxConnsPerHost: d.config.MaxConnsPerHost,
+		Timeout:             d.config.Timeout,
+		TimeoutPerMB:        d.config.TimeoutPerMB,
+		MaxTimeout:          d.config.MaxTimeout,
+		MaxIdleConns:        d.config.MaxIdleConns,
+		MaxConnsPerHost:     d.config.MaxConnsPerHost,
+		MaxRequestBodySize:  d.config.MaxRequestBodySize,
+		MaxBytesPerSecond:   d.config.MaxBytesPerSecond,
+		MaxResponseBodySize: d.config.Response.MaxBodySize,
+
+		DisableDecompression: !d.config.Response.Decompress,
+
+		ForceAttemptHTTP2:     d.config.ForceAttemptHTTP2,
+		DisableKeepAlives:     d.config.DisableKeepAlives,
+		KeepAlive:             d.config.KeepAlive,
+		IdleConnTimeout:       d.config.IdleConnTimeout,
+		DialTimeout:           d.config.DialTimeout,
+		TLSHandshakeTimeout:   d.config.TLS.HandshakeTimeout,
+		ResponseHeaderTimeout: d.config.ResponseHeaderTimeout,
+		ExpectContinueTimeout: d.config.ExpectContinueTimeout,
+		DNSCacheTTL:           d.config.DNSCacheTTL,
+		HostAliases:           hostAliases,
+		DNSServer:             d.config.DNSServer,
+		IPFamily:              d.config.IPFamily,
+		LocalAddr:             localAddr,
+		ProxyFromEnvironment:  d.config.ProxyFromEnvironment,
+		HTTP3:                 d.config.HTTPVersion == "h3",
+		Redirect:              d.config.redirectConfig(),
+		Guard:                 d.config.guardConfig(),
+		Signing:               credentials.signingConfig(),
+		Capture:               d.captureExchange,
+		CaptureBodySize:       d.config.Capture.MaxBodySize,
+		UserAgent:             userAgent,
+		Accept:                d.config.Accept,
+		CookieJar:             d.cookieJar,
+		UnixSocketPath:        d.config.GetUnixSocketPath(),
+		Interceptors:          d.config.Interceptors,
+		Logger:                logRequest,
+	}
+
+	if d.config.CoolDown.Threshold > 0 {
+		logger := sdk.Logger(ctx)
+		httpConfig.CoolDown = d.config.coolDownConfig()
+		httpConfig.CoolDown.OnCoolDown = func(host string, wait time.Duration) {
+			logger.Warn().
+				Str("host", host).
+				Dur("wait", wait).
+				Msg("Endpoint overloaded, pausing requests to it")
+		}
+	}
+
+	if len(d.config.Failover.URLs) > 0 {
+		logger := sdk.Logger(ctx)
+		httpConfig.Failover = d.config.failoverConfig()
+		httpConfig.Failover.OnSwitch = func(from, to string, reason error) {
+			if reason == nil {
+				logger.Info().Str("from", from).Str("to", to).Msg("Endpoint recovered, failing back to it")
+				return
+			}
+			logger.Warn().Err(reason).Str("from", from).Str("to", to).Msg("Endpoint failed, failing over")
+		}
+	}
+
+	if len(d.config.LoadBalance.URLs) > 0 {
+		logger := sdk.Logger(ctx)
+		httpConfig.Balance = d.config.balanceConfig()
+		httpConfig.Balance.OnBreaker = func(url string, open bool, reason error) {
+			if !open {
+				logger.Info().Str("endpoint", url).Msg("Circuit breaker closed, endpoint recovered")
+				return
+			}
+			logger.Warn().Err(reason).Str("endpoint", url).Msg("Circuit breaker opened, records go to the other endpoints")
+		}
+	}
+
+	// The audit interceptor copies request bodies, it is only set up if needed
+	if d.config.Audit.Path != "" {
+		httpConfig.Auditor = d.auditRequest
+	}
+
+	if d.config.RequestBuilderPlugin != "" {
+		httpConfig.RequestBuilder, err = http.LoadRequestBuilder(d.config.RequestBuilderPlugin)
+		if err != nil {
+			return fmt.Errorf("failed to load request builder plugin: %w", err)
+		}
+		sdk.Logger(ctx).Info().
+			Str("plugin", d.config.RequestBuilderPlugin).
+			Msg("Request builder plugin loaded")
+	}
+
+	if d.config.GRPC.Method != "" {
+		d.grpcTransport, err = grpc.NewTransport(ctx, grpc.Config{
+			URL:           d.config.URL,
+			Method:        d.config.GRPC.Method,
+			DescriptorSet: d.config.GRPC.DescriptorSet,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set up gRPC transport: %w", err)
+		}
+		httpConfig.Transport = d.grpcTransport
+		sdk.Logger(ctx).Info().
+			Str("method", d.config.GRPC.Method).
+			Msg("gRPC transport configured")
+	}
+
+	// Dry runs render requests instead of sending them
+	if d.config.DryRun {
+		httpConfig.Transport, err = newDryRunTransport(ctx, d.config)
+		if err != nil {
+			return fmt.Errorf("failed to set up dry run: %w", err)
+		}
+		sdk.Logger(ctx).Warn().Msg("Dry run enabled, requests are rendered without being sent")
+	}
+
+	// Fault injection is only compiled into builds with the chaos tag
+	faults, err := chaos.FromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid fault injection: %w", err)
+	}
+	if faults != nil {
+		httpConfig.Middlewares = append(httpConfig.Middlewares, faults)
+		sdk.Logger(ctx).Warn().Msg("Fault injection enabled, requests are delayed, dropped and failed at random")
+	}
+
+	switch {
+	case d.config.BatchBody.Format == "csv":
+		httpConfig.ContentType = "text/csv"
+	case d.config.BatchBody.Format == "es-bulk" || d.config.Stream.Enabled:
+		httpConfig.ContentType = "application/x-ndjson"
 	}
 
 	d.httpClient = http.NewClient(
@@ -108,132 +412,1124 @@ func (d *Destination) Open(ctx context.Context) error {
 		d.config.LoadedEnvHeaders(),
 	)
 
+	if err := d.parseRequestTemplates(credentials.RedactHashKey); err != nil {
+		return err
+	}
+
+	d.csvEncoder = nil
+	if d.config.BatchBody.Format == "csv" {
+		d.csvEncoder, err = newCSVEncoder(d.config.BatchBody.CSV, d.redactor)
+		if err != nil {
+			return fmt.Errorf("failed to create CSV encoder: %w", err)
+		}
+	}
+	d.envelope = nil
+	if d.config.BatchBody.Format == "json-array" || d.config.BatchBody.Format == "splunk-hec" {
+		d.envelope = &envelopeEncoder{format: d.config.BatchBody.Format}
+	}
+	d.esBulk = nil
+	if d.config.BatchBody.Format == "es-bulk" {
+		d.esBulk, err = newESBulkEncoder(d.config.BatchBody.ESBulk, d.config.templateFuncs())
+		if err != nil {
+			return fmt.Errorf("failed to create bulk encoder: %w", err)
+		}
+	}
+
+	d.items, err = newItemMapper(d.config.BatchBody.Items)
+	if err != nil {
+		return fmt.Errorf("failed to parse batchBody.items: %w", err)
+	}
+	d.captures, err = parseCaptureHeaders(d.config.CaptureHeaders)
+	if err != nil {
+		return err
+	}
+	d.poller, err = newPoller(d.config.Poll)
+	if err != nil {
+		return fmt.Errorf("failed to parse poll: %w", err)
+	}
+
+	d.skipFilter, err = newRecordFilter(d.config.SkipFilter)
+	if err != nil {
+		return fmt.Errorf("failed to create skip filter: %w", err)
+	}
+
+	// Parse status code routing rules
+	d.statusRules, err = http.ParseStatusRules(d.config.statusRules())
+	if err != nil {
+		return fmt.Errorf("failed to parse status rules: %w", err)
+	}
+
+	d.bodyPredicate, err = http.NewBodyPredicate(d.config.bodyPredicateConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create success body predicate: %w", err)
+	}
+
 	// Initialize retry engine
 	retryConfig := http.RetryConfig{
-		MaxRetries:        d.config.MaxRetries,
-		BackoffBase:       d.config.RetryBackoffBase,
-		BackoffMax:        d.config.RetryBackoffMax,
-		RetryOn5xx:        d.config.RetryOn5xx,
-		RetryOn429:        d.config.RetryOn429,
-		RetryOnNetworkErr: d.config.RetryOnNetworkErr,
+		MaxRetries:        d.config.Retry.Max,
+		MaxRetryDuration:  d.config.Retry.MaxDuration,
+		BackoffBase:       d.config.Retry.BackoffBase,
+		BackoffMax:        d.config.Retry.BackoffMax,
+		RetryOn5xx:        d.config.Retry.On5xx,
+		RetryOn429:        d.config.Retry.On429,
+		RetryOnNetworkErr: d.config.Retry.OnNetworkError,
+		StatusRules:       d.statusRules,
+		BodyPredicate:     d.bodyPredicate,
+		AtMostOnce:        d.config.IsAtMostOnce(),
+		ReauthOn401:       d.config.Auth.ReauthOn401,
+		ReauthOn403:       d.config.Auth.ReauthOn403,
+		Hooks:             http.MultiRetryHooks(retryLogHooks, d.metrics.retryHooks()),
+	}
+	if d.config.Auth.ReauthOn401 || d.config.Auth.ReauthOn403 {
+		retryConfig.Reauth = d.reauth
 	}
 
 	d.retryEngine = http.NewRetryEngine(retryConfig)
 
-	// Initialize Kafka producer if enabled
-	if d.config.KafkaEnabled {
-		kafkaConfig := kafka.Config{
-			Brokers:           d.config.GetKafkaBrokers(),
-			Topic:             d.config.KafkaTopic,
-			ClientID:          d.config.KafkaClientID,
-			Compression:       d.config.KafkaCompression,
-			EnableIdempotence: d.config.KafkaEnableIdempotence,
-			SASLEnabled:       d.config.KafkaSASLEnabled,
-			SASLMechanism:     d.config.KafkaSASLMechanism,
-			SASLUsername:      d.config.KafkaSASLUsername,
-			SASLPassword:      d.config.KafkaSASLPassword,
-			TLSEnabled:        d.config.KafkaTLSEnabled,
+	// Load delivery state if tracking is enabled
+	if d.config.DeliveryStateFile != "" {
+		d.deliveryLog, err = loadDeliveryLog(d.config.DeliveryStateFile, d.config.DeliveryStateSize)
+		if err != nil {
+			return fmt.Errorf("failed to load delivery state: %w", err)
 		}
+	}
 
-		var err error
-		d.kafkaProducer, err = kafka.NewProducer(ctx, kafkaConfig)
+	// Remember delivered keys, unless kept from before a config update
+	if d.config.Dedup.Enabled && d.dedup == nil {
+		d.dedup, err = loadDedupCache(d.config.Dedup.Path, d.config.Dedup.Size, d.config.Dedup.TTL)
 		if err != nil {
-			return fmt.Errorf("failed to create Kafka producer: %w", err)
+			return fmt.Errorf("failed to load dedup state: %w", err)
+		}
+	}
+	d.dedupKey = nil
+	if d.config.Dedup.Key != "" {
+		d.dedupKey, err = parseRecordTemplate("dedup.key", d.config.Dedup.Key, d.config.templateFuncs())
+		if err != nil {
+			return fmt.Errorf("failed to parse dedup key: %w", err)
+		}
+	}
+
+	// Buffer records during endpoint outages, unless kept from before a config update
+	if d.config.Buffer.Enabled && d.buffer == nil {
+		d.buffer, err = newRecordBuffer(d.config.Buffer.Path, d.config.Buffer.MemorySize, d.config.Buffer.MaxSize)
+		if err != nil {
+			return fmt.Errorf("failed to create record buffer: %w", err)
+		}
+		if n := d.buffer.Len(); n > 0 {
+			sdk.Logger(ctx).Info().Int("records", n).Msg("Delivering records buffered before the restart")
 		}
 
+		drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		d.stopBufferDrain = cancel
+		go d.drainBuffer(drainCtx, d.buffer, d.config.Buffer.RetryInterval)
+	}
+
+	// Initialize request hedging if enabled
+	if d.config.HedgeDelay > 0 {
+		d.hedger = http.NewHedger(d.config.HedgeDelay, d.statusRules)
+	}
+
+	// Initialize the response transform if configured
+	if d.config.Response.Transform.WasmPath != "" {
+		d.transformer, err = wasm.NewTransformer(ctx, d.config.Response.Transform.WasmPath)
+		if err != nil {
+			return fmt.Errorf("failed to load response transform: %w", err)
+		}
 		sdk.Logger(ctx).Info().
-			Str("topic", d.config.KafkaTopic).
-			Strs("brokers", d.config.GetKafkaBrokers()).
-			Msg("Kafka producer initialized")
+			Str("wasmPath", d.config.Response.Transform.WasmPath).
+			Msg("Response transform loaded")
+	}
+
+	// Connect to the message brokers responses are published to
+	if err := d.openPublishers(ctx, credentials); err != nil {
+		return err
+	}
+	if err := d.openResponseDatabase(ctx, credentials); err != nil {
+		return err
+	}
+
+	// Capture the requests of sampled records
+	if d.config.Capture.SampleRate > 0 {
+		d.capture, err = newCaptureSink(ctx, d.config.Capture, d.config.SyncOnWrite.syncPolicy(), d.config.apiKeyHeaders(), d.kafkaProducer)
+		if err != nil {
+			return fmt.Errorf("failed to create capture: %w", err)
+		}
+	}
+
+	// Log every request to the audit log
+	if d.config.Audit.Path != "" {
+		policy := d.config.SyncOnWrite.syncPolicy()
+		if d.config.Audit.Sync {
+			policy.Lines = 1
+		}
+		var repaired int64
+		d.auditLog, repaired, err = audit.Open(d.config.Audit.Path, policy)
+		if err != nil {
+			return err
+		}
+		if repaired > 0 {
+			sdk.Logger(ctx).Warn().
+				Str("path", d.config.Audit.Path).
+				Int64("bytes", repaired).
+				Msg("Removed an audit entry torn by a crash, its request may not be audited")
+		}
+	}
+
+	// Verify connectivity and credentials before accepting records
+	if d.config.ValidateOnOpen {
+		if err := d.probe(ctx); err != nil {
+			return fmt.Errorf("connectivity check failed: %w", err)
+		}
+	}
+
+	if len(d.config.LoadBalance.URLs) > 0 && d.config.LoadBalance.StatsInterval > 0 {
+		statsCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		d.stopEndpointStats = cancel
+		go logEndpointStatsPeriodically(statsCtx, d.httpClient, d.config.LoadBalance.StatsInterval)
+	}
+	if d.config.PoolStatsInterval > 0 && d.httpClient != nil {
+		statsCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		d.stopPoolStats = cancel
+		go logPoolStatsPeriodically(statsCtx, d.httpClient, d.config.PoolStatsInterval)
+	}
+
+	if d.config.Shadow.URL != "" && !d.config.DryRun {
+		d.shadow = newShadowSender(d.config.Shadow, d.config.URL, d.httpClient)
+	}
+
+	// Receive the results of requests processed asynchronously
+	if d.config.Callback.Address != "" {
+		d.callbacks, err = newCallbackListener(d.config.Callback)
+		if err != nil {
+			return fmt.Errorf("failed to parse callback: %w", err)
+		}
+		d.callbacks.publish = d.publishCallback
+		if d.config.Response.MaxBodySize > 0 {
+			d.callbacks.maxBodySize = d.config.Response.MaxBodySize
+		}
+		if err := d.callbacks.Start(ctx); err != nil {
+			d.callbacks = nil
+			return err
+		}
+	}
+
+	if err := d.openMetrics(ctx); err != nil {
+		return err
+	}
+
+	// Pick up rotated secrets in the background
+	if d.secrets != nil && d.config.Auth.SecretRefreshInterval > 0 {
+		refreshCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		d.stopSecretRefresh = cancel
+		go d.refreshSecretsPeriodically(refreshCtx, d.config.Auth.SecretRefreshInterval)
 	}
 
-	sdk.Logger(ctx).Info().Msg("HTTP destination opened successfully")
 	return nil
 }
 
 // Write sends records to the HTTP endpoint
 func (d *Destination) Write(ctx context.Context, records []opencdc.Record) (int, error) {
-	logger := sdk.Logger(ctx)
+	// Config updates wait for the batch to complete
+	d.reloadMu.RLock()
+	defer d.reloadMu.RUnlock()
+
+	// Teardown waits for the batch up to drainTimeout, then cancels it
+	ctx, end, err := d.drainState.begin(ctx, len(records))
+	if err != nil {
+		return 0, err
+	}
+	var summary *batchSummary
+	if d.config.Kafka.BatchSummaryTopic != "" {
+		summary = newBatchSummary()
+		ctx = withBatchSummary(ctx, summary)
+	}
+	n, err := d.write(ctx, records)
+	if summary != nil {
+		d.publishBatchSummary(ctx, summary, records, n)
+	}
+	d.metrics.written(n, err)
+	end(n)
+	return n, err
+}
+
+// write sends a batch of records with the components of the current config
+func (d *Destination) write(ctx context.Context, records []opencdc.Record) (int, error) {
+	// Buffered records were acked already, fail with the first that couldn't be delivered
+	if d.buffer != nil {
+		if err := d.buffer.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	if d.dedup != nil {
+		// Persist the keys delivered so far, even if the batch fails
+		defer func() {
+			if err := d.dedup.Flush(); err != nil {
+				sdk.Logger(ctx).Warn().Err(err).Msg("Failed to persist dedup state")
+			}
+		}()
+	}
+
+	if d.deliveryLog != nil {
+		// Persist the records delivered so far, even if the batch fails
+		defer func() {
+			if err := d.deliveryLog.Flush(); err != nil {
+				sdk.Logger(ctx).Warn().Err(err).Msg("Failed to persist delivery state")
+			}
+		}()
+	}
+
+	if d.responseDB != nil {
+		// Outcomes of the batch are queryable once it completed
+		defer d.flushResponseDatabase(ctx)
+	}
+
+	if d.config.BatchBody.Format != "none" {
+		return d.writeBatch(ctx, records)
+	}
+
+	if d.config.Stream.Enabled {
+		return d.writeStream(ctx, records)
+	}
+
+	if d.config.Concurrency > 1 && len(records) > 1 {
+		return d.writeConcurrently(ctx, records)
+	}
 
 	for i, record := range records {
-		// Prepare request body from record payload
-		body, err := d.prepareRequestBody(record)
+		// Skip records confirmed delivered before a redelivery
+		if d.delivered(ctx, record) {
+			continue
+		}
+
+		if err := d.deliver(ctx, record); err != nil {
+			return i, d.recordError(err)
+		}
+
+		if d.deliveryLog != nil && record.Position != nil {
+			d.deliveryLog.Add(record.Position)
+		}
+	}
+
+	return len(records), nil
+}
+
+// delivered reports whether the delivery log confirms the record was delivered
+// before a redelivery
+func (d *Destination) delivered(ctx context.Context, record opencdc.Record) bool {
+	if d.deliveryLog != nil && record.Position != nil && d.deliveryLog.Delivered(record.Position) {
+		sdk.Logger(ctx).Debug().Msg("Record already delivered, skipping")
+		return true
+	}
+	return false
+}
+
+// recordError returns the error of a failed record in the configured errorFormat
+func (d *Destination) recordError(err error) error {
+	var recordErr *RecordError
+	if d.config.ErrorFormat == "json" && !errors.As(err, &recordErr) {
+		return &RecordError{Category: connerrors.CategoryName(err), Message: err.Error(), err: err}
+	}
+	return err
+}
+
+// writeRecord sends a single record to the HTTP endpoint unless it is skipped
+// or was delivered within the dedup window
+func (d *Destination) writeRecord(ctx context.Context, record opencdc.Record) error {
+	key, ok, err := d.admitRecord(ctx, record)
+	if err != nil || !ok {
+		return err
+	}
+
+	if err := d.sendRecord(ctx, record); err != nil {
+		return err
+	}
+
+	if key != "" {
+		d.dedup.Add(key)
+	}
+	return nil
+}
+
+// admitRecord reports whether the record is sent, returning its dedup key to
+// add once it was delivered. Records matching the skip filter or delivered
+// within the dedup window are acked without sending a request.
+func (d *Destination) admitRecord(ctx context.Context, record opencdc.Record) (string, bool, error) {
+	logger := sdk.Logger(ctx)
+
+	if d.skipFilter != nil && d.skipFilter.Matches(record, d.config.templateOptions()) {
+		logger.Debug().Msg("Record matched skip filter, skipping")
+		return "", false, nil
+	}
+	if d.isDelete(record, deleteStrategySkip) {
+		logger.Debug().Msg("Delete record skipped by deleteStrategy")
+		return "", false, nil
+	}
+
+	if d.dedup == nil {
+		return "", true, nil
+	}
+	key, err := d.recordDedupKey(record)
+	if err != nil {
+		return "", false, connerrors.WithCategory(fmt.Errorf("failed to build dedup key: %w", err), connerrors.ErrValidation)
+	}
+	if key != "" && d.dedup.Seen(key) {
+		logger.Debug().Str("key", key).Msg("Record already delivered within dedup window, skipping")
+		return "", false, nil
+	}
+	return key, true, nil
+}
+
+// sendRecord sends a single record to the HTTP endpoint and routes the response
+func (d *Destination) sendRecord(ctx context.Context, record opencdc.Record) error {
+	logger := sdk.Logger(ctx)
+
+	// Bound the total time spent on the record across all attempts
+	if d.config.RecordTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.RecordTimeout)
+		defer cancel()
+	}
+
+	ctx, targetURL, body, err := d.recordRequest(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	// The request builder plugin receives the record with each request
+	if d.config.RequestBuilderPlugin != "" {
+		ctx = http.WithRecord(ctx, record.Bytes())
+	}
+
+	// Response messages carry the position, key and operation of the record
+	if len(d.publishers) > 0 {
+		ctx = withRecordRef(ctx, record)
+	}
+
+	// The response message of the record embeds it to be replayable
+	if d.kafkaProducer != nil && d.config.Kafka.IncludeOriginalRecord {
+		original, err := d.originalRecord(record)
 		if err != nil {
-			logger.Error().Err(err).Msg("Failed to prepare request body")
-			return i, fmt.Errorf("failed to prepare request body: %w", err)
+			logger.Error().Err(err).Msg("Failed to embed original record")
+			return connerrors.WithCategory(err, connerrors.ErrValidation)
 		}
+		ctx = withOriginalRecord(ctx, original)
+	}
 
-		// Send HTTP request with retry logic
-		resp, err := d.retryEngine.Do(ctx, func() (*stdhttp.Response, error) {
-			return d.httpClient.Post(ctx, d.config.URL, body)
-		})
+	_, err = d.send(ctx, targetURL, body, record.Metadata)
+	return err
+}
+
+// recordRequest returns the body and URL of the request of a record, and ctx
+// carrying its method, endpoint profile, auth profile and headers
+func (d *Destination) recordRequest(ctx context.Context, record opencdc.Record) (context.Context, string, []byte, error) {
+	logger := sdk.Logger(ctx)
+
+	// Prepare request body from record payload
+	body, err := d.prepareRequestBody(ctx, record)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to prepare request body")
+		return ctx, "", nil, connerrors.WithCategory(fmt.Errorf("failed to prepare request body: %w", err), connerrors.ErrValidation)
+	}
 
+	// Redact fields that must never reach the endpoint
+	if d.redactor != nil && body != nil {
+		body, err = d.redactor.Redact(body)
 		if err != nil {
-			logger.Error().Err(err).Msg("HTTP request failed after retries")
-			return i, fmt.Errorf("HTTP request failed: %w", err)
+			logger.Error().Err(err).Msg("Failed to redact request body")
+			return ctx, "", nil, connerrors.WithCategory(err, connerrors.ErrValidation)
 		}
+	}
 
-		// Read response body
-		var responseBody []byte
-		if resp.Body != nil {
-			responseBody, err = io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				logger.Error().Err(err).Msg("Failed to read response body")
-				return i, fmt.Errorf("failed to read response body: %w", err)
-			}
+	// Select the endpoint profile named in the record metadata
+	endpoint, err := d.recordEndpoint(record)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to select endpoint")
+		return ctx, "", nil, connerrors.WithCategory(err, connerrors.ErrValidation)
+	}
+
+	// Operations are sent with their own method and path, upserted records
+	// with the route of the other operation if the first is rejected
+	route := d.routes[record.Operation]
+	first, fallback, fallbackStatus, upsert := d.upsertRoutes(record.Operation)
+	if upsert {
+		route = first
+	}
+	if route != nil && route.method != "" {
+		ctx = withMethod(ctx, route.method)
+	}
+
+	targetURL, err := d.requestURL(record, endpoint, route)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to build request URL")
+		return ctx, "", nil, connerrors.WithCategory(fmt.Errorf("failed to build request URL: %w", err), connerrors.ErrValidation)
+	}
+	if upsert {
+		fallbackURL, err := d.requestURL(record, endpoint, fallback)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to build upsert URL")
+			return ctx, "", nil, connerrors.WithCategory(fmt.Errorf("failed to build upsert URL: %w", err), connerrors.ErrValidation)
+		}
+		method := fallback.method
+		switch {
+		case method != "":
+		case endpoint != nil:
+			method = endpoint.Method
+		default:
+			method = d.config.Method
+		}
+		ctx = withUpsertFallback(ctx, &upsertFallback{status: fallbackStatus, method: method, url: fallbackURL})
+	}
+	if d.isDelete(record, deleteStrategyDelete) {
+		ctx, targetURL, err = d.deleteRequest(ctx, record, targetURL)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to build delete URL")
+			return ctx, "", nil, connerrors.WithCategory(fmt.Errorf("failed to build delete URL: %w", err), connerrors.ErrValidation)
 		}
+	}
 
-		// Publish response to Kafka if enabled
-		if d.kafkaProducer != nil {
-			// Convert OpenCDC metadata to map[string]string for record headers
-			recordHeaders := make(map[string]string)
-			for key, value := range record.Metadata {
-				recordHeaders[key] = value
-			}
+	// Select the auth profile named in the record metadata, the auth profile
+	// of the endpoint takes precedence
+	if d.config.Auth.ProfileMetadataKey != "" {
+		if profile, ok := record.Metadata[d.config.Auth.ProfileMetadataKey]; ok {
+			ctx = auth.WithProfile(ctx, profile)
+		}
+	}
+	if endpoint != nil {
+		ctx = withEndpoint(ctx, endpoint)
+		if endpoint.AuthProfile != "" {
+			ctx = auth.WithProfile(ctx, endpoint.AuthProfile)
+		}
+		if len(endpoint.Headers) > 0 {
+			ctx = http.WithHeaders(ctx, endpoint.Headers)
+		}
+	}
 
-			if err := d.kafkaProducer.PublishResponse(ctx, resp.StatusCode, resp.Header, responseBody, d.config.URL, d.config.Method, recordHeaders); err != nil {
-				logger.Error().Err(err).Msg("Failed to publish response to Kafka")
-				return i, fmt.Errorf("failed to publish to Kafka: %w", err)
-			}
-			logger.Debug().
-				Str("topic", d.config.KafkaTopic).
-				Int("recordHeaders", len(recordHeaders)).
-				Msg("Response published to Kafka")
+	// Make the write conditional on the resource still having the record's ETag
+	if key := d.config.Conditional.ETagMetadataKey; key != "" {
+		if etag, ok := record.Metadata[key]; ok && etag != "" {
+			ctx = http.WithHeaders(ctx, map[string]string{d.config.Conditional.Header: etag})
 		}
+	}
+
+	return ctx, targetURL, body, nil
+}
+
+// send sends a request body to targetURL with retries and routes the
+// response. Metadata are passed to Kafka as record headers. The response body
+// is returned if it was read, before the response transform.
+func (d *Destination) send(ctx context.Context, targetURL string, body []byte, metadata opencdc.Metadata) ([]byte, error) {
+	logger := sdk.Logger(ctx)
 
-		// Check response status code
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+	method := d.requestMethod(ctx)
+	fallback := upsertFallbackFrom(ctx)
+	send := func(ctx context.Context) (*stdhttp.Response, error) {
+		method, targetURL := method, targetURL
+		resp, err := d.httpClient.Do(ctx, method, targetURL, body)
+		if fallback != nil && err == nil && resp.StatusCode == fallback.status {
+			// The resource doesn't exist yet, or exists already
+			discardBody(resp)
 			logger.Debug().
 				Int("status", resp.StatusCode).
-				Msg("HTTP request successful")
-		} else {
-			logger.Warn().
-				Int("status", resp.StatusCode).
-				Msg("HTTP request returned non-2xx status")
-			return i, fmt.Errorf("HTTP %d", resp.StatusCode)
+				Str("method", fallback.method).
+				Msg("Upserting record with the route of the other operation")
+			method, targetURL = fallback.method, fallback.url
+			resp, err = d.httpClient.Do(ctx, method, targetURL, body)
+		}
+		if d.config.OnConflict == conflictOverwrite && err == nil && resp.StatusCode == stdhttp.StatusConflict {
+			discardBody(resp)
+			logger.Debug().Msg("Overwriting resource of conflicting request")
+			return d.overwrite(ctx, method, targetURL, body)
 		}
+		return resp, err
 	}
 
-	return len(records), nil
+	// All attempts carry the same request ID, the logs of the record too
+	var requestID string
+	if d.config.RequestID.Enabled {
+		requestID = d.requestID(metadata)
+		ctx = http.WithHeaders(ctx, map[string]string{d.config.RequestID.Header: requestID})
+		withID := logger.With().Str("requestId", requestID).Logger()
+		logger = &withID
+		ctx = logger.WithContext(ctx)
+	}
+
+	if d.shadow != nil {
+		d.shadow.Send(ctx, targetURL, body)
+	}
+
+	// The endpoint calls the listener back with the result, shadow endpoints
+	// don't as their callbacks couldn't be correlated
+	if d.callbacks != nil {
+		ctx = http.WithHeaders(ctx, map[string]string{d.config.Callback.Header: d.config.Callback.URL})
+	}
+
+	if d.capture != nil && d.capture.Sample() {
+		ctx = http.WithCapture(ctx)
+	}
+
+	// Dry runs render the request without sending it, the record is acked
+	if d.config.DryRun {
+		return nil, d.dryRun(ctx, method, targetURL, body, requestID, metadata)
+	}
+
+	// Send HTTP request with retry logic, measuring its latency. The phases
+	// are only traced if Kafka messages, JSON errors or the response sink
+	// report them.
+	var stats *http.RequestStats
+	if len(d.publishers) > 0 || d.config.ErrorFormat == "json" || d.config.ResponseSink != "none" {
+		ctx, stats = http.WithStats(ctx)
+	}
+	start := time.Now()
+	attempts := 0
+	resp, err := d.retryEngine.Do(ctx, func() (*stdhttp.Response, error) {
+		attempts++
+		defer d.detectSlowRequest(ctx, method, targetURL, attempts, time.Now())
+		if d.hedger != nil {
+			return d.hedger.Do(ctx, send)
+		}
+		return send(ctx)
+	})
+	if summary := batchSummaryFrom(ctx); summary != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		summary.add(status, time.Since(start))
+	}
+	latency := func() RequestLatency {
+		return newRequestLatency(stats.Timing(), time.Since(start))
+	}
+
+	if err != nil {
+		// The request may have been processed, resending it on redelivery
+		// could duplicate its side effects
+		if d.config.IsAtMostOnce() && http.IsAmbiguous(err, resp) {
+			if resp != nil && resp.Body != nil {
+				resp.Body.Close()
+			}
+			logger.Error().Err(err).Msg("HTTP request failed ambiguously, acking record without resending (at-most-once)")
+			return nil, nil
+		}
+		// The record is buffered until the endpoint recovers
+		if d.buffer != nil && http.IsOutage(err, resp) {
+			if resp != nil {
+				closeResponse(resp)
+			}
+			return nil, fmt.Errorf("%w: %w", errEndpointUnavailable, err)
+		}
+		if resp == nil {
+			logger.Error().Err(err).Str("category", connerrors.CategoryName(err)).Msg("HTTP request failed after retries")
+			if d.config.ErrorFormat == "json" || d.config.ResponseSink != "none" {
+				recordErr := newRecordError(err, nil, "", attempts, targetURL, latency())
+				recordErr.RequestID = requestID
+				recordErr.addHistory(stats)
+				if d.config.ResponseSink != "none" {
+					d.sinkFailure(ctx, recordErr, method, metadata)
+				}
+				if d.config.ErrorFormat == "json" {
+					return nil, recordErr
+				}
+			}
+			return nil, fmt.Errorf("HTTP request failed: %w", err)
+		}
+
+		action := d.statusRules.Classify(resp.StatusCode)
+		if errors.Is(err, http.ErrBodyPredicateFailed) {
+			action = d.bodyPredicate.Action()
+		} else if resp.StatusCode == stdhttp.StatusPreconditionFailed && d.config.Conditional.ETagMetadataKey != "" {
+			err = fmt.Errorf("%w: %w", errPreconditionFailed, err)
+		}
+		logger.Warn().
+			Int("status", resp.StatusCode).
+			Str("action", string(action)).
+			Str("category", connerrors.CategoryName(err)).
+			Msg("HTTP request returned unsuccessful status")
+
+		if d.config.ErrorFormat == "json" || d.config.ResponseSink != "none" {
+			recordErr := newRecordError(err, resp, string(action), attempts, targetURL, latency())
+			recordErr.RequestID = requestID
+			recordErr.addHistory(stats)
+			if d.config.ResponseSink != "none" {
+				d.sinkFailure(ctx, recordErr, method, metadata)
+			}
+			if d.config.ErrorFormat == "json" {
+				closeResponse(resp)
+				return nil, recordErr
+			}
+		}
+		closeResponse(resp)
+		if action == http.ActionDLQ {
+			return nil, fmt.Errorf("HTTP %d routed to DLQ: %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	// Read response body, only Kafka, the response transform, the response
+	// sink, bulk batches, batch items, status URLs and callback IDs use it
+	var responseBody []byte
+	if len(d.publishers) > 0 || d.transformer != nil || d.esBulk != nil || d.items != nil || d.poller.needsBody() || d.callbacks != nil || d.config.ResponseSink != "none" {
+		responseBody, err = readBody(resp)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to read response body")
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+	} else {
+		discardBody(resp)
+	}
+
+	requestLatency := latency()
+	action := d.statusRules.Classify(resp.StatusCode)
+	logger.Debug().
+		Int("status", resp.StatusCode).
+		Str("action", string(action)).
+		Int("attempts", attempts).
+		Float64("latencyMs", requestLatency.TotalMs).
+		Msg("HTTP request successful")
+
+	// Ignored responses are acked without being published
+	if action == http.ActionIgnore {
+		return nil, nil
+	}
+
+	// Selected response headers are published with the metadata
+	if len(d.captures) > 0 {
+		metadata = captureResponseHeaders(d.captures, metadata, resp.Header)
+	}
+
+	// The result of the request is published once it is called back
+	if d.callbacks != nil {
+		d.callbacks.Await(ctx, responseBody, pendingCallback{
+			url:       targetURL,
+			method:    method,
+			requestID: requestID,
+			metadata:  maps.Clone(metadata),
+			original:  originalRecordFrom(ctx),
+			record:    recordRefFrom(ctx),
+			sent:      start,
+		})
+	}
+
+	// Accepted operations are only acked once they completed
+	if d.poller != nil && resp.StatusCode == stdhttp.StatusAccepted {
+		if err := d.awaitOperation(ctx, targetURL, resp.Header, responseBody); err != nil {
+			logger.Error().Err(err).Str("category", connerrors.CategoryName(err)).Msg("Accepted operation failed")
+			return nil, fmt.Errorf("HTTP request failed: %w", err)
+		}
+	}
+
+	rawBody := responseBody
+	if d.transformer != nil {
+		responseBody, err = d.transformer.Transform(ctx, responseBody)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to transform response body")
+			return nil, fmt.Errorf("failed to transform response body: %w", err)
+		}
+	}
+
+	// Publish response to the message brokers if enabled
+	if len(d.publishers) > 0 {
+		msg := responseMessage{
+			statusCode:     resp.StatusCode,
+			protocol:       resp.Proto,
+			header:         resp.Header,
+			body:           responseBody,
+			url:            targetURL,
+			method:         method,
+			requestID:      requestID,
+			attempts:       attempts,
+			latency:        requestLatency.message(),
+			originalRecord: originalRecordFrom(ctx),
+			record:         recordRefFrom(ctx),
+		}
+		if err := d.publishResponse(ctx, msg, metadata); err != nil {
+			logger.Error().Err(err).Msg("Failed to publish response")
+			return nil, err
+		}
+	}
+
+	if d.config.ResponseSink != "none" {
+		d.sinkResponse(ctx, resp, responseBody, method, targetURL, requestID, metadata, attempts, requestLatency)
+	}
+
+	return rawBody, nil
 }
 
 // Teardown cleans up resources
 func (d *Destination) Teardown(ctx context.Context) error {
 	sdk.Logger(ctx).Info().Msg("Tearing down HTTP destination")
 
-	// Close Kafka producer if initialized
-	if d.kafkaProducer != nil {
-		d.kafkaProducer.Close()
-		sdk.Logger(ctx).Info().Msg("Kafka producer closed")
-	}
+	// Wait for records in flight, the buffer drain is stopped afterwards and
+	// the delivery of a buffered record aborted, it stays buffered
+	d.drain(ctx)
+	defer d.reloadMu.Unlock()
+	d.authMu.Lock()
+	defer d.authMu.Unlock()
+
+	d.close(ctx)
+	d.closeBuffer(ctx)
+	d.closeDedup(ctx)
+	d.opened = false
 
 	sdk.Logger(ctx).Info().Msg("HTTP destination torn down successfully")
 	return nil
 }
 
-// prepareRequestBody extracts the payload from the record
-func (d *Destination) prepareRequestBody(record opencdc.Record) ([]byte, error) {
+// close releases the components built by open
+func (d *Destination) close(ctx context.Context) {
+	if d.stopSecretRefresh != nil {
+		d.stopSecretRefresh()
+		d.stopSecretRefresh = nil
+	}
+
+	// Callbacks are published until the listener stopped
+	if d.callbacks != nil {
+		d.callbacks.Close(ctx)
+		d.callbacks = nil
+	}
+	d.closeMetrics(ctx)
+
+	// Close the producers if initialized
+	d.closePublishers(ctx)
+	d.closeResponseDatabase(ctx)
+
+	// Complete the open stream while the client is still available
+	d.closeStream(ctx)
+
+	d.closeCapture(ctx)
+	d.closeAudit(ctx)
+
+	// Release the WASM runtime if initialized
+	if d.transformer != nil {
+		if err := d.transformer.Close(ctx); err != nil {
+			sdk.Logger(ctx).Warn().Err(err).Msg("Failed to close response transform")
+		}
+		d.transformer = nil
+	}
+
+	if d.stopEndpointStats != nil {
+		d.stopEndpointStats()
+		d.stopEndpointStats = nil
+	}
+	if d.stopPoolStats != nil {
+		d.stopPoolStats()
+		d.stopPoolStats = nil
+	}
+
+	if d.shadow != nil {
+		d.shadow.Close(ctx)
+		d.shadow = nil
+	}
+
+	if d.httpClient != nil {
+		logEndpointStats(ctx, d.httpClient)
+		logPoolStats(ctx, d.httpClient)
+		d.httpClient.CloseIdleConnections()
+		d.httpClient = nil
+	}
+	if d.grpcTransport != nil {
+		if err := d.grpcTransport.Close(); err != nil {
+			sdk.Logger(ctx).Warn().Err(err).Msg("Failed to close gRPC connection")
+		}
+		d.grpcTransport = nil
+	}
+
+	// Optional components are only rebuilt if still configured
+	d.secrets = nil
+	d.deliveryLog = nil
+	d.hedger = nil
+}
+
+// reauth refreshes the credentials after the endpoint rejected them, picking
+// up rotated secrets first
+func (d *Destination) reauth(ctx context.Context) error {
+	if d.secrets != nil {
+		if err := d.refreshSecrets(ctx); err != nil {
+			return err
+		}
+	}
+
+	d.authMu.Lock()
+	authManager := d.authManager
+	d.authMu.Unlock()
+	return authManager.Refresh(ctx)
+}
+
+// refreshSecrets re-resolves the secret references and replaces the auth
+// manager if any credential changed. Kafka credentials are only resolved at Open.
+func (d *Destination) refreshSecrets(ctx context.Context) error {
+	d.authMu.Lock()
+	defer d.authMu.Unlock()
+
+	// The destination was closed or reconfigured without secret references
+	if d.secrets == nil {
+		return nil
+	}
+
+	credentials, err := d.config.withSecrets(ctx, d.secrets)
+	if err != nil {
+		return fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+
+	values := credentials.secretValues()
+	if maps.Equal(values, d.secretValues) {
+		return nil
+	}
+
+	authManager, err := newAuthManager(credentials, d.cookieJar)
+	if err != nil {
+		return err
+	}
+	d.authManager = authManager
+	d.secretValues = values
+	d.httpClient.SetAuthManager(authManager)
+
+	sdk.Logger(ctx).Info().Msg("Secrets rotated, credentials updated")
+	return nil
+}
+
+// refreshSecretsPeriodically re-resolves the secret references every interval until ctx is done
+func (d *Destination) refreshSecretsPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.refreshSecrets(ctx); err != nil && ctx.Err() == nil {
+				sdk.Logger(ctx).Warn().Err(err).Msg("Failed to refresh secrets")
+			}
+		}
+	}
+}
+
+// recordDedupKey returns the key identifying the record in the dedup window
+func (d *Destination) recordDedupKey(record opencdc.Record) (string, error) {
+	if d.dedupKey != nil {
+		return d.dedupKey.Render(newTemplateData(record, d.config.templateOptions()))
+	}
+	if record.Key == nil {
+		return "", nil
+	}
+	return string(record.Key.Bytes()), nil
+}
+
+// closeDedup persists the dedup cache and releases it
+func (d *Destination) closeDedup(ctx context.Context) {
+	if d.dedup == nil {
+		return
+	}
+	if err := d.dedup.Flush(); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to persist dedup state")
+	}
+	d.dedup = nil
+}
+
+// logRequest logs a request passing the logging interceptor
+func logRequest(ctx context.Context, req *stdhttp.Request, resp *stdhttp.Response, err error, elapsed time.Duration) {
+	event := sdk.Logger(ctx).Debug().
+		Str("method", req.Method).
+		Str("url", req.URL.Redacted()).
+		Dur("elapsed", elapsed)
+	if err != nil {
+		event.Err(err).Msg("HTTP request failed")
+		return
+	}
+	event.Int("status", resp.StatusCode).Msg("HTTP request sent")
+}
+
+// retryLogHooks log the retries of the retry engine, failures after the last
+// attempt are logged with the record
+var retryLogHooks = http.RetryHookFuncs{
+	RetryScheduled: func(ctx context.Context, n int, backoff time.Duration, attempt http.Attempt) {
+		event := sdk.Logger(ctx).Debug().
+			Int("attempt", n).
+			Dur("backoff", backoff)
+		if attempt.Status != 0 {
+			event.Int("status", attempt.Status)
+		}
+		if attempt.Err != "" {
+			event.Str("error", attempt.Err)
+		}
+		event.Msg("Retrying HTTP request")
+	},
+}
+
+// closeResponse closes the body of a response that is not read
+func closeResponse(resp *stdhttp.Response) {
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+}
+
+// maxDrainSize is how much of an unused response body is read so its
+// connection can be reused, larger bodies close the connection
+const maxDrainSize = 64 << 10
+
+// maxPooledBodySize bounds the buffers kept for reuse, so a single large
+// response doesn't pin its memory
+const maxPooledBodySize = 1 << 20
+
+// bodyBuffers are reused to read response bodies
+var bodyBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// readBody reads and closes the response body. The body is read into a
+// reused buffer and copied once, instead of growing a new slice.
+func readBody(resp *stdhttp.Response) ([]byte, error) {
+	if resp.Body == nil {
+		return nil, nil
+	}
+	defer resp.Body.Close()
+
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBodySize {
+			bodyBuffers.Put(buf)
+		}
+	}()
+	buf.Reset()
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// discardBody drains and closes an unused response body
+func discardBody(resp *stdhttp.Response) {
+	if resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+	resp.Body.Close()
+}
+
+// requestURL returns the URL of the endpoint profile, or the configured URL
+// without, with the path of the operation route and the query parameters
+// rendered from the record appended
+func (d *Destination) requestURL(record opencdc.Record, endpoint *EndpointProfile, route *operationRoute) (string, error) {
+	base := d.config.URL
+	if endpoint != nil {
+		base = endpoint.URL
+	}
+	hasPath := route != nil && route.path != nil
+	if len(d.queryParams) == 0 && !hasPath {
+		return base, nil
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+
+	data := newTemplateData(record, d.config.templateOptions())
+	if hasPath {
+		path, err := route.path.Render(data)
+		if err != nil {
+			return "", err
+		}
+		u = u.JoinPath(path)
+	}
+	if len(d.queryParams) == 0 {
+		return u.String(), nil
+	}
+
+	query := u.Query()
+	for name, tmpl := range d.queryParams {
+		value, err := tmpl.Render(data)
+		if err != nil {
+			return "", err
+		}
+		query.Set(name, value)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// parseRequestTemplates compiles the templates and transforms building the
+// requests of records, redacted fields are hashed with redactHashKey
+func (d *Destination) parseRequestTemplates(redactHashKey string) error {
+	var err error
+	d.queryParams, err = parseRecordTemplates(d.config.QueryParams, d.config.templateFuncs())
+	if err != nil {
+		return fmt.Errorf("failed to parse query params: %w", err)
+	}
+
+	d.bodyTemplate = nil
+	if d.config.BodyTemplate != "" {
+		d.bodyTemplate, err = parseRecordTemplate("bodyTemplate", d.config.BodyTemplate, d.config.templateFuncs())
+		if err != nil {
+			return fmt.Errorf("failed to parse body template: %w", err)
+		}
+	}
+	d.deleteURL, d.tombstone = nil, nil
+	if d.config.Delete.URL != "" {
+		d.deleteURL, err = parseRecordTemplate("delete.url", d.config.Delete.URL, d.config.templateFuncs())
+		if err != nil {
+			return fmt.Errorf("failed to parse delete URL: %w", err)
+		}
+	}
+	if d.config.Delete.Tombstone != "" {
+		d.tombstone, err = parseRecordTemplate("delete.tombstone", d.config.Delete.Tombstone, d.config.templateFuncs())
+		if err != nil {
+			return fmt.Errorf("failed to parse tombstone: %w", err)
+		}
+	}
+	d.routes, err = parseOperationRoutes(d.config.Operations, d.config.templateFuncs())
+	if err != nil {
+		return err
+	}
+	d.bodyJQ = nil
+	if d.config.BodyTransform.JQ != "" {
+		d.bodyJQ, err = newJQTransform(d.config.BodyTransform.JQ, d.config.TemplateEnvPrefix)
+		if err != nil {
+			return fmt.Errorf("failed to compile body transform: %w", err)
+		}
+	}
+
+	d.redactor = nil
+	if len(d.config.RedactFields) > 0 {
+		d.redactor, err = newFieldRedactor(d.config.RedactFields, d.config.RedactMask, redactHashKey)
+		if err != nil {
+			return fmt.Errorf("failed to parse redactFields: %w", err)
+		}
+	}
+	return nil
+}
+
+// prepareRequestBody renders the body template, applies the body transform
+// or extracts the payload from the record. Deletes get the body of the delete
+// strategy. Raw payloads are returned without
+// copying so large bodies are not duplicated in memory.
+func (d *Destination) prepareRequestBody(ctx context.Context, record opencdc.Record) ([]byte, error) {
+	switch {
+	case d.isDelete(record, deleteStrategyDelete):
+		return nil, nil
+	case d.isDelete(record, deleteStrategyTombstone):
+		return d.tombstoneBody(record)
+	}
+	if d.bodyJQ != nil {
+		return d.bodyJQ.Apply(ctx, record, d.config.UsePayloadAfter)
+	}
+	if d.bodyTemplate != nil {
+		body, err := d.bodyTemplate.Render(newTemplateData(record, d.config.templateOptions()))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(body), nil
+	}
+
+	switch d.config.BodyFormat {
+	case bodyFormatOpenCDC:
+		return record.Bytes(), nil
+	case bodyFormatFlattened:
+		return flattenRecord(record, d.config.templateOptions(), d.config.Flatten)
+	}
+
 	// Use the After payload (for inserts/updates)
 	if d.config.UsePayloadAfter && record.Payload.After != nil {
 		return record.Payload.After.Bytes(), nil
@@ -246,3 +1542,16 @@ func (d *Destination) prepareRequestBody(record opencdc.Record) ([]byte, error)
 
 	return nil, fmt.Errorf("record has no payload")
 }
+
+// recordBody returns the request body of a record with its fields redacted
+func (d *Destination) recordBody(ctx context.Context, record opencdc.Record) ([]byte, error) {
+	body, err := d.prepareRequestBody(ctx, record)
+	if err != nil {
+		return nil, connerrors.WithCategory(fmt.Errorf("failed to prepare request body: %w", err), connerrors.ErrValidation)
+	}
+	if d.redactor != nil {
+		body, err = d.redactor.Redact(body)
+		return body, connerrors.WithCategory(err, connerrors.ErrValidation)
+	}
+	return body, nil
+}
@@ -0,0 +1,173 @@
+package destination_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+
+	connector "github.com/dev-in-black/connector-http"
+	"github.com/dev-in-black/connector-http/destination"
+)
+
+// newDestination opens a destination with settings parsed like Conduit does
+func newDestination(t *testing.T, settings map[string]string) sdk.Destination {
+	t.Helper()
+	is := is.New(t)
+	ctx := context.Background()
+
+	dest := destination.NewDestination()
+	is.NoErr(sdk.Util.ParseConfig(ctx, settings, dest.Config(), connector.Connector.NewSpecification().DestinationParams))
+	is.NoErr(dest.Open(ctx))
+	return dest
+}
+
+// openDestination opens a destination torn down once the test completes
+func openDestination(t *testing.T, settings map[string]string) sdk.Destination {
+	t.Helper()
+	dest := newDestination(t, settings)
+	t.Cleanup(func() { _ = dest.Teardown(context.Background()) })
+	return dest
+}
+
+// testEndpoint records the bodies of the requests it accepts and answers 503
+// while it is down
+type testEndpoint struct {
+	*httptest.Server
+	down atomic.Bool
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newTestEndpoint(t *testing.T) *testEndpoint {
+	e := &testEndpoint{}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if e.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		e.mu.Lock()
+		e.bodies = append(e.bodies, string(body))
+		e.mu.Unlock()
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// Bodies returns the bodies of the accepted requests in the order received
+func (e *testEndpoint) Bodies() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.bodies...)
+}
+
+// waitForBodies waits up to 5 seconds for the endpoint to accept n requests
+func (e *testEndpoint) waitForBodies(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(e.Bodies()) < n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return e.Bodies()
+}
+
+func testRecord(id string) opencdc.Record {
+	return opencdc.Record{
+		Position:  opencdc.Position(id),
+		Operation: opencdc.OperationCreate,
+		Metadata:  opencdc.Metadata{},
+		Key:       opencdc.RawData(id),
+		Payload:   opencdc.Change{After: opencdc.RawData(`{"id":"` + id + `"}`)},
+	}
+}
+
+func testRecords(ids ...string) []opencdc.Record {
+	records := make([]opencdc.Record, len(ids))
+	for i, id := range ids {
+		records[i] = testRecord(id)
+	}
+	return records
+}
+
+func testBodies(ids ...string) []string {
+	bodies := make([]string, len(ids))
+	for i, id := range ids {
+		bodies[i] = `{"id":"` + id + `"}`
+	}
+	return bodies
+}
+
+// BenchmarkWrite writes batches of small records to an endpoint answering
+// every request with 200, without Kafka
+func BenchmarkWrite(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	b.Cleanup(server.Close)
+
+	records := make([]opencdc.Record, 100)
+	for i := range records {
+		id := strconv.Itoa(i)
+		records[i] = opencdc.Record{
+			Position:  opencdc.Position(id),
+			Operation: opencdc.OperationCreate,
+			Metadata:  opencdc.Metadata{"opencdc.collection": "users"},
+			Key:       opencdc.RawData(id),
+			Payload: opencdc.Change{After: opencdc.StructuredData{
+				"id":    i,
+				"name":  "Jane",
+				"email": "jane@example.com",
+			}},
+		}
+	}
+
+	benchmarks := []struct {
+		name     string
+		settings map[string]string
+	}{{
+		name:     "payload",
+		settings: map[string]string{},
+	}, {
+		name:     "bodyTemplate",
+		settings: map[string]string{"bodyTemplate": `{"user":{{toJson .Payload}}}`},
+	}}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx := context.Background()
+			settings := map[string]string{"url": server.URL}
+			for key, value := range bm.settings {
+				settings[key] = value
+			}
+			dest := destination.NewDestination()
+			if err := sdk.Util.ParseConfig(ctx, settings, dest.Config(), connector.Connector.NewSpecification().DestinationParams); err != nil {
+				b.Fatal(err)
+			}
+			if err := dest.Open(ctx); err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { _ = dest.Teardown(ctx) })
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := dest.Write(ctx, records); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(records)), "ns/record")
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package destination
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// errShuttingDown fails writes that arrive once Teardown started draining
+var errShuttingDown = errors.New("destination is shutting down")
+
+// drainState tracks the records of Write calls in flight, so Teardown can
+// wait for them to complete and report what was flushed and abandoned
+type drainState struct {
+	mu        sync.Mutex
+	draining  bool
+	inflight  int // Records of Write calls in progress
+	flushed   int // Records acked by Write calls completing while draining
+	abandoned int // Records not acked by Write calls completing while draining
+
+	// Write requests are canceled once the drain timeout expired
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newDrainState returns the drain state of an open destination
+func newDrainState() *drainState {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &drainState{ctx: ctx, cancel: cancel}
+}
+
+// begin registers a Write call of n records, failing once draining started.
+// The returned context is canceled if the drain timeout expires.
+func (s *drainState) begin(ctx context.Context, n int) (context.Context, func(written int), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return nil, nil, errShuttingDown
+	}
+	s.inflight += n
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.ctx, cancel)
+	end := func(written int) {
+		stop()
+		cancel()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.inflight -= n
+		if s.draining {
+			s.flushed += written
+			s.abandoned += n - written
+		}
+	}
+	return ctx, end, nil
+}
+
+// start stops accepting records and returns the number still in flight
+func (s *drainState) start() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draining = true
+	return s.inflight
+}
+
+// abandon cancels the requests of the Write calls in flight
+func (s *drainState) abandon() {
+	s.cancel()
+}
+
+// inFlight returns the number of records of Write calls in progress
+func (s *drainState) inFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inflight
+}
+
+// result returns the records flushed and abandoned while draining
+func (s *drainState) result() (flushed, abandoned int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushed, s.abandoned
+}
+
+// drain stops accepting records and waits up to drainTimeout for the Write
+// calls in flight and the outage buffer to complete, then cancels what is
+// left. It returns once reloadMu is held for writing, the caller unlocks it.
+func (d *Destination) drain(ctx context.Context) {
+	logger := sdk.Logger(ctx)
+	if d.drainState == nil {
+		d.reloadMu.Lock()
+		return
+	}
+
+	inflight := d.drainState.start()
+	buffered := 0
+	if d.buffer != nil {
+		buffered = d.buffer.Len()
+	}
+	if inflight > 0 || buffered > 0 {
+		logger.Info().
+			Int("inflight", inflight).
+			Int("buffered", buffered).
+			Dur("drainTimeout", d.config.DrainTimeout).
+			Msg("Draining records in flight")
+	}
+
+	var deadline <-chan time.Time
+	if d.config.DrainTimeout > 0 {
+		timer := time.NewTimer(d.config.DrainTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	// Buffered records were acked already, they are delivered in the
+	// background until the buffer is empty or the timeout expires
+	expired := false
+	if buffer := d.buffer; buffer != nil {
+		for !expired {
+			changed := buffer.Changed()
+			if buffer.Len() == 0 {
+				break
+			}
+			select {
+			case <-changed:
+			case <-deadline:
+				expired = true
+			case <-ctx.Done():
+				expired = true
+			}
+		}
+	}
+	if d.stopBufferDrain != nil {
+		d.stopBufferDrain()
+	}
+
+	// Write calls hold reloadMu for reading until they complete
+	locked := make(chan struct{})
+	go func() {
+		d.reloadMu.Lock()
+		close(locked)
+	}()
+	if !expired {
+		select {
+		case <-locked:
+		case <-deadline:
+			expired = true
+		case <-ctx.Done():
+			expired = true
+		}
+	}
+	if expired {
+		d.drainState.abandon()
+		<-locked
+	}
+
+	flushed, abandoned := d.drainState.result()
+	if inflight > 0 || buffered > 0 {
+		event := logger.Info()
+		if abandoned > 0 {
+			event = logger.Warn()
+		}
+		event.
+			Int("flushed", flushed).
+			Int("abandoned", abandoned).
+			Msg("Drained records in flight")
+	}
+}
@@ -0,0 +1,130 @@
+package destination_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// blockingEndpoint holds requests until it is released or they are canceled
+type blockingEndpoint struct {
+	*httptest.Server
+	received chan struct{}
+	release  chan struct{}
+}
+
+func newBlockingEndpoint(t *testing.T) *blockingEndpoint {
+	e := &blockingEndpoint{
+		received: make(chan struct{}, 10),
+		release:  make(chan struct{}),
+	}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.received <- struct{}{}
+		select {
+		case <-e.release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// writeInFlight starts a Write and waits for its request to reach the endpoint
+func writeInFlight(t *testing.T, e *blockingEndpoint, write func() (int, error)) <-chan error {
+	t.Helper()
+	written := make(chan error, 1)
+	go func() {
+		n, err := write()
+		if err == nil && n != 1 {
+			t.Errorf("Write acked %d records, expected 1", n)
+		}
+		written <- err
+	}()
+	select {
+	case <-e.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request wasn't sent")
+	}
+	return written
+}
+
+func TestDrainWaitsForWritesInFlight(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newBlockingEndpoint(t)
+	dest := newDestination(t, map[string]string{"url": endpoint.URL, "retry.max": "0"})
+	written := writeInFlight(t, endpoint, func() (int, error) {
+		return dest.Write(ctx, testRecords("1"))
+	})
+
+	tornDown := make(chan error, 1)
+	go func() { tornDown <- dest.Teardown(ctx) }()
+	select {
+	case err := <-tornDown:
+		t.Fatalf("Teardown returned with a record in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The record completes before Teardown returns
+	close(endpoint.release)
+	is.NoErr(<-written)
+	is.NoErr(<-tornDown)
+}
+
+func TestDrainRefusesNewWrites(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newBlockingEndpoint(t)
+	dest := newDestination(t, map[string]string{"url": endpoint.URL, "retry.max": "0"})
+	written := writeInFlight(t, endpoint, func() (int, error) {
+		return dest.Write(ctx, testRecords("1"))
+	})
+	tornDown := make(chan error, 1)
+	go func() { tornDown <- dest.Teardown(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+
+	// Teardown started draining, later Writes fail without sending records
+	refused := make(chan error, 1)
+	go func() {
+		n, err := dest.Write(ctx, testRecords("2"))
+		if n != 0 {
+			t.Errorf("Write acked %d records while shutting down", n)
+		}
+		refused <- err
+	}()
+
+	close(endpoint.release)
+	is.NoErr(<-written)
+	is.NoErr(<-tornDown)
+	err := <-refused
+	is.True(err != nil)
+	is.Equal(err.Error(), "destination is shutting down")
+	is.Equal(len(endpoint.received), 0) // Only the first record was sent
+}
+
+func TestDrainTimeoutCancelsWrites(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newBlockingEndpoint(t)
+	t.Cleanup(func() { close(endpoint.release) })
+	dest := newDestination(t, map[string]string{
+		"url":          endpoint.URL,
+		"retry.max":    "0",
+		"drainTimeout": "100ms",
+	})
+	written := writeInFlight(t, endpoint, func() (int, error) {
+		return dest.Write(ctx, testRecords("1"))
+	})
+
+	// The endpoint never answers, the record is canceled once the timeout expired
+	start := time.Now()
+	is.NoErr(dest.Teardown(ctx))
+	elapsed := time.Since(start)
+	is.True(elapsed >= 100*time.Millisecond)
+	is.True(elapsed < 5*time.Second)
+	is.True(<-written != nil)
+}
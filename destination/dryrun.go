@@ -0,0 +1,129 @@
+package destination
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdhttp "net/http"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/jsonl"
+)
+
+// dryRunHeader marks the responses of requests rendered by a dry run
+const dryRunHeader = "X-Dry-Run"
+
+// dryRunTransport renders requests instead of sending them, it answers each
+// with 200 and the request as JSON body, redacted like captures. It sees the
+// requests as sent, after the headers, authentication and signing.
+type dryRunTransport struct {
+	redactor *captureSink
+}
+
+// newDryRunTransport returns the transport of dry runs, redacting the
+// headers and patterns of the capture
+func newDryRunTransport(ctx context.Context, cfg Config) (*dryRunTransport, error) {
+	redaction := CaptureConfig{
+		RedactHeaders:  cfg.Capture.RedactHeaders,
+		RedactPatterns: cfg.Capture.RedactPatterns,
+	}
+	redactor, err := newCaptureSink(ctx, redaction, jsonl.SyncPolicy{}, cfg.apiKeyHeaders(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &dryRunTransport{redactor: redactor}, nil
+}
+
+// RoundTrip renders the request
+func (t *dryRunTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	ex := &http.Exchange{
+		Time: time.Now(),
+		Request: http.CapturedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: req.Header.Clone(),
+		},
+	}
+	ex.Request.Body, ex.Request.BodyEncoding = http.EncodeBody(body)
+	t.redactor.redact(ex)
+	data, err := json.Marshal(ex.Request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render request: %w", err)
+	}
+
+	return &stdhttp.Response{
+		Status:     "200 OK",
+		StatusCode: stdhttp.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: stdhttp.Header{
+			"Content-Type": {"application/json"},
+			dryRunHeader:   {"true"},
+		},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// dryRun renders the request of a record or batch through the HTTP client
+// and passes it to the message brokers and the response sink like a response.
+// The response checks, callbacks and polling are skipped as nothing was sent.
+func (d *Destination) dryRun(ctx context.Context, method, targetURL string, body []byte, requestID string, metadata opencdc.Metadata) error {
+	start := time.Now()
+	resp, err := d.httpClient.Do(ctx, method, targetURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to render request: %w", err)
+	}
+	rendered, err := readBody(resp)
+	if err != nil {
+		return fmt.Errorf("failed to render request: %w", err)
+	}
+	latency := newRequestLatency(http.Timing{}, time.Since(start))
+
+	sdk.Logger(ctx).Debug().
+		Str("method", method).
+		Int("bytes", len(body)).
+		Msg("Dry run, request rendered without sending it")
+
+	if len(d.publishers) > 0 {
+		msg := responseMessage{
+			statusCode:     resp.StatusCode,
+			protocol:       resp.Proto,
+			header:         resp.Header,
+			body:           rendered,
+			url:            targetURL,
+			method:         method,
+			requestID:      requestID,
+			attempts:       1,
+			latency:        latency.message(),
+			originalRecord: originalRecordFrom(ctx),
+			record:         recordRefFrom(ctx),
+		}
+		if err := d.publishResponse(ctx, msg, metadata); err != nil {
+			sdk.Logger(ctx).Error().Err(err).Msg("Failed to publish rendered request")
+			return err
+		}
+	}
+	if d.config.ResponseSink != "none" {
+		d.sinkResponse(ctx, resp, rendered, method, targetURL, requestID, metadata, 1, latency)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// endpointKey is the context key of the endpoint profile a record selected
+type endpointKey struct{}
+
+// withEndpoint returns a context sending requests to the endpoint profile
+func withEndpoint(ctx context.Context, endpoint *EndpointProfile) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// requestMethod returns the method of requests sent with ctx: the method of
+// the delete strategy or the operation route, of the endpoint profile the
+// record selected or the configured method
+func (d *Destination) requestMethod(ctx context.Context) string {
+	if method, ok := ctx.Value(methodKey{}).(string); ok {
+		return method
+	}
+	if endpoint, ok := ctx.Value(endpointKey{}).(*EndpointProfile); ok && endpoint != nil {
+		return endpoint.Method
+	}
+	return d.config.Method
+}
+
+// recordEndpoint returns the endpoint profile named in the record metadata,
+// nil if the record is sent to url
+func (d *Destination) recordEndpoint(record opencdc.Record) (*EndpointProfile, error) {
+	if d.config.EndpointMetadataKey == "" {
+		return nil, nil
+	}
+	name, ok := record.Metadata[d.config.EndpointMetadataKey]
+	if !ok || name == "" {
+		return nil, nil
+	}
+	endpoint, ok := d.config.Endpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown endpoint %q in metadata %s", name, d.config.EndpointMetadataKey)
+	}
+	return &endpoint, nil
+}
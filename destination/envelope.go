@@ -0,0 +1,61 @@
+package destination
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// envelopeEncoder renders a batch of records as a single JSON body of an
+// intake endpoint: a JSON array of the record bodies (json-array), or Splunk
+// HEC events (splunk-hec)
+type envelopeEncoder struct {
+	format string
+}
+
+// Encode renders the records in the envelope of the format, source renders
+// the body of a record
+func (e *envelopeEncoder) Encode(records []opencdc.Record, source func(opencdc.Record) ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	if e.format == "json-array" {
+		buf.WriteByte('[')
+	}
+	for n, record := range records {
+		body, err := source(record)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+
+		switch e.format {
+		case "json-array":
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+		case "splunk-hec":
+			// HEC takes a sequence of event objects, the event time is
+			// the time the record was created at, in seconds
+			buf.WriteString(`{`)
+			if createdAt, err := record.Metadata.GetCreatedAt(); err == nil {
+				buf.WriteString(`"time":`)
+				buf.WriteString(strconv.FormatFloat(float64(createdAt.UnixMilli())/1000, 'f', 3, 64))
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`"event":`)
+		}
+
+		if err := json.Compact(&buf, body); err != nil {
+			return nil, fmt.Errorf("record %d: body is not JSON: %w", n, err)
+		}
+
+		if e.format == "splunk-hec" {
+			buf.WriteString("}\n")
+		}
+	}
+	if e.format == "json-array" {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), nil
+}
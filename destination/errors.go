@@ -0,0 +1,165 @@
+package destination
+
+import (
+	"encoding/json"
+	"io"
+	stdhttp "net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	connerrors "github.com/dev-in-black/connector-http/internal/errors"
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+// bodyExcerptSize is the maximum number of response body bytes included in a RecordError
+const bodyExcerptSize = 512
+
+// RecordError describes why a record failed to be delivered. With errorFormat
+// json its message is the JSON encoding of the error, which Conduit stores
+// with nacked records in the DLQ.
+type RecordError struct {
+	Status       int    `json:"status,omitempty"`
+	Action       string `json:"action,omitempty"`
+	Category     string `json:"category,omitempty"` // See internal/errors
+	Attempts     int    `json:"attempts,omitempty"`
+	Body         string `json:"body,omitempty"`         // Excerpt of the response body
+	BodyEncoding string `json:"bodyEncoding,omitempty"` // base64 for binary bodies
+	URL          string `json:"url,omitempty"`
+	RequestID    string `json:"requestId,omitempty"` // With requestId.enabled
+	Message      string `json:"error"`
+
+	Latency *RequestLatency `json:"latency,omitempty"`
+
+	// Outcomes counts the attempts by status, "error" for attempts without
+	// response, History lists them in order
+	Outcomes       map[string]int  `json:"outcomes,omitempty"`
+	History        []AttemptRecord `json:"history,omitempty"`
+	HistoryOmitted int             `json:"historyOmitted,omitempty"` // Older attempts not in History
+
+	err error
+}
+
+// AttemptRecord is an attempt of a failed record's request
+type AttemptRecord struct {
+	Time       time.Time `json:"time"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	BackoffMs  float64   `json:"backoffMs,omitempty"` // Waited before the attempt
+	DurationMs float64   `json:"durationMs"`
+}
+
+// Error returns the JSON encoding of the error
+func (e *RecordError) Error() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(data)
+}
+
+// Unwrap returns the underlying error
+func (e *RecordError) Unwrap() error {
+	return e.err
+}
+
+// RequestLatency is the latency breakdown of a record's request in
+// milliseconds. DNS, connect, TLS and TTFB are of the last attempt that
+// received a response, total spans all attempts including backoff.
+type RequestLatency struct {
+	DNSMs     float64 `json:"dnsMs"`
+	ConnectMs float64 `json:"connectMs"`
+	TLSMs     float64 `json:"tlsMs"`
+	TTFBMs    float64 `json:"ttfbMs"`
+	TotalMs   float64 `json:"totalMs"`
+}
+
+// newRequestLatency converts the timing of a request to milliseconds
+func newRequestLatency(timing http.Timing, total time.Duration) RequestLatency {
+	return RequestLatency{
+		DNSMs:     millis(timing.DNS),
+		ConnectMs: millis(timing.Connect),
+		TLSMs:     millis(timing.TLS),
+		TTFBMs:    millis(timing.TTFB),
+		TotalMs:   millis(total),
+	}
+}
+
+// message converts the latency to the format of published response messages
+func (l RequestLatency) message() response.Latency {
+	return response.Latency{
+		DNSMs:     l.DNSMs,
+		ConnectMs: l.ConnectMs,
+		TLSMs:     l.TLSMs,
+		TTFBMs:    l.TTFBMs,
+		TotalMs:   l.TotalMs,
+	}
+}
+
+// millis returns d in milliseconds with microsecond precision
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// newRecordError wraps err with the details of a failed response
+func newRecordError(err error, resp *stdhttp.Response, action string, attempts int, url string, latency RequestLatency) *RecordError {
+	e := &RecordError{
+		Action:   action,
+		Category: connerrors.CategoryName(err),
+		Attempts: attempts,
+		URL:      url,
+		Message:  err.Error(),
+		Latency:  &latency,
+		err:      err,
+	}
+	if resp != nil {
+		e.Status = resp.StatusCode
+		e.Body, e.BodyEncoding = bodyExcerpt(resp)
+	}
+	return e
+}
+
+// addHistory adds the attempts collected in stats to the error if the request
+// was retried, a single attempt is described by the error itself
+func (e *RecordError) addHistory(stats *http.RequestStats) {
+	attempts, dropped := stats.Attempts()
+	if len(attempts)+dropped < 2 {
+		return
+	}
+
+	e.Outcomes = make(map[string]int)
+	e.History = make([]AttemptRecord, len(attempts))
+	e.HistoryOmitted = dropped
+	for i, a := range attempts {
+		outcome := "error"
+		if a.Status != 0 {
+			outcome = strconv.Itoa(a.Status)
+		}
+		e.Outcomes[outcome]++
+		e.History[i] = AttemptRecord{
+			Time:       a.Start.UTC(),
+			Status:     a.Status,
+			Error:      a.Err,
+			BackoffMs:  millis(a.Backoff),
+			DurationMs: millis(a.Duration),
+		}
+	}
+}
+
+// bodyExcerpt reads the beginning of the response body as a string, base64
+// encoded with its encoding for binary bodies
+func bodyExcerpt(resp *stdhttp.Response) (string, string) {
+	if resp.Body == nil {
+		return "", ""
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, bodyExcerptSize))
+	if err != nil && len(data) == 0 {
+		return "", ""
+	}
+	// Don't cut a multi-byte character in half
+	if text := truncateUTF8(data, len(data)); utf8.Valid(text) {
+		return string(text), ""
+	}
+	return http.EncodeBody(data)
+}
@@ -0,0 +1,170 @@
+package destination
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// errBulkItemFailed marks records the endpoint rejected in a _bulk response
+var errBulkItemFailed = errors.New("bulk item failed")
+
+// Actions of _bulk requests
+const (
+	bulkIndex  = "index"
+	bulkCreate = "create"
+	bulkUpdate = "update"
+	bulkDelete = "delete"
+)
+
+// esBulkEncoder renders a batch of records in the Elasticsearch and
+// OpenSearch _bulk format, an action line followed by the document of each
+// record
+type esBulkEncoder struct {
+	index        *recordTemplate
+	id           *recordTemplate
+	createAction string
+	docAsUpsert  bool
+}
+
+// newESBulkEncoder parses the index and ID templates
+func newESBulkEncoder(cfg ESBulkConfig, funcs template.FuncMap) (*esBulkEncoder, error) {
+	if cfg.Index == "" {
+		return nil, fmt.Errorf("index is required")
+	}
+	if cfg.CreateAction != bulkIndex && cfg.CreateAction != bulkCreate {
+		return nil, fmt.Errorf("invalid createAction: %s (must be index or create)", cfg.CreateAction)
+	}
+
+	index, err := parseRecordTemplate("index", cfg.Index, funcs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index: %w", err)
+	}
+	id, err := parseRecordTemplate("id", cfg.ID, funcs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %w", err)
+	}
+	return &esBulkEncoder{index: index, id: id, createAction: cfg.CreateAction, docAsUpsert: cfg.DocAsUpsert}, nil
+}
+
+// action returns the _bulk action of a record's operation
+func (e *esBulkEncoder) action(op opencdc.Operation) string {
+	switch op {
+	case opencdc.OperationUpdate:
+		return bulkUpdate
+	case opencdc.OperationDelete:
+		return bulkDelete
+	default:
+		return e.createAction
+	}
+}
+
+// Encode renders the records as a _bulk body. The document of a record is
+// its request body, source renders it; deletes have no document.
+func (e *esBulkEncoder) Encode(records []opencdc.Record, opts templateOptions, source func(opencdc.Record) ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	for n, record := range records {
+		data := newTemplateData(record, opts)
+		index, err := e.index.Render(data)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		if index == "" {
+			return nil, fmt.Errorf("record %d: index is empty", n)
+		}
+		id, err := e.id.Render(data)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+
+		action := e.action(record.Operation)
+		if id == "" && action != bulkIndex && action != bulkCreate {
+			return nil, fmt.Errorf("record %d: %s requires a document ID", n, action)
+		}
+		meta := map[string]string{"_index": index}
+		if id != "" {
+			meta["_id"] = id
+		}
+		line, err := json.Marshal(map[string]map[string]string{action: meta})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+
+		if action == bulkDelete {
+			continue
+		}
+		doc, err := source(record)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		if action == bulkUpdate {
+			buf.WriteString(`{"doc":`)
+		}
+		// Documents must fit on a single line
+		if err := json.Compact(&buf, doc); err != nil {
+			return nil, fmt.Errorf("record %d: body is not JSON: %w", n, err)
+		}
+		if action == bulkUpdate {
+			if e.docAsUpsert {
+				buf.WriteString(`,"doc_as_upsert":true`)
+			}
+			buf.WriteByte('}')
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// esBulkResponse is the response of a _bulk request
+type esBulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]esBulkItemResult `json:"items"`
+}
+
+// esBulkItemResult is the result of a single action of a _bulk request
+type esBulkItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// parseESBulkResponse returns the number of records acked by a _bulk response
+// for n records, the items up to the first that failed. Conflicts of create
+// and missing documents of delete are acked, the endpoint already is in the
+// requested state, e.g. after a redelivery.
+func parseESBulkResponse(body []byte, n int) (int, error) {
+	var resp esBulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, fmt.Errorf("invalid bulk response: %w", err)
+	}
+	if len(resp.Items) != n {
+		return 0, fmt.Errorf("invalid bulk response: %d items for %d records", len(resp.Items), n)
+	}
+	if !resp.Errors {
+		return n, nil
+	}
+
+	for i, item := range resp.Items {
+		for action, result := range item {
+			switch {
+			case result.Status < 300,
+				action == bulkCreate && result.Status == 409,
+				action == bulkDelete && result.Status == 404:
+				continue
+			case result.Error != nil:
+				return i, fmt.Errorf("%w: %s status %d: %s: %s", errBulkItemFailed, action, result.Status, result.Error.Type, result.Error.Reason)
+			default:
+				return i, fmt.Errorf("%w: %s status %d", errBulkItemFailed, action, result.Status)
+			}
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,161 @@
+package destination
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/matryer/is"
+)
+
+func TestESBulkEncoderEncode(t *testing.T) {
+	record := func(op opencdc.Operation, key, after string) opencdc.Record {
+		r := opencdc.Record{
+			Operation: op,
+			Metadata:  opencdc.Metadata{"tenant": "acme"},
+			Key:       opencdc.RawData(key),
+		}
+		if after != "" {
+			r.Payload.After = opencdc.RawData(after)
+		}
+		return r
+	}
+	source := func(r opencdc.Record) ([]byte, error) { return r.Payload.After.Bytes(), nil }
+
+	testCases := []struct {
+		name    string
+		config  ESBulkConfig
+		records []opencdc.Record
+		want    string
+		wantErr bool
+	}{{
+		name:   "operations",
+		config: ESBulkConfig{Index: "logs-{{.Metadata.tenant}}", ID: "{{.Key}}", CreateAction: bulkIndex, DocAsUpsert: true},
+		records: []opencdc.Record{
+			record(opencdc.OperationCreate, "1", "{\n  \"a\": 1\n}"),
+			record(opencdc.OperationSnapshot, "2", `{"a":2}`),
+			record(opencdc.OperationUpdate, "3", `{"a":3}`),
+			record(opencdc.OperationDelete, "4", ""),
+		},
+		want: `{"index":{"_id":"1","_index":"logs-acme"}}` + "\n" + `{"a":1}` + "\n" +
+			`{"index":{"_id":"2","_index":"logs-acme"}}` + "\n" + `{"a":2}` + "\n" +
+			`{"update":{"_id":"3","_index":"logs-acme"}}` + "\n" + `{"doc":{"a":3},"doc_as_upsert":true}` + "\n" +
+			`{"delete":{"_id":"4","_index":"logs-acme"}}` + "\n",
+	}, {
+		name:    "create action without upsert",
+		config:  ESBulkConfig{Index: "logs", ID: "{{.Key}}", CreateAction: bulkCreate},
+		records: []opencdc.Record{record(opencdc.OperationCreate, "1", `{}`), record(opencdc.OperationUpdate, "1", `{}`)},
+		want: `{"create":{"_id":"1","_index":"logs"}}` + "\n" + `{}` + "\n" +
+			`{"update":{"_id":"1","_index":"logs"}}` + "\n" + `{"doc":{}}` + "\n",
+	}, {
+		name:    "generated IDs",
+		config:  ESBulkConfig{Index: "logs", CreateAction: bulkIndex},
+		records: []opencdc.Record{record(opencdc.OperationCreate, "1", `{}`)},
+		want:    `{"index":{"_index":"logs"}}` + "\n" + `{}` + "\n",
+	}, {
+		name:    "update requires an ID",
+		config:  ESBulkConfig{Index: "logs", CreateAction: bulkIndex},
+		records: []opencdc.Record{record(opencdc.OperationUpdate, "1", `{}`)},
+		wantErr: true,
+	}, {
+		name:    "empty index",
+		config:  ESBulkConfig{Index: "{{.Metadata.missing}}", CreateAction: bulkIndex},
+		records: []opencdc.Record{record(opencdc.OperationCreate, "1", `{}`)},
+		wantErr: true,
+	}, {
+		name:    "document isn't JSON",
+		config:  ESBulkConfig{Index: "logs", CreateAction: bulkIndex},
+		records: []opencdc.Record{record(opencdc.OperationCreate, "1", `id=1`)},
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			e, err := newESBulkEncoder(tc.config, templateFuncs(""))
+			is.NoErr(err)
+			got, err := e.Encode(tc.records, templateOptions{usePayloadAfter: true}, source)
+			if tc.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(string(got), tc.want)
+		})
+	}
+}
+
+func TestNewESBulkEncoderInvalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config ESBulkConfig
+	}{
+		{name: "no index", config: ESBulkConfig{CreateAction: bulkIndex}},
+		{name: "invalid create action", config: ESBulkConfig{Index: "logs", CreateAction: bulkUpdate}},
+		{name: "invalid index template", config: ESBulkConfig{Index: "{{.Key", CreateAction: bulkIndex}},
+		{name: "invalid id template", config: ESBulkConfig{Index: "logs", ID: "{{end}}", CreateAction: bulkIndex}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := newESBulkEncoder(tc.config, templateFuncs(""))
+			is.True(err != nil)
+		})
+	}
+}
+
+func TestParseESBulkResponse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		n        int
+		want     int
+		wantErr  bool
+		itemFail bool
+	}{{
+		name: "no errors",
+		body: `{"errors":false,"items":[{"index":{"status":201}},{"delete":{"status":200}}]}`,
+		n:    2,
+		want: 2,
+	}, {
+		name: "conflicting create and missing delete are acked",
+		body: `{"errors":true,"items":[{"create":{"status":409}},{"delete":{"status":404}}]}`,
+		n:    2,
+		want: 2,
+	}, {
+		name:     "acked up to the first failure",
+		body:     `{"errors":true,"items":[{"index":{"status":201}},{"update":{"status":404,"error":{"type":"document_missing_exception","reason":"missing"}}},{"index":{"status":201}}]}`,
+		n:        3,
+		want:     1,
+		wantErr:  true,
+		itemFail: true,
+	}, {
+		name:     "conflicting index fails",
+		body:     `{"errors":true,"items":[{"index":{"status":409}}]}`,
+		n:        1,
+		want:     0,
+		wantErr:  true,
+		itemFail: true,
+	}, {
+		name:    "item count mismatch",
+		body:    `{"errors":false,"items":[{"index":{"status":201}}]}`,
+		n:       2,
+		wantErr: true,
+	}, {
+		name:    "not JSON",
+		body:    `<html>`,
+		n:       1,
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			got, err := parseESBulkResponse([]byte(tc.body), tc.n)
+			is.Equal(got, tc.want)
+			is.Equal(err != nil, tc.wantErr)
+			is.Equal(errors.Is(err, errBulkItemFailed), tc.itemFail)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package destination
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// SkipFilter describes records that are acked without sending a request.
+// A record is skipped when every configured condition matches.
+type SkipFilter struct {
+	Operations string            `json:"operations"` // Comma-separated: create, update, delete, snapshot
+	Metadata   map[string]string `json:"metadata"`   // Metadata keys and the values they must equal
+	Path       string            `json:"path"`       // JSONPath into the payload, e.g. $.type
+	Value      string            `json:"value"`      // Expected value at path
+}
+
+var operations = map[string]opencdc.Operation{
+	"create":   opencdc.OperationCreate,
+	"update":   opencdc.OperationUpdate,
+	"delete":   opencdc.OperationDelete,
+	"snapshot": opencdc.OperationSnapshot,
+}
+
+// recordFilter is the compiled form of a SkipFilter
+type recordFilter struct {
+	operations map[opencdc.Operation]bool
+	metadata   map[string]string
+	path       *jsonpath.Path
+	value      string
+}
+
+// newRecordFilter compiles a skip filter, returning nil if no condition is configured
+func newRecordFilter(cfg SkipFilter) (*recordFilter, error) {
+	if cfg.Operations == "" && len(cfg.Metadata) == 0 && cfg.Path == "" {
+		return nil, nil
+	}
+
+	f := &recordFilter{
+		metadata: cfg.Metadata,
+		value:    cfg.Value,
+	}
+
+	if cfg.Operations != "" {
+		f.operations = make(map[opencdc.Operation]bool)
+		for _, name := range strings.Split(cfg.Operations, ",") {
+			op, ok := operations[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("invalid operation %q (must be create, update, delete, or snapshot)", name)
+			}
+			f.operations[op] = true
+		}
+	}
+
+	if cfg.Path != "" {
+		path, err := jsonpath.Parse(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path: %w", err)
+		}
+		f.path = path
+	}
+
+	return f, nil
+}
+
+// Matches reports whether the record should be skipped
+func (f *recordFilter) Matches(record opencdc.Record, opts templateOptions) bool {
+	if f.operations != nil && !f.operations[record.Operation] {
+		return false
+	}
+
+	for key, want := range f.metadata {
+		if got, ok := record.Metadata[key]; !ok || got != want {
+			return false
+		}
+	}
+
+	if f.path != nil {
+		v, ok := f.path.Get(newTemplateData(record, opts).Payload)
+		if !ok || jsonpath.Stringify(v) != f.value {
+			return false
+		}
+	}
+
+	return true
+}
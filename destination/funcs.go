@@ -0,0 +1,271 @@
+package destination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// templateFuncs returns the functions available to record templates. They
+// follow sprig's argument order, so the value being transformed comes last and
+// can be piped: {{.Payload.name | default "anonymous" | upper}}. Templates are
+// sandboxed: no function reads files, opens connections or runs processes,
+// and env only reads variables starting with envPrefix (none if it is empty).
+func templateFuncs(envPrefix string) template.FuncMap {
+	return template.FuncMap{
+		// JSON
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"fromJson":     fromJSON,
+
+		// Encoding
+		"b64enc":        b64enc,
+		"b64dec":        b64dec,
+		"urlEncode":     url.QueryEscape,
+		"urlPathEncode": url.PathEscape,
+
+		// Dates
+		"now":       time.Now,
+		"date":      formatDate,
+		"toDate":    time.Parse,
+		"unixEpoch": unixEpoch,
+
+		// Strings
+		"toString":   jsonpath.Stringify,
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"snakecase":  func(s string) string { return strings.Join(lowerWords(s), "_") },
+		"kebabcase":  func(s string) string { return strings.Join(lowerWords(s), "-") },
+		"camelcase":  camelcase,
+
+		// Defaults
+		"default":  func(def, v any) any { return ternary(def, v, empty(v)) },
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+
+		// Environment
+		"env": func(name string) (string, error) {
+			if envPrefix == "" || !strings.HasPrefix(name, envPrefix) {
+				return "", fmt.Errorf("environment variable %s is not accessible to templates (must start with templateEnvPrefix %q)", name, envPrefix)
+			}
+			return os.Getenv(name), nil
+		},
+	}
+}
+
+// toJSON encodes v as compact JSON
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// toPrettyJSON encodes v as indented JSON
+func toPrettyJSON(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// fromJSON decodes a JSON document
+func fromJSON(s string) (any, error) {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// b64enc encodes s as standard base64
+func b64enc(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// b64dec decodes standard base64
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// formatDate formats a time with a Go layout, e.g. {{date "2006-01-02" .Payload.createdAt}}
+func formatDate(layout string, v any) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+// unixEpoch returns a time as seconds since the Unix epoch
+func unixEpoch(v any) (int64, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+// toTime converts a time, an RFC 3339 string or seconds since the Unix epoch
+// to a time, epoch times are in UTC
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		if parsed, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return parsed, nil
+		}
+		secs, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q (must be RFC 3339 or seconds since the epoch)", t)
+		}
+		return epochTime(secs), nil
+	case float64:
+		return epochTime(t), nil
+	case int:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case int64:
+		return time.Unix(t, 0).UTC(), nil
+	case json.Number:
+		secs, err := t.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return epochTime(secs), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid time of type %T", v)
+	}
+}
+
+// epochTime converts fractional seconds since the Unix epoch to a time
+func epochTime(secs float64) time.Time {
+	return time.UnixMilli(int64(secs * 1000)).UTC()
+}
+
+// join joins the elements of a list, converting them to strings
+func join(sep string, list any) string {
+	switch l := list.(type) {
+	case []string:
+		return strings.Join(l, sep)
+	case []any:
+		parts := make([]string, len(l))
+		for i, v := range l {
+			parts[i] = jsonpath.Stringify(v)
+		}
+		return strings.Join(parts, sep)
+	default:
+		return jsonpath.Stringify(list)
+	}
+}
+
+// title upper-cases the first letter of every word
+func title(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '-' || runes[i-1] == '_' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// camelcase joins the words of s as lowerCamelCase
+func camelcase(s string) string {
+	words := lowerWords(s)
+	for i := 1; i < len(words); i++ {
+		first, size := utf8.DecodeRuneInString(words[i])
+		words[i] = string(unicode.ToUpper(first)) + words[i][size:]
+	}
+	return strings.Join(words, "")
+}
+
+// lowerWords splits s into lower-case words at non-alphanumeric characters and
+// lower to upper case transitions, e.g. "userId" and "user_id" are [user id]
+func lowerWords(s string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]))):
+			// userId, HTTPServer
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// empty reports whether v is nil or the zero value of its type, or an empty
+// string, slice or map
+func empty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+// coalesce returns the first value that is not empty
+func coalesce(values ...any) any {
+	for _, v := range values {
+		if !empty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+// ternary returns a if cond is true, b otherwise
+func ternary(a, b any, cond bool) any {
+	if cond {
+		return a
+	}
+	return b
+}
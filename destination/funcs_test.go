@@ -0,0 +1,61 @@
+package destination
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCaseFuncs(t *testing.T) {
+	testCases := []struct {
+		in        string
+		camel     string
+		snake     string
+		titleCase string
+	}{
+		{in: "user_id", camel: "userId", snake: "user_id", titleCase: "User_Id"},
+		{in: "userId", camel: "userId", snake: "user_id", titleCase: "UserId"},
+		{in: "HTTPServer error", camel: "httpServerError", snake: "http_server_error", titleCase: "HTTPServer Error"},
+		{in: "  ", camel: "", snake: "", titleCase: "  "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(camelcase(tc.in), tc.camel)
+			funcs := templateFuncs("")
+			is.Equal(funcs["snakecase"].(func(string) string)(tc.in), tc.snake)
+			is.Equal(title(tc.in), tc.titleCase)
+		})
+	}
+}
+
+func TestDefaultFuncs(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value any
+		empty bool
+	}{
+		{name: "nil", value: nil, empty: true},
+		{name: "empty string", value: "", empty: true},
+		{name: "zero", value: float64(0), empty: true},
+		{name: "false", value: false, empty: true},
+		{name: "empty slice", value: []any{}, empty: true},
+		{name: "empty map", value: map[string]any{}, empty: true},
+		{name: "string", value: "x", empty: false},
+		{name: "number", value: float64(1), empty: false},
+		{name: "slice", value: []any{nil}, empty: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(empty(tc.value), tc.empty)
+			if tc.empty {
+				is.Equal(coalesce(tc.value, "fallback"), "fallback")
+			} else {
+				is.Equal(coalesce(tc.value, "fallback"), tc.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"strconv"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	connerrors "github.com/dev-in-black/connector-http/internal/errors"
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// errItemFailed marks records whose item in a batch response failed
+var errItemFailed = errors.New("batch item failed")
+
+// itemResult is the outcome of a record reported by a batch response, a
+// record without item has status 0
+type itemResult struct {
+	status  int
+	message string
+}
+
+// itemMapper correlates the items of batch responses with the records of the
+// batch, by position or by the index each item holds
+type itemMapper struct {
+	items   *jsonpath.Path
+	status  *jsonpath.Path
+	index   *jsonpath.Path // nil to match items by position
+	message *jsonpath.Path // nil without error messages
+}
+
+// newItemMapper compiles the item paths, returning nil if no items path is
+// configured
+func newItemMapper(cfg BatchItemsConfig) (*itemMapper, error) {
+	if cfg.Path == "" {
+		return nil, nil
+	}
+
+	m := &itemMapper{}
+	var err error
+	if m.items, err = jsonpath.Parse(cfg.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	if m.status, err = jsonpath.Parse(cfg.StatusPath); err != nil {
+		return nil, fmt.Errorf("invalid statusPath: %w", err)
+	}
+	if cfg.IndexPath != "" {
+		if m.index, err = jsonpath.Parse(cfg.IndexPath); err != nil {
+			return nil, fmt.Errorf("invalid indexPath: %w", err)
+		}
+	}
+	if cfg.ErrorPath != "" {
+		if m.message, err = jsonpath.Parse(cfg.ErrorPath); err != nil {
+			return nil, fmt.Errorf("invalid errorPath: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Parse returns the result of each of the n records of a batch from the
+// response body
+func (m *itemMapper) Parse(body []byte, n int) ([]itemResult, error) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid batch response: %w", err)
+	}
+	value, ok := m.items.Get(doc)
+	if !ok {
+		return nil, fmt.Errorf("invalid batch response: no items at %s", m.items)
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid batch response: items at %s are not an array", m.items)
+	}
+	if m.index == nil && len(items) != n {
+		return nil, fmt.Errorf("invalid batch response: %d items for %d records", len(items), n)
+	}
+
+	results := make([]itemResult, n)
+	for i, item := range items {
+		record := i
+		if m.index != nil {
+			v, _ := m.index.Get(item)
+			index, ok := itemNumber(v)
+			if !ok || index < 0 || index >= n {
+				return nil, fmt.Errorf("invalid batch response: item %d has no index of the %d records at %s", i, n, m.index)
+			}
+			record = index
+		}
+
+		v, _ := m.status.Get(item)
+		status, ok := itemNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid batch response: item %d has no status at %s", i, m.status)
+		}
+		results[record].status = status
+		if m.message != nil {
+			if v, ok := m.message.Get(item); ok && v != nil {
+				results[record].message = jsonpath.Stringify(v)
+			}
+		}
+	}
+	return results, nil
+}
+
+// err returns the error of a failed item
+func (r itemResult) err() error {
+	if r.message == "" {
+		return fmt.Errorf("%w: status %d", errItemFailed, r.status)
+	}
+	return fmt.Errorf("%w: status %d: %s", errItemFailed, r.status, r.message)
+}
+
+// itemNumber converts a JSON number or numeric string to an int
+func itemNumber(v any) (int, bool) {
+	switch v := v.(type) {
+	case float64:
+		if v != float64(int(v)) {
+			return 0, false
+		}
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// ackItems acks the records whose item succeeded and sends those whose item
+// is retried again, as a batch of their own, up to retry.max times. It returns
+// the records acked up to the first that failed; records after it that
+// succeeded are skipped on redelivery if dedup or the delivery log is enabled.
+func (d *Destination) ackItems(ctx context.Context, rows []opencdc.Record, keys []string, respBody []byte) (int, error) {
+	errs := make([]error, len(rows)) // Error of each record, nil once acked
+	pending := make([]int, len(rows))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for attempt := 0; ; attempt++ {
+		results, err := d.items.Parse(respBody, len(pending))
+		if err != nil {
+			for _, i := range pending {
+				errs[i] = err
+			}
+			break
+		}
+
+		var retry []int
+		for j, result := range results {
+			i := pending[j]
+			switch d.itemAction(result.status) {
+			case http.ActionAck, http.ActionIgnore:
+				d.markDelivered(rows[i], keys[i])
+				errs[i] = nil
+			case http.ActionRetry:
+				retry = append(retry, i)
+				errs[i] = result.err()
+			default:
+				errs[i] = connerrors.WithCategory(result.err(), connerrors.ErrNonRetryableStatus)
+			}
+		}
+		if len(retry) == 0 || attempt >= d.config.Retry.Max {
+			break
+		}
+
+		backoff := min(d.config.Retry.BackoffBase<<attempt, d.config.Retry.BackoffMax)
+		sdk.Logger(ctx).Debug().
+			Int("records", len(retry)).
+			Dur("backoff", backoff).
+			Msg("Retrying failed batch items")
+		if !sleepContext(ctx, backoff) {
+			break
+		}
+
+		subset := make([]opencdc.Record, len(retry))
+		for j, i := range retry {
+			subset[j] = rows[i]
+		}
+		body, err := d.encodeBatch(ctx, subset)
+		if err == nil {
+			respBody, err = d.send(ctx, d.config.URL, body, nil)
+		}
+		if err != nil {
+			for _, i := range retry {
+				errs[i] = err
+			}
+			break
+		}
+		if respBody == nil {
+			// The response was ignored or acked without being read
+			for _, i := range retry {
+				d.markDelivered(rows[i], keys[i])
+				errs[i] = nil
+			}
+			break
+		}
+		pending = retry
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return i, d.recordError(err)
+		}
+	}
+	return len(rows), nil
+}
+
+// itemAction returns the action for the status of a batch item. Status rules
+// apply as to responses; otherwise 429 and 5xx are retried as the retry
+// policy retries requests, and a record without item is acked.
+func (d *Destination) itemAction(status int) http.Action {
+	if action, ok := d.statusRules.Match(status); ok {
+		return action
+	}
+	switch {
+	case status == 0 || (status >= 200 && status < 300):
+		return http.ActionAck
+	case status == stdhttp.StatusTooManyRequests && d.config.Retry.On429,
+		status >= 500 && d.config.Retry.On5xx:
+		return http.ActionRetry
+	}
+	return http.ActionFail
+}
@@ -0,0 +1,117 @@
+package destination
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestItemMapperParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  BatchItemsConfig
+		body    string
+		n       int
+		want    []itemResult
+		wantErr bool
+	}{{
+		name:   "by position",
+		config: BatchItemsConfig{Path: "$.results", StatusPath: "$.status"},
+		body:   `{"results":[{"status":201},{"status":"409"}]}`,
+		n:      2,
+		want:   []itemResult{{status: 201}, {status: 409}},
+	}, {
+		name:   "top-level array with messages",
+		config: BatchItemsConfig{Path: "$", StatusPath: "$.code", ErrorPath: "$.error.message"},
+		body:   `[{"code":200,"error":null},{"code":400,"error":{"message":"invalid email"}}]`,
+		n:      2,
+		want:   []itemResult{{status: 200}, {status: 400, message: "invalid email"}},
+	}, {
+		name:   "by index, failed items only",
+		config: BatchItemsConfig{Path: "$.errors", StatusPath: "$.status", IndexPath: "$.index"},
+		body:   `{"errors":[{"index":2,"status":422},{"index":"0","status":500}]}`,
+		n:      3,
+		want:   []itemResult{{status: 500}, {}, {status: 422}},
+	}, {
+		name:    "count mismatch by position",
+		config:  BatchItemsConfig{Path: "$.results", StatusPath: "$.status"},
+		body:    `{"results":[{"status":201}]}`,
+		n:       2,
+		wantErr: true,
+	}, {
+		name:    "index out of range",
+		config:  BatchItemsConfig{Path: "$.results", StatusPath: "$.status", IndexPath: "$.index"},
+		body:    `{"results":[{"index":2,"status":201}]}`,
+		n:       2,
+		wantErr: true,
+	}, {
+		name:    "fractional status",
+		config:  BatchItemsConfig{Path: "$.results", StatusPath: "$.status"},
+		body:    `{"results":[{"status":200.5}]}`,
+		n:       1,
+		wantErr: true,
+	}, {
+		name:    "missing status",
+		config:  BatchItemsConfig{Path: "$.results", StatusPath: "$.status"},
+		body:    `{"results":[{"code":200}]}`,
+		n:       1,
+		wantErr: true,
+	}, {
+		name:    "items aren't an array",
+		config:  BatchItemsConfig{Path: "$.results", StatusPath: "$.status"},
+		body:    `{"results":{"status":200}}`,
+		n:       1,
+		wantErr: true,
+	}, {
+		name:    "no items",
+		config:  BatchItemsConfig{Path: "$.results", StatusPath: "$.status"},
+		body:    `{"data":[]}`,
+		n:       1,
+		wantErr: true,
+	}, {
+		name:    "not JSON",
+		config:  BatchItemsConfig{Path: "$.results", StatusPath: "$.status"},
+		body:    `OK`,
+		n:       1,
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			m, err := newItemMapper(tc.config)
+			is.NoErr(err)
+			got, err := m.Parse([]byte(tc.body), tc.n)
+			if tc.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(got, tc.want)
+		})
+	}
+}
+
+func TestNewItemMapper(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  BatchItemsConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "unset", config: BatchItemsConfig{StatusPath: "$.status"}, wantNil: true},
+		{name: "valid", config: BatchItemsConfig{Path: "$.results", StatusPath: "$.status", IndexPath: "$.i", ErrorPath: "$.e"}},
+		{name: "invalid path", config: BatchItemsConfig{Path: "$.results[", StatusPath: "$.status"}, wantErr: true},
+		{name: "invalid status path", config: BatchItemsConfig{Path: "$.results", StatusPath: "status["}, wantErr: true},
+		{name: "invalid index path", config: BatchItemsConfig{Path: "$.results", StatusPath: "$.status", IndexPath: "$.["}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			m, err := newItemMapper(tc.config)
+			is.Equal(err != nil, tc.wantErr)
+			is.Equal(m == nil, tc.wantNil || tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/itchyny/gojq"
+)
+
+// BodyTransform configures an expression transforming the payload into the
+// request body
+type BodyTransform struct {
+	// JQ is a jq expression transforming the JSON payload into the request
+	// body. $key, $metadata and $operation hold the rest of the record.
+	JQ string `json:"jq"`
+}
+
+// jqVariables are the record fields available to jq expressions besides the payload
+var jqVariables = []string{"$key", "$metadata", "$operation"}
+
+// jqTransform is a compiled jq expression. It is sandboxed like templates:
+// $ENV and env only contain variables starting with envPrefix.
+type jqTransform struct {
+	code *gojq.Code
+}
+
+// newJQTransform compiles a jq expression
+func newJQTransform(expr, envPrefix string) (*jqTransform, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	code, err := gojq.Compile(query,
+		gojq.WithVariables(jqVariables),
+		gojq.WithEnvironLoader(func() []string {
+			var env []string
+			if envPrefix == "" {
+				return env
+			}
+			for _, kv := range os.Environ() {
+				if strings.HasPrefix(kv, envPrefix) {
+					env = append(env, kv)
+				}
+			}
+			return env
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &jqTransform{code: code}, nil
+}
+
+// Apply runs the expression on the record's payload and returns the JSON
+// encoding of its first result
+func (t *jqTransform) Apply(ctx context.Context, record opencdc.Record, usePayloadAfter bool) ([]byte, error) {
+	payload := record.Payload.Before
+	if usePayloadAfter && record.Payload.After != nil {
+		payload = record.Payload.After
+	}
+	if payload == nil {
+		return nil, fmt.Errorf("record has no payload")
+	}
+
+	// Decoding the JSON encoding yields the value types jq works with
+	var input any
+	if err := json.Unmarshal(payload.Bytes(), &input); err != nil {
+		return nil, fmt.Errorf("payload is not JSON: %w", err)
+	}
+
+	var key any
+	if record.Key != nil {
+		key = string(record.Key.Bytes())
+	}
+	metadata := make(map[string]any, len(record.Metadata))
+	for k, v := range record.Metadata {
+		metadata[k] = v
+	}
+
+	iter := t.code.RunWithContext(ctx, input, key, metadata, record.Operation.String())
+	v, ok := iter.Next()
+	if !ok {
+		return nil, fmt.Errorf("jq expression produced no output")
+	}
+	if err, ok := v.(error); ok {
+		return nil, fmt.Errorf("jq expression failed: %w", err)
+	}
+
+	body, err := gojq.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode jq output: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,65 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/kafka"
+)
+
+// openKafkaClusters creates the producers of kafka.clusters, in the order of
+// their names, with the credentials resolved in credentials
+func (d *Destination) openKafkaClusters(ctx context.Context, credentials Config) error {
+	names := slices.Collect(maps.Keys(d.config.Kafka.Clusters))
+	sort.Strings(names)
+	for _, name := range names {
+		cfg := d.config.Kafka.Clusters[name]
+		statusCodes, err := http.ParseStatusRanges(cfg.StatusCodes)
+		if err != nil {
+			return fmt.Errorf("invalid kafka.clusters.%s.statusCodes: %w", name, err)
+		}
+
+		producer, err := kafka.NewProducer(ctx, kafka.Config{
+			Brokers:           cfg.Brokers,
+			Topic:             cfg.Topic,
+			ClientID:          cfg.ClientID,
+			Compression:       cfg.Compression,
+			EnableIdempotence: cfg.EnableIdempotence,
+			SASLEnabled:       cfg.SASL.Enabled,
+			SASLMechanism:     cfg.SASL.Mechanism,
+			SASLUsername:      credentials.Kafka.Clusters[name].SASL.Username,
+			SASLPassword:      credentials.Kafka.Clusters[name].SASL.Password,
+			TLSEnabled:        cfg.TLS.Enabled,
+			Partitioner:       d.config.Kafka.Partitioner,
+			PartitionHeader:   d.config.Kafka.PartitionMetadataKey,
+			KeyHeader:         d.config.Kafka.KeyMetadataKey,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create Kafka producer of cluster %s: %w", name, err)
+		}
+		selector := responseSelector{
+			statusCodes:    statusCodes,
+			metadataKey:    cfg.MetadataKey,
+			metadataValues: cfg.MetadataValues,
+		}
+		d.publishers = append(d.publishers, &publisher{
+			name:      "kafka cluster " + name,
+			target:    cfg.Topic,
+			publisher: producer,
+			selects:   selector.selects,
+		})
+
+		sdk.Logger(ctx).Info().
+			Str("cluster", name).
+			Str("topic", cfg.Topic).
+			Strs("brokers", cfg.Brokers).
+			Msg("Kafka producer initialized")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package destination_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+
+	connector "github.com/dev-in-black/connector-http"
+	"github.com/dev-in-black/connector-http/destination"
+	"github.com/dev-in-black/connector-http/internal/harness"
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+func TestKafkaPublishesResponses(t *testing.T) {
+	is := is.New(t)
+	brokers := harness.KafkaBrokers(t)
+	endpoint := harness.NewEndpoint(t)
+	endpoint.SetDefault(harness.Response{
+		Status:  http.StatusCreated,
+		Headers: http.Header{"Content-Type": {"application/json"}},
+		Body:    `{"id":42}`,
+	})
+	topic := kafkaTopic(t)
+
+	writeRecords(t, map[string]string{
+		"url":           endpoint.URL(),
+		"kafka.enabled": "true",
+		"kafka.brokers": strings.Join(brokers, ","),
+		"kafka.topic":   topic,
+	}, testRecord("1"), testRecord("2"))
+
+	msgs := harness.ReadTopic(t, brokers, topic)
+	is.Equal(len(msgs), 2)
+	for _, msg := range msgs {
+		var got response.Message
+		is.NoErr(json.Unmarshal(msg.Value, &got))
+		is.Equal(got.StatusCode, http.StatusCreated)
+		is.Equal(got.RequestMethod, http.MethodPost)
+		is.Equal(got.RequestURL, endpoint.URL())
+		is.Equal(got.Attempts, 1)
+		is.Equal(got.Body, map[string]any{"id": float64(42)})
+	}
+}
+
+func TestKafkaPublishesRetriedResponses(t *testing.T) {
+	is := is.New(t)
+	brokers := harness.KafkaBrokers(t)
+	endpoint := harness.NewEndpoint(t)
+	endpoint.Script(
+		harness.Response{Status: http.StatusServiceUnavailable},
+		harness.Response{Status: http.StatusOK, Body: "ok"},
+	)
+	topic := kafkaTopic(t)
+
+	writeRecords(t, map[string]string{
+		"url":               endpoint.URL(),
+		"retry.max":         "1",
+		"retry.backoffBase": "10ms",
+		"kafka.enabled":     "true",
+		"kafka.brokers":     strings.Join(brokers, ","),
+		"kafka.topic":       topic,
+	}, testRecord("1"))
+
+	is.Equal(len(endpoint.Requests()), 2)
+	msgs := harness.ReadTopic(t, brokers, topic)
+	is.Equal(len(msgs), 1)
+	var got response.Message
+	is.NoErr(json.Unmarshal(msgs[0].Value, &got))
+	is.Equal(got.StatusCode, http.StatusOK)
+	is.Equal(got.Attempts, 2)
+	is.Equal(got.Body, "ok")
+}
+
+// writeRecords writes records with a destination with settings, published
+// responses are flushed once it is torn down
+func writeRecords(t *testing.T, settings map[string]string, records ...opencdc.Record) {
+	t.Helper()
+	is := is.New(t)
+	ctx := context.Background()
+
+	dest := destination.NewDestination()
+	is.NoErr(sdk.Util.ParseConfig(ctx, settings, dest.Config(), connector.Connector.NewSpecification().DestinationParams))
+	is.NoErr(dest.Open(ctx))
+	n, err := dest.Write(ctx, records)
+	is.NoErr(err)
+	is.Equal(n, len(records))
+	is.NoErr(dest.Teardown(ctx))
+}
+
+// kafkaTopic returns a topic of its own for the test
+func kafkaTopic(t *testing.T) string {
+	return fmt.Sprintf("http-responses-%s-%d", strings.ToLower(t.Name()), time.Now().UnixNano())
+}
@@ -0,0 +1,203 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/conduitio/conduit-commons/config"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// LegacyConfig holds the flat parameters of earlier versions. They are
+// deprecated aliases of the nested parameters that replaced them, which take
+// precedence when set.
+type LegacyConfig struct {
+	// Deprecated: use auth.type
+	AuthType string `json:"authType"`
+	// Deprecated: use auth.basic.username
+	BasicUsername string `json:"basicUsername"`
+	// Deprecated: use auth.basic.password
+	BasicPassword string `json:"basicPassword"`
+	// Deprecated: use auth.bearer.token
+	BearerToken string `json:"bearerToken"`
+	// Deprecated: use auth.oauth2.clientId
+	OAuth2ClientID string `json:"oauth2ClientId"`
+	// Deprecated: use auth.oauth2.clientSecret
+	OAuth2ClientSecret string `json:"oauth2ClientSecret"`
+	// Deprecated: use auth.oauth2.tokenUrl
+	OAuth2TokenURL string `json:"oauth2TokenUrl"`
+	// Deprecated: use auth.oauth2.scopes
+	OAuth2Scopes string `json:"oauth2Scopes"`
+
+	// Deprecated: use retry.max
+	MaxRetries string `json:"maxRetries"`
+	// Deprecated: use retry.backoffBase
+	RetryBackoffBase string `json:"retryBackoffBase"`
+	// Deprecated: use retry.backoffMax
+	RetryBackoffMax string `json:"retryBackoffMax"`
+	// Deprecated: use retry.on5xx
+	RetryOn5xx string `json:"retryOn5xx"`
+	// Deprecated: use retry.on429
+	RetryOn429 string `json:"retryOn429"`
+	// Deprecated: use retry.onNetworkError
+	RetryOnNetworkErr string `json:"retryOnNetworkErr"`
+
+	// Deprecated: use kafka.enabled
+	KafkaEnabled string `json:"kafkaEnabled"`
+	// Deprecated: use kafka.brokers
+	KafkaBrokers string `json:"kafkaBrokers"`
+	// Deprecated: use kafka.topic
+	KafkaTopic string `json:"kafkaTopic"`
+	// Deprecated: use kafka.clientId
+	KafkaClientID string `json:"kafkaClientId"`
+	// Deprecated: use kafka.compression
+	KafkaCompression string `json:"kafkaCompression"`
+	// Deprecated: use kafka.enableIdempotence
+	KafkaEnableIdempotence string `json:"kafkaEnableIdempotence"`
+	// Deprecated: use kafka.sasl.enabled
+	KafkaSASLEnabled string `json:"kafkaSaslEnabled"`
+	// Deprecated: use kafka.sasl.mechanism
+	KafkaSASLMechanism string `json:"kafkaSaslMechanism"`
+	// Deprecated: use kafka.sasl.username
+	KafkaSASLUsername string `json:"kafkaSaslUsername"`
+	// Deprecated: use kafka.sasl.password
+	KafkaSASLPassword string `json:"kafkaSaslPassword"`
+	// Deprecated: use kafka.tls.enabled
+	KafkaTLSEnabled string `json:"kafkaTlsEnabled"`
+}
+
+// legacyAlias is a deprecated parameter and the parameter replacing it
+type legacyAlias struct {
+	from  string
+	to    string
+	value string
+}
+
+// aliases returns the deprecated parameters with the parameters replacing them
+func (l *LegacyConfig) aliases() []legacyAlias {
+	return []legacyAlias{
+		{"authType", "auth.type", l.AuthType},
+		{"basicUsername", "auth.basic.username", l.BasicUsername},
+		{"basicPassword", "auth.basic.password", l.BasicPassword},
+		{"bearerToken", "auth.bearer.token", l.BearerToken},
+		{"oauth2ClientId", "auth.oauth2.clientId", l.OAuth2ClientID},
+		{"oauth2ClientSecret", "auth.oauth2.clientSecret", l.OAuth2ClientSecret},
+		{"oauth2TokenUrl", "auth.oauth2.tokenUrl", l.OAuth2TokenURL},
+		{"oauth2Scopes", "auth.oauth2.scopes", l.OAuth2Scopes},
+
+		{"maxRetries", "retry.max", l.MaxRetries},
+		{"retryBackoffBase", "retry.backoffBase", l.RetryBackoffBase},
+		{"retryBackoffMax", "retry.backoffMax", l.RetryBackoffMax},
+		{"retryOn5xx", "retry.on5xx", l.RetryOn5xx},
+		{"retryOn429", "retry.on429", l.RetryOn429},
+		{"retryOnNetworkErr", "retry.onNetworkError", l.RetryOnNetworkErr},
+
+		{"kafkaEnabled", "kafka.enabled", l.KafkaEnabled},
+		{"kafkaBrokers", "kafka.brokers", l.KafkaBrokers},
+		{"kafkaTopic", "kafka.topic", l.KafkaTopic},
+		{"kafkaClientId", "kafka.clientId", l.KafkaClientID},
+		{"kafkaCompression", "kafka.compression", l.KafkaCompression},
+		{"kafkaEnableIdempotence", "kafka.enableIdempotence", l.KafkaEnableIdempotence},
+		{"kafkaSaslEnabled", "kafka.sasl.enabled", l.KafkaSASLEnabled},
+		{"kafkaSaslMechanism", "kafka.sasl.mechanism", l.KafkaSASLMechanism},
+		{"kafkaSaslUsername", "kafka.sasl.username", l.KafkaSASLUsername},
+		{"kafkaSaslPassword", "kafka.sasl.password", l.KafkaSASLPassword},
+		{"kafkaTlsEnabled", "kafka.tls.enabled", l.KafkaTLSEnabled},
+	}
+}
+
+// DeprecatedParams returns the parameters replacing the deprecated
+// parameters, keyed by the deprecated ones
+func DeprecatedParams() map[string]string {
+	var l LegacyConfig
+	params := make(map[string]string)
+	for _, alias := range l.aliases() {
+		params[alias.from] = alias.to
+	}
+	return params
+}
+
+// applyLegacyConfig copies the deprecated parameters that are set to the
+// parameters replacing them and clears them. Parameters replacing them that
+// are set to a value other than their default are kept.
+func (c *Config) applyLegacyConfig(ctx context.Context) error {
+	var deprecated []string
+	values := make(config.Config)
+	for _, alias := range c.LegacyConfig.aliases() {
+		if alias.value == "" {
+			continue
+		}
+		deprecated = append(deprecated, alias.from)
+		set, err := c.paramSet(alias.to)
+		if err != nil {
+			return err
+		}
+		if !set {
+			values[alias.to] = alias.value
+		}
+	}
+
+	if len(values) > 0 {
+		if err := values.DecodeInto(c); err != nil {
+			return fmt.Errorf("invalid deprecated parameters: %w", err)
+		}
+	}
+
+	if len(deprecated) > 0 {
+		sdk.Logger(ctx).Warn().
+			Strs("parameters", deprecated).
+			Msg("Deprecated flat parameters are set, use the nested parameters replacing them")
+	}
+
+	c.LegacyConfig = LegacyConfig{}
+	return nil
+}
+
+// paramSet reports whether the parameter name holds a value other than its
+// default, parameters are set to their default before the config is decoded
+func (c *Config) paramSet(name string) (bool, error) {
+	field, tag, ok := paramField(reflect.ValueOf(c).Elem(), name)
+	if !ok {
+		return false, fmt.Errorf("unknown parameter %s", name)
+	}
+
+	var defaults Config
+	if value := tag.Get("default"); value != "" {
+		if err := (config.Config{name: value}).DecodeInto(&defaults); err != nil {
+			return false, fmt.Errorf("invalid default of %s: %w", name, err)
+		}
+	}
+	def, _, _ := paramField(reflect.ValueOf(&defaults).Elem(), name)
+	return !reflect.DeepEqual(field.Interface(), def.Interface()), nil
+}
+
+// paramField returns the field of the struct v holding the parameter name,
+// e.g. retry.max, and its struct tag. Embedded structs without a json name
+// hold parameters of the struct embedding them.
+func paramField(v reflect.Value, name string) (reflect.Value, reflect.StructTag, bool) {
+	key, rest, nested := strings.Cut(name, ".")
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && jsonName == "" && field.Type.Kind() == reflect.Struct {
+			if value, tag, ok := paramField(v.Field(i), name); ok {
+				return value, tag, true
+			}
+			continue
+		}
+		if jsonName != key {
+			continue
+		}
+		if !nested {
+			return v.Field(i), field.Tag, true
+		}
+		if field.Type.Kind() != reflect.Struct {
+			return reflect.Value{}, "", false
+		}
+		return paramField(v.Field(i), rest)
+	}
+	return reflect.Value{}, "", false
+}
@@ -0,0 +1,134 @@
+package destination
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	connerrors "github.com/dev-in-black/connector-http/internal/errors"
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/metrics"
+)
+
+// destinationMetrics are the counters of a destination, kept across config
+// updates and served at metrics.address
+type destinationMetrics struct {
+	registry       *metrics.Registry
+	recordsWritten *expvar.Int
+	recordsFailed  *expvar.Int
+	failures       *expvar.Map // Failed records by error category, other if uncategorized
+	requests       *expvar.Int // Attempts, including retries
+	retries        *expvar.Int
+	slowRequests   *expvar.Int // Attempts exceeding slowRequestThreshold
+}
+
+// newDestinationMetrics registers the counters of d and gauges of its queues
+func newDestinationMetrics(d *Destination) *destinationMetrics {
+	r := metrics.NewRegistry()
+	m := &destinationMetrics{
+		registry:       r,
+		recordsWritten: r.Counter("records_written"),
+		recordsFailed:  r.Counter("records_failed"),
+		failures:       r.Counters("failures"),
+		requests:       r.Counter("requests"),
+		retries:        r.Counter("retries"),
+		slowRequests:   r.Counter("slow_requests"),
+	}
+
+	// The components are replaced by config updates. Gauges don't wait for
+	// an update, which stops the metrics server, and are null meanwhile.
+	r.Gauge("records_in_flight", d.gauge(func() any {
+		return d.drainState.inFlight()
+	}))
+	r.Gauge("records_buffered", d.gauge(func() any {
+		if d.buffer == nil {
+			return 0
+		}
+		return d.buffer.Len()
+	}))
+	r.Gauge("connections", d.gauge(func() any {
+		if d.httpClient == nil {
+			return nil
+		}
+		return poolMetrics(d.httpClient.PoolStats())
+	}))
+	r.Gauge("callbacks_pending", d.gauge(func() any {
+		if d.callbacks == nil {
+			return 0
+		}
+		return d.callbacks.Pending()
+	}))
+	return m
+}
+
+// gauge returns a gauge reading the components of d with fn, or null while
+// they are replaced or d isn't open
+func (d *Destination) gauge(fn func() any) func() any {
+	return func() any {
+		if !d.reloadMu.TryRLock() {
+			return nil
+		}
+		defer d.reloadMu.RUnlock()
+		if !d.opened {
+			return nil
+		}
+		return fn()
+	}
+}
+
+// written counts the records of a Write call, err is the error of the first
+// record not written
+func (m *destinationMetrics) written(n int, err error) {
+	m.recordsWritten.Add(int64(n))
+	if err != nil {
+		m.recordsFailed.Add(1)
+		category := connerrors.CategoryName(err)
+		if category == "" {
+			category = "other"
+		}
+		m.failures.Add(category, 1)
+	}
+}
+
+// retryHooks count the attempts and retries of the retry engine
+func (m *destinationMetrics) retryHooks() http.RetryHooks {
+	return http.RetryHookFuncs{
+		Attempt: func(context.Context, int, http.Attempt) {
+			m.requests.Add(1)
+		},
+		RetryScheduled: func(context.Context, int, time.Duration, http.Attempt) {
+			m.retries.Add(1)
+		},
+	}
+}
+
+// openMetrics serves the metrics at metrics.address
+func (d *Destination) openMetrics(ctx context.Context) error {
+	if d.config.Metrics.Address == "" {
+		return nil
+	}
+	server, err := metrics.Serve(d.config.Metrics.Address, d.metrics.registry)
+	if err != nil {
+		return err
+	}
+	d.metricsServer = server
+
+	sdk.Logger(ctx).Info().
+		Str("address", server.Addr()).
+		Str("path", metrics.Path).
+		Msg("Metrics endpoint started")
+	return nil
+}
+
+// closeMetrics stops serving the metrics
+func (d *Destination) closeMetrics(ctx context.Context) {
+	if d.metricsServer == nil {
+		return
+	}
+	if err := d.metricsServer.Close(ctx); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to stop metrics endpoint")
+	}
+	d.metricsServer = nil
+}
@@ -0,0 +1,128 @@
+package destination
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// OperationsConfig maps the operations of records to the method and path of
+// their requests, the common shape of REST resources
+type OperationsConfig struct {
+	// Create routes create records, and snapshot records unless snapshot is set.
+	Create OperationRoute `json:"create"`
+	// Update routes update records.
+	Update OperationRoute `json:"update"`
+	// Delete routes delete records.
+	Delete OperationRoute `json:"delete"`
+	// Snapshot routes snapshot records.
+	Snapshot OperationRoute `json:"snapshot"`
+}
+
+// OperationRoute is the method and path of the requests of an operation
+type OperationRoute struct {
+	// Method is the method of the requests: POST, PUT or PATCH. Empty uses
+	// method.
+	Method string `json:"method"`
+	// Path is a template of the path appended to the URL of the record, e.g.
+	// /items/{{.Key | urlPathEncode}}. Empty sends requests to the URL.
+	Path string `json:"path"`
+}
+
+// isSet reports whether the route changes the method or the URL
+func (r OperationRoute) isSet() bool {
+	return r.Method != "" || r.Path != ""
+}
+
+// operationRoute is the compiled route of an operation
+type operationRoute struct {
+	method string          // Empty to use the method of the record
+	path   *recordTemplate // nil to send requests to the URL of the record
+}
+
+// routes returns the configured routes by the name of their operation
+func (c OperationsConfig) routes() map[string]OperationRoute {
+	routes := make(map[string]OperationRoute)
+	for name, route := range map[string]OperationRoute{
+		"create":   c.Create,
+		"update":   c.Update,
+		"delete":   c.Delete,
+		"snapshot": c.Snapshot,
+	} {
+		if route.isSet() {
+			routes[name] = route
+		}
+	}
+	return routes
+}
+
+// operationRoutes are the compiled routes by operation
+type operationRoutes map[opencdc.Operation]*operationRoute
+
+// parseOperationRoutes compiles the routes of the operations. Snapshots use
+// the route of creates unless they have their own.
+func parseOperationRoutes(cfg OperationsConfig, funcs template.FuncMap) (operationRoutes, error) {
+	operations := map[string]opencdc.Operation{
+		"create":   opencdc.OperationCreate,
+		"update":   opencdc.OperationUpdate,
+		"delete":   opencdc.OperationDelete,
+		"snapshot": opencdc.OperationSnapshot,
+	}
+	routes := make(operationRoutes)
+	for name, route := range cfg.routes() {
+		compiled := &operationRoute{method: route.Method}
+		if route.Path != "" {
+			var err error
+			compiled.path, err = parseRecordTemplate("operations."+name+".path", route.Path, funcs)
+			if err != nil {
+				return nil, fmt.Errorf("invalid operations.%s.path: %w", name, err)
+			}
+		}
+		routes[operations[name]] = compiled
+	}
+	if _, ok := routes[opencdc.OperationSnapshot]; !ok {
+		if create, ok := routes[opencdc.OperationCreate]; ok {
+			routes[opencdc.OperationSnapshot] = create
+		}
+	}
+	return routes, nil
+}
+
+// validateOperations checks the routes of the operations, which are sent per
+// record
+func (c *Config) validateOperations() error {
+	routes := c.Operations.routes()
+	if len(routes) == 0 {
+		return nil
+	}
+	switch {
+	case c.BatchBody.Format != "none":
+		return fmt.Errorf("operations cannot be used with batchBody.format %s", c.BatchBody.Format)
+	case c.Stream.Enabled:
+		return fmt.Errorf("operations cannot be used with stream.enabled")
+	case c.GRPC.Method != "":
+		return fmt.Errorf("operations cannot be used with grpc.method")
+	}
+	for name, route := range routes {
+		if route.Method != "" && route.Method != "POST" && route.Method != "PUT" && route.Method != "PATCH" {
+			return fmt.Errorf("invalid operations.%s.method: %s (must be POST, PUT, or PATCH)", name, route.Method)
+		}
+		if route.Path != "" && c.GetUnixSocketPath() != "" && c.UnixSocketPath == "" {
+			return fmt.Errorf("operations.%s.path cannot be used with a unix:// url, use unixSocketPath", name)
+		}
+	}
+
+	// DELETE requests are sent by the delete strategy
+	if c.DeleteStrategy == deleteStrategyDelete {
+		switch {
+		case c.Operations.Delete.Method != "":
+			return fmt.Errorf("operations.delete.method cannot be used with deleteStrategy delete")
+		case c.Operations.Delete.Path != "" && c.Delete.URL != "":
+			return fmt.Errorf("operations.delete.path cannot be used with delete.url")
+		}
+	}
+
+	_, err := parseOperationRoutes(c.Operations, c.templateFuncs())
+	return err
+}
@@ -0,0 +1,86 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// originalRecordFields are the fields of a record kafka.originalRecordFields
+// can select, named like in the OpenCDC JSON of the record
+var originalRecordFields = []string{"position", "operation", "metadata", "key", "payload"}
+
+// originalRecordKey is the context key of the record embedded in the response
+// messages of its requests
+type originalRecordKey struct{}
+
+// withOriginalRecord returns a context embedding the record in the response
+// messages published to Kafka
+func withOriginalRecord(ctx context.Context, record json.RawMessage) context.Context {
+	return context.WithValue(ctx, originalRecordKey{}, record)
+}
+
+// originalRecordFrom returns the record embedded with ctx, nil if none
+func originalRecordFrom(ctx context.Context) json.RawMessage {
+	record, _ := ctx.Value(originalRecordKey{}).(json.RawMessage)
+	return record
+}
+
+// recordRef correlates the response messages of a record's request with the
+// record
+type recordRef struct {
+	position  string // Base64-encoded unless UTF-8
+	key       any    // Object of structured keys, string of raw keys
+	operation string
+}
+
+// recordRefKey is the context key of the record a request is sent for
+type recordRefKey struct{}
+
+// withRecordRef returns a context whose response messages carry the position,
+// key and operation of the record
+func withRecordRef(ctx context.Context, record opencdc.Record) context.Context {
+	ref := recordRef{operation: record.Operation.String()}
+	ref.position, _ = http.EncodeBody(record.Position)
+	switch key := record.Key.(type) {
+	case nil:
+	case opencdc.StructuredData:
+		ref.key = jsonValue(map[string]any(key))
+	default:
+		if raw := key.Bytes(); len(raw) > 0 {
+			ref.key, _ = http.EncodeBody(raw)
+		}
+	}
+	return context.WithValue(ctx, recordRefKey{}, ref)
+}
+
+// recordRefFrom returns the record of the request of ctx, zero for batches
+func recordRefFrom(ctx context.Context) recordRef {
+	ref, _ := ctx.Value(recordRefKey{}).(recordRef)
+	return ref
+}
+
+// originalRecord returns the fields of the record selected by
+// kafka.originalRecordFields as JSON object
+func (d *Destination) originalRecord(record opencdc.Record) (json.RawMessage, error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal original record: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("failed to marshal original record: %w", err)
+	}
+
+	selected := make(map[string]json.RawMessage, len(d.config.Kafka.OriginalRecordFields))
+	for _, field := range d.config.Kafka.OriginalRecordFields {
+		if v, ok := fields[field]; ok {
+			selected[field] = v
+		}
+	}
+	return json.Marshal(selected)
+}
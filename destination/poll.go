@@ -0,0 +1,173 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	connerrors "github.com/dev-in-black/connector-http/internal/errors"
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// errOperationFailed marks records whose accepted operation failed
+var errOperationFailed = errors.New("accepted operation failed")
+
+// poller polls the status URL of operations accepted with 202 until they
+// complete
+type poller struct {
+	config  PollConfig
+	urlPath *jsonpath.Path // nil without urlPath
+	state   *jsonpath.Path // nil without statusPath
+}
+
+// newPoller compiles the paths of the poll config, returning nil if polling
+// is disabled
+func newPoller(cfg PollConfig) (*poller, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	p := &poller{config: cfg}
+	var err error
+	if cfg.URLPath != "" {
+		if p.urlPath, err = jsonpath.Parse(cfg.URLPath); err != nil {
+			return nil, fmt.Errorf("invalid urlPath: %w", err)
+		}
+	}
+	if cfg.StatusPath != "" {
+		if p.state, err = jsonpath.Parse(cfg.StatusPath); err != nil {
+			return nil, fmt.Errorf("invalid statusPath: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// statusURL returns the status URL of an accepted request, resolved against
+// the request URL, empty if the response has none
+func (p *poller) statusURL(targetURL string, header stdhttp.Header, body []byte) (string, error) {
+	location := ""
+	if p.config.URLHeader != "" {
+		location = header.Get(p.config.URLHeader)
+	}
+	if location == "" && p.urlPath != nil {
+		if v, ok := p.urlPath.Lookup(body); ok && v != nil {
+			location = jsonpath.Stringify(v)
+		}
+	}
+	if location == "" {
+		return "", nil
+	}
+
+	base, err := url.Parse(targetURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid status URL %q: %w", location, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// needsBody reports whether accepted responses are read for the status URL
+func (p *poller) needsBody() bool {
+	return p != nil && p.urlPath != nil
+}
+
+// done reports whether a status response completes the operation, and the
+// error of a failed one
+func (p *poller) done(resp *stdhttp.Response, body []byte) (bool, error) {
+	switch {
+	case resp.StatusCode == stdhttp.StatusAccepted,
+		resp.StatusCode == stdhttp.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		// Pending, or the status is temporarily unavailable
+		return false, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return true, fmt.Errorf("%w: status check returned %d", errOperationFailed, resp.StatusCode)
+	case p.state == nil:
+		return true, nil
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return false, nil
+	}
+	v, ok := p.state.Get(doc)
+	if !ok || v == nil {
+		return false, nil
+	}
+	state := jsonpath.Stringify(v)
+	switch {
+	case slices.ContainsFunc(p.config.SuccessValues, func(s string) bool { return strings.EqualFold(s, state) }):
+		return true, nil
+	case slices.ContainsFunc(p.config.FailureValues, func(s string) bool { return strings.EqualFold(s, state) }):
+		return true, fmt.Errorf("%w: state %q", errOperationFailed, state)
+	}
+	return false, nil
+}
+
+// awaitOperation polls the status URL of an accepted request until the
+// operation completes, poll.timeout elapses or ctx is done. Failed operations
+// fail the record, responses without status URL are acked.
+func (d *Destination) awaitOperation(ctx context.Context, targetURL string, header stdhttp.Header, body []byte) error {
+	logger := sdk.Logger(ctx)
+	statusURL, err := d.poller.statusURL(targetURL, header, body)
+	if err != nil {
+		return connerrors.WithCategory(fmt.Errorf("failed to poll accepted operation: %w", err), connerrors.ErrValidation)
+	}
+	if statusURL == "" {
+		logger.Warn().Msg("Accepted response has no status URL, acking record without polling")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.Poll.Timeout)
+	defer cancel()
+	for polls := 1; ; polls++ {
+		wait := d.config.Poll.Interval
+		resp, err := d.httpClient.Do(ctx, stdhttp.MethodGet, statusURL, nil)
+		if err == nil {
+			var respBody []byte
+			respBody, err = readBody(resp)
+			if err == nil {
+				done, opErr := d.poller.done(resp, respBody)
+				logger.Debug().
+					Str("statusUrl", statusURL).
+					Int("status", resp.StatusCode).
+					Int("polls", polls).
+					Bool("done", done).
+					Msg("Polled accepted operation")
+				if opErr != nil {
+					return connerrors.WithCategory(opErr, connerrors.ErrNonRetryableStatus)
+				}
+				if done {
+					return nil
+				}
+			}
+			if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds >= 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			// Transient failures are polled again until the timeout
+			logger.Debug().Err(err).Str("statusUrl", statusURL).Msg("Failed to poll accepted operation")
+		}
+
+		if !sleepContext(ctx, wait) {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err := fmt.Errorf("accepted operation didn't complete within poll.timeout of %s after %d polls", d.config.Poll.Timeout, polls)
+				return connerrors.WithCategory(err, connerrors.ErrTimeout)
+			}
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package destination
+
+import (
+	"context"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// logPoolStats logs the connections of the pool to each host
+func logPoolStats(ctx context.Context, client *http.Client) {
+	for _, stats := range client.PoolStats() {
+		sdk.Logger(ctx).Info().
+			Str("host", stats.Host).
+			Int64("open", stats.Open).
+			Int64("inUse", stats.InUse).
+			Int64("idle", stats.Idle).
+			Int64("waits", stats.Waits).
+			Dur("waitTime", stats.WaitTime).
+			Msg("Connection pool stats")
+	}
+}
+
+// logPoolStatsPeriodically logs the connections of the pool every interval
+// until ctx is done
+func logPoolStatsPeriodically(ctx context.Context, client *http.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logPoolStats(ctx, client)
+		}
+	}
+}
+
+// poolMetrics returns the connections of the pool keyed by host, as served
+// by the metrics endpoint
+func poolMetrics(stats []http.PoolStats) map[string]any {
+	hosts := make(map[string]any, len(stats))
+	for _, s := range stats {
+		hosts[s.Host] = map[string]int64{
+			"open":         s.Open,
+			"in_use":       s.InUse,
+			"idle":         s.Idle,
+			"waits":        s.Waits,
+			"wait_time_ms": s.WaitTime.Milliseconds(),
+		}
+	}
+	return hosts
+}
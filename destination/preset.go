@@ -0,0 +1,75 @@
+package destination
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// endpointPreset holds the settings of a common intake endpoint
+type endpointPreset struct {
+	path         string // URL path, used if the url has none
+	apiKeyHeader string
+	apiKeyScheme string
+	batchFormat  string
+	success      BodyPredicate // Success predicate, empty for none
+}
+
+// endpointPresets are the supported presets by name
+var endpointPresets = map[string]endpointPreset{
+	"splunk-hec": {
+		path:         "/services/collector/event",
+		apiKeyHeader: "Authorization",
+		apiKeyScheme: "Splunk",
+		batchFormat:  "splunk-hec",
+		success:      BodyPredicate{Path: "$.code", Value: "0"},
+	},
+	"datadog-logs": {
+		path:         "/api/v2/logs",
+		apiKeyHeader: "DD-API-KEY",
+		batchFormat:  "json-array",
+	},
+}
+
+// applyPreset fills in the settings of the preset that are left at their
+// defaults, parameters set explicitly take precedence
+func (c *Config) applyPreset() error {
+	if c.Preset == "" {
+		return nil
+	}
+	preset, ok := endpointPresets[c.Preset]
+	if !ok {
+		names := make([]string, 0, len(endpointPresets))
+		for name := range endpointPresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("invalid preset: %s (must be one of %s)", c.Preset, strings.Join(names, ", "))
+	}
+
+	if u, err := url.Parse(c.URL); err == nil && u.Host != "" && (u.Path == "" || u.Path == "/") {
+		u.Path = preset.path
+		c.URL = u.String()
+	}
+
+	if slices.Equal(c.Auth.Type, []string{"none"}) {
+		c.Auth.Type = []string{"apikey"}
+	}
+	if c.Auth.APIKey.Header == "X-API-Key" {
+		c.Auth.APIKey.Header = preset.apiKeyHeader
+	}
+	if c.Auth.APIKey.Scheme == "" {
+		c.Auth.APIKey.Scheme = preset.apiKeyScheme
+	}
+
+	if c.BatchBody.Format == "none" {
+		c.BatchBody.Format = preset.batchFormat
+	}
+	if c.SuccessBodyPredicate.Path == "" && c.SuccessBodyPredicate.Regex == "" {
+		c.SuccessBodyPredicate.Path = preset.success.Path
+		c.SuccessBodyPredicate.Value = preset.success.Value
+	}
+	return nil
+}
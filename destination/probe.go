@@ -0,0 +1,81 @@
+package destination
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	stdhttp "net/http"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/kafka"
+)
+
+// probe verifies at Open that credentials can be obtained, the endpoint is
+// reachable and accepts them, and the Kafka topic exists, so misconfigurations
+// fail fast instead of on the first record
+func (d *Destination) probe(ctx context.Context) error {
+	// Prefetched credentials were already obtained
+	if !d.config.Auth.Prefetch {
+		if err := d.authManager.Refresh(ctx); err != nil {
+			return fmt.Errorf("failed to obtain credentials: %w", err)
+		}
+	}
+
+	target := d.config.ValidateProbeURL
+	if target == "" {
+		target = d.config.URL
+	}
+	method := d.config.ValidateProbeMethod
+
+	resp, err := d.httpClient.Probe(ctx, method, target)
+	if err != nil {
+		if hint := probeHint(err); hint != "" {
+			return fmt.Errorf("probe %s %s failed (%s): %w", method, target, hint, err)
+		}
+		return fmt.Errorf("probe %s %s failed: %w", method, target, err)
+	}
+	closeResponse(resp)
+
+	// Any other response shows the endpoint is reachable, e.g. 405 for HEAD
+	switch resp.StatusCode {
+	case stdhttp.StatusUnauthorized, stdhttp.StatusForbidden, stdhttp.StatusProxyAuthRequired:
+		return fmt.Errorf("probe %s %s: credentials rejected with status %d, check auth.type and credentials", method, target, resp.StatusCode)
+	}
+
+	// The NATS and AMQP producers verified their subject and exchange at Open
+	for _, p := range d.publishers {
+		if producer, ok := p.publisher.(*kafka.Producer); ok {
+			if err := producer.CheckTopic(ctx); err != nil {
+				return fmt.Errorf("failed to verify %s topic: %w", p.name, err)
+			}
+		}
+	}
+
+	sdk.Logger(ctx).Info().
+		Str("url", target).
+		Int("status", resp.StatusCode).
+		Msg("Connectivity check succeeded")
+	return nil
+}
+
+// probeHint names the likely cause of a failed probe request
+func probeHint(err error) string {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return "DNS lookup of " + dnsErr.Name + " failed"
+	case errors.As(err, &certErr):
+		return "TLS certificate verification failed"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timed out"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connection refused or unreachable"
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/amqp"
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/kafka"
+	"github.com/dev-in-black/connector-http/internal/nats"
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+// publisher is a message broker or webhook responses are published to
+type publisher struct {
+	name      string // e.g. kafka, nats or kafka cluster audit
+	target    string // Topic, subject, exchange or URL
+	publisher response.Publisher
+	// selects reports whether the response of a record is published, nil
+	// selects all
+	selects func(statusCode int, metadata opencdc.Metadata) bool
+}
+
+// openPublishers connects to the message brokers and webhook responses are
+// published to, with the credentials resolved in credentials
+func (d *Destination) openPublishers(ctx context.Context, credentials Config) error {
+	logger := sdk.Logger(ctx)
+
+	if d.config.Kafka.Enabled {
+		var err error
+		d.kafkaProducer, err = kafka.NewProducer(ctx, kafka.Config{
+			Brokers:           d.config.Kafka.Brokers,
+			Topic:             d.config.Kafka.Topic,
+			ClientID:          d.config.Kafka.ClientID,
+			Compression:       d.config.Kafka.Compression,
+			EnableIdempotence: d.config.Kafka.EnableIdempotence,
+			SASLEnabled:       d.config.Kafka.SASL.Enabled,
+			SASLMechanism:     d.config.Kafka.SASL.Mechanism,
+			SASLUsername:      credentials.Kafka.SASL.Username,
+			SASLPassword:      credentials.Kafka.SASL.Password,
+			TLSEnabled:        d.config.Kafka.TLS.Enabled,
+			Partitioner:       d.config.Kafka.Partitioner,
+			PartitionHeader:   d.config.Kafka.PartitionMetadataKey,
+			KeyHeader:         d.config.Kafka.KeyMetadataKey,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create Kafka producer: %w", err)
+		}
+		d.publishers = append(d.publishers, &publisher{name: "kafka", target: d.config.Kafka.Topic, publisher: d.kafkaProducer})
+
+		logger.Info().
+			Str("topic", d.config.Kafka.Topic).
+			Strs("brokers", d.config.Kafka.Brokers).
+			Str("partitioner", d.config.Kafka.Partitioner).
+			Msg("Kafka producer initialized")
+
+		if err := d.openKafkaClusters(ctx, credentials); err != nil {
+			return err
+		}
+	}
+
+	if d.config.NATS.Enabled {
+		producer, err := nats.NewProducer(ctx, nats.Config{
+			URL:        d.config.NATS.URL,
+			Subject:    d.config.NATS.Subject,
+			ClientName: d.config.NATS.ClientName,
+			Username:   credentials.NATS.Username,
+			Password:   credentials.NATS.Password,
+			Token:      credentials.NATS.Token,
+			TLSEnabled: d.config.NATS.TLS.Enabled,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create NATS producer: %w", err)
+		}
+		d.publishers = append(d.publishers, &publisher{name: "nats", target: d.config.NATS.Subject, publisher: producer})
+
+		logger.Info().
+			Str("subject", d.config.NATS.Subject).
+			Msg("NATS producer initialized")
+	}
+
+	if d.config.AMQP.Enabled {
+		producer, err := amqp.NewProducer(ctx, amqp.Config{
+			URL:        credentials.AMQP.URL,
+			Exchange:   d.config.AMQP.Exchange,
+			RoutingKey: d.config.AMQP.RoutingKey,
+			ClientName: d.config.AMQP.ClientName,
+			Persistent: d.config.AMQP.Persistent,
+			Mandatory:  d.config.AMQP.Mandatory,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create AMQP producer: %w", err)
+		}
+		d.publishers = append(d.publishers, &publisher{name: "amqp", target: d.config.AMQP.Exchange, publisher: producer})
+
+		logger.Info().
+			Str("exchange", d.config.AMQP.Exchange).
+			Str("routingKey", d.config.AMQP.RoutingKey).
+			Msg("AMQP producer initialized")
+	}
+
+	if d.config.ResponseWebhook.URL != "" {
+		webhook, err := d.newWebhookPublisher(credentials)
+		if err != nil {
+			return fmt.Errorf("failed to create response webhook: %w", err)
+		}
+		selects := responseSelector{}
+		selects.statusCodes, err = http.ParseStatusRanges(d.config.ResponseWebhook.StatusCodes)
+		if err != nil {
+			return fmt.Errorf("invalid responseWebhook.statusCodes: %w", err)
+		}
+		d.publishers = append(d.publishers, &publisher{name: "webhook", target: d.config.ResponseWebhook.URL, publisher: webhook, selects: selects.selects})
+
+		logger.Info().
+			Str("url", d.config.ResponseWebhook.URL).
+			Msg("Response webhook initialized")
+	}
+	return nil
+}
+
+// responseSelector selects the responses published to a publisher by status
+// code and record metadata
+type responseSelector struct {
+	statusCodes    http.StatusRanges // Empty selects any status
+	metadataKey    string
+	metadataValues []string // Empty selects any value
+}
+
+// selects reports whether the response of a record is published
+func (s responseSelector) selects(statusCode int, metadata opencdc.Metadata) bool {
+	if len(s.statusCodes) > 0 && !s.statusCodes.Contains(statusCode) {
+		return false
+	}
+	if s.metadataKey == "" {
+		return true
+	}
+	value, ok := metadata[s.metadataKey]
+	return ok && (len(s.metadataValues) == 0 || slices.Contains(s.metadataValues, value))
+}
+
+// responseMessage is a response to publish
+type responseMessage struct {
+	statusCode     int
+	protocol       string
+	header         map[string][]string
+	body           []byte
+	url            string
+	method         string
+	requestID      string
+	attempts       int
+	latency        response.Latency
+	originalRecord []byte
+	record         recordRef
+}
+
+// publishResponse publishes a response to the publishers selecting it. A
+// failure to publish to any of them fails the record.
+func (d *Destination) publishResponse(ctx context.Context, resp responseMessage, metadata opencdc.Metadata) error {
+	msg := &response.Message{
+		StatusCode:      resp.statusCode,
+		Protocol:        resp.protocol,
+		ResponseHeaders: response.FlattenHeaders(resp.header),
+		RequestURL:      resp.url,
+		RequestMethod:   resp.method,
+		RequestID:       resp.requestID,
+		Timestamp:       time.Now(),
+		Attempts:        resp.attempts,
+		Latency:         resp.latency,
+		OriginalRecord:  resp.originalRecord,
+		RecordPosition:  resp.record.position,
+		RecordKey:       resp.record.key,
+		RecordOperation: resp.record.operation,
+	}
+	body := d.responseBody(resp.header, resp.body)
+	msg.Body, msg.BodyEncoding = body.value, body.encoding
+	msg.BodyTruncated, msg.BodySize, msg.BodySHA256 = body.truncated, body.size, body.sha256
+	// OpenCDC metadata become message headers
+	recordHeaders := map[string]string(metadata)
+
+	for _, p := range d.publishers {
+		if p.selects != nil && !p.selects(resp.statusCode, metadata) {
+			continue
+		}
+		if err := p.publisher.Publish(ctx, msg, recordHeaders); err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", p.name, err)
+		}
+		sdk.Logger(ctx).Debug().
+			Str("publisher", p.name).
+			Str("target", p.target).
+			Int("recordHeaders", len(recordHeaders)).
+			Msg("Response published")
+	}
+	return nil
+}
+
+// closePublishers closes the connections of the publishers
+func (d *Destination) closePublishers(ctx context.Context) {
+	for _, p := range d.publishers {
+		p.publisher.Close()
+		sdk.Logger(ctx).Info().Str("publisher", p.name).Msg("Producer closed")
+	}
+	d.publishers = nil
+	d.kafkaProducer = nil
+}
@@ -0,0 +1,113 @@
+package destination
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// Redaction strategies of payload fields
+const (
+	redactDrop = "drop"
+	redactMask = "mask"
+	redactHash = "hash"
+)
+
+// redactField is a payload field and how it is redacted
+type redactField struct {
+	path     *jsonpath.Path
+	strategy string
+}
+
+// fieldRedactor removes, masks or hashes fields of JSON request bodies, so
+// they never reach the endpoint
+type fieldRedactor struct {
+	fields  []redactField
+	mask    string
+	hashKey []byte // HMAC key of hashed values, plain SHA-256 if empty
+}
+
+// newFieldRedactor parses the fields to redact. Fields are "$.path=strategy",
+// or just "$.path" to mask the field.
+func newFieldRedactor(fields []string, mask, hashKey string) (*fieldRedactor, error) {
+	r := &fieldRedactor{mask: mask, hashKey: []byte(hashKey)}
+	for _, field := range fields {
+		expr, strategy := field, redactMask
+		if i := strings.LastIndex(field, "="); i >= 0 {
+			expr, strategy = field[:i], strings.TrimSpace(field[i+1:])
+		}
+		switch strategy {
+		case redactDrop, redactMask, redactHash:
+		default:
+			return nil, fmt.Errorf("invalid field %q: unknown strategy %q (must be drop, mask or hash)", field, strategy)
+		}
+
+		if strings.TrimSpace(expr) == "$" {
+			return nil, fmt.Errorf("invalid field %q: the whole body can't be redacted", field)
+		}
+		path, err := jsonpath.Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+		r.fields = append(r.fields, redactField{path: path, strategy: strategy})
+	}
+	return r, nil
+}
+
+// Redact redacts the fields of a JSON body. Bodies that aren't JSON fail, so
+// fields can't slip through unredacted.
+func (r *fieldRedactor) Redact(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to redact fields, body is not JSON: %w", err)
+	}
+
+	doc = r.apply(doc)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode redacted body: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// apply redacts the fields of a decoded JSON document, fields missing from
+// the document are ignored
+func (r *fieldRedactor) apply(doc any) any {
+	for _, field := range r.fields {
+		doc, _ = field.path.Update(doc, func(v any) (any, bool) {
+			switch field.strategy {
+			case redactDrop:
+				return nil, false
+			case redactHash:
+				return r.hash(v), true
+			default:
+				return r.mask, true
+			}
+		})
+	}
+	return doc
+}
+
+// hash returns the hex HMAC-SHA256, or SHA-256 without hash key, of a value.
+// Equal values hash equally, so hashed fields can still be joined on.
+func (r *fieldRedactor) hash(v any) string {
+	data := []byte(jsonpath.Stringify(v))
+	if len(r.hashKey) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package destination
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// requestID returns the ID of a request sending a record with the metadata,
+// the ID in the metadata or a generated one
+func (d *Destination) requestID(metadata opencdc.Metadata) string {
+	if key := d.config.RequestID.MetadataKey; key != "" {
+		if id := metadata[key]; id != "" {
+			return id
+		}
+	}
+	return newRequestID()
+}
+
+// newRequestID returns a random version 4 UUID
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,89 @@
+package destination
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stdhttp "net/http"
+	"unicode/utf8"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+// How response bodies beyond responseBodyMaxBytes are stored
+const (
+	responseBodyOverflowTruncate = "truncate" // Cut at responseBodyMaxBytes
+	responseBodyOverflowHash     = "hash"     // Replaced with their SHA-256
+)
+
+// sinkBody is a response body as it is published and written to the response
+// sink
+type sinkBody struct {
+	value     any    // String, or the value of response.Decode
+	encoding  string // base64 for binary bodies
+	truncated bool
+	size      int    // Size of bodies beyond responseBodyMaxBytes
+	sha256    string // Hex SHA-256 of bodies replaced by their hash
+}
+
+// responseBody returns a response body as it is published and written to the
+// response sink: cut or hashed beyond responseBodyMaxBytes, otherwise decoded
+// by its Content-Type with response.decode, binary bodies base64-encoded
+func (d *Destination) responseBody(header map[string][]string, body []byte) sinkBody {
+	var b sinkBody
+	if limit := d.config.ResponseBodyMaxBytes; limit > 0 && len(body) > limit {
+		b.size = len(body)
+		if d.config.ResponseBodyOverflow == responseBodyOverflowHash {
+			sum := sha256.Sum256(body)
+			b.value, b.sha256 = "", hex.EncodeToString(sum[:])
+			return b
+		}
+		// Cut bodies don't parse, they are kept as text
+		body, b.truncated = truncateUTF8(body, limit), true
+	} else if d.config.Response.Decode {
+		if v, ok := response.Decode(stdhttp.Header(header).Get("Content-Type"), body); ok {
+			b.value = v
+			return b
+		}
+	}
+	b.value, b.encoding = http.EncodeBody(body)
+	return b
+}
+
+// responseBodyText returns a response body as responseBody does, as text.
+// Hashed bodies are sha256:<hex>.
+func (d *Destination) responseBodyText(header map[string][]string, body []byte) string {
+	b := d.responseBody(header, body)
+	if b.sha256 != "" {
+		return "sha256:" + b.sha256
+	}
+	switch v := b.value.(type) {
+	case string:
+		return v
+	case json.RawMessage:
+		return string(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return string(body)
+		}
+		return string(data)
+	}
+}
+
+// truncateUTF8 cuts data at n bytes, before a UTF-8 character it would split
+func truncateUTF8(data []byte, n int) []byte {
+	if len(data) > n {
+		data = data[:n]
+	}
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				data = data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return data
+}
@@ -0,0 +1,169 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/dev-in-black/connector-http/internal/secrets"
+)
+
+// credentialFields returns the credential fields that may reference secrets
+func (c *Config) credentialFields() map[string]*string {
+	return map[string]*string{
+		"auth.basic.username":      &c.Auth.Basic.Username,
+		"auth.basic.password":      &c.Auth.Basic.Password,
+		"auth.bearer.token":        &c.Auth.Bearer.Token,
+		"auth.oauth2.clientId":     &c.Auth.OAuth2.ClientID,
+		"auth.oauth2.clientSecret": &c.Auth.OAuth2.ClientSecret,
+		"auth.apiKey.key":          &c.Auth.APIKey.Key,
+		"auth.azure.accountKey":    &c.Auth.Azure.AccountKey,
+		"auth.digest.username":     &c.Auth.Digest.Username,
+		"auth.digest.password":     &c.Auth.Digest.Password,
+		"auth.ntlm.username":       &c.Auth.NTLM.Username,
+		"auth.ntlm.password":       &c.Auth.NTLM.Password,
+		"auth.session.username":    &c.Auth.Session.Username,
+		"auth.session.password":    &c.Auth.Session.Password,
+		"kafka.sasl.username":      &c.Kafka.SASL.Username,
+		"kafka.sasl.password":      &c.Kafka.SASL.Password,
+		"nats.username":            &c.NATS.Username,
+		"nats.password":            &c.NATS.Password,
+		"nats.token":               &c.NATS.Token,
+		"amqp.url":                 &c.AMQP.URL,
+		"responseDatabase.dsn":     &c.ResponseDatabase.DSN,
+		"redactHashKey":            &c.RedactHashKey,
+		"signing.secret":           &c.Signing.Secret,
+	}
+}
+
+// credentialFields returns the credential fields of the profile that may reference secrets
+func (p *AuthProfile) credentialFields() map[string]*string {
+	return map[string]*string{
+		"username":           &p.Username,
+		"password":           &p.Password,
+		"token":              &p.Token,
+		"apiKey":             &p.APIKey,
+		"oauth2ClientId":     &p.OAuth2ClientID,
+		"oauth2ClientSecret": &p.OAuth2ClientSecret,
+	}
+}
+
+// credentialFields returns the credential fields of the Kafka cluster that may reference secrets
+func (k *KafkaClusterConfig) credentialFields() map[string]*string {
+	return map[string]*string{
+		"sasl.username": &k.SASL.Username,
+		"sasl.password": &k.SASL.Password,
+	}
+}
+
+// CredentialParams returns the parameters holding credentials, including
+// deprecated ones, e.g. to redact them. Parameters of map entries have * in
+// place of the entry name.
+func CredentialParams() []string {
+	var c Config
+	var names []string
+	for name := range c.credentialFields() {
+		names = append(names, name)
+	}
+	for _, alias := range c.LegacyConfig.aliases() {
+		if _, ok := c.credentialFields()[alias.to]; ok {
+			names = append(names, alias.from)
+		}
+	}
+	var profile AuthProfile
+	for name := range profile.credentialFields() {
+		names = append(names, "auth.profiles.*."+name)
+	}
+	var cluster KafkaClusterConfig
+	for name := range cluster.credentialFields() {
+		names = append(names, "kafka.clusters.*."+name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasSecretReferences reports whether any credential references a secret
+func (c *Config) hasSecretReferences() bool {
+	for _, field := range c.credentialFields() {
+		if secrets.IsReference(*field) {
+			return true
+		}
+	}
+	for _, profile := range c.Auth.Profiles {
+		for _, field := range profile.credentialFields() {
+			if secrets.IsReference(*field) {
+				return true
+			}
+		}
+	}
+	for _, cluster := range c.Kafka.Clusters {
+		for _, field := range cluster.credentialFields() {
+			if secrets.IsReference(*field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withSecrets returns a copy of the config with all secret references
+// replaced by the values they reference
+func (c *Config) withSecrets(ctx context.Context, r *secrets.Resolver) (Config, error) {
+	resolved := *c
+	for name, field := range resolved.credentialFields() {
+		value, err := r.Resolve(ctx, *field)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		*field = value
+	}
+
+	if len(c.Auth.Profiles) > 0 {
+		resolved.Auth.Profiles = make(map[string]AuthProfile, len(c.Auth.Profiles))
+		for profileName, profile := range c.Auth.Profiles {
+			for name, field := range profile.credentialFields() {
+				value, err := r.Resolve(ctx, *field)
+				if err != nil {
+					return Config{}, fmt.Errorf("failed to resolve auth.profiles.%s.%s: %w", profileName, name, err)
+				}
+				*field = value
+			}
+			resolved.Auth.Profiles[profileName] = profile
+		}
+	}
+
+	if len(c.Kafka.Clusters) > 0 {
+		resolved.Kafka.Clusters = make(map[string]KafkaClusterConfig, len(c.Kafka.Clusters))
+		for clusterName, cluster := range c.Kafka.Clusters {
+			for name, field := range cluster.credentialFields() {
+				value, err := r.Resolve(ctx, *field)
+				if err != nil {
+					return Config{}, fmt.Errorf("failed to resolve kafka.clusters.%s.%s: %w", clusterName, name, err)
+				}
+				*field = value
+			}
+			resolved.Kafka.Clusters[clusterName] = cluster
+		}
+	}
+	return resolved, nil
+}
+
+// secretValues returns the credentials of the config keyed by field, used to
+// detect rotated secrets
+func (c *Config) secretValues() map[string]string {
+	values := make(map[string]string)
+	for name, field := range c.credentialFields() {
+		values[name] = *field
+	}
+	for profileName, profile := range c.Auth.Profiles {
+		for name, field := range profile.credentialFields() {
+			values["auth.profiles."+profileName+"."+name] = *field
+		}
+	}
+	for clusterName, cluster := range c.Kafka.Clusters {
+		for name, field := range cluster.credentialFields() {
+			values["kafka.clusters."+clusterName+"."+name] = *field
+		}
+	}
+	return values
+}
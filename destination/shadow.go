@@ -0,0 +1,110 @@
+package destination
+
+import (
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// shadowSender sends a sample of the requests to a shadow endpoint in the
+// background. Shadow responses never affect records.
+type shadowSender struct {
+	client       *http.Client
+	primary      string // Requests to URLs starting with it are shadowed
+	url          string
+	sampleRate   float64
+	logResponses bool
+
+	slots   chan struct{} // Bounds the shadow requests in flight
+	dropped atomic.Int64  // Sampled requests not shadowed as all slots were taken
+	wg      sync.WaitGroup
+
+	// Shadow requests in flight are canceled on close
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newShadowSender returns the shadow sender of the client, primary is the url
+// of the destination
+func newShadowSender(cfg ShadowConfig, primary string, client *http.Client) *shadowSender {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &shadowSender{
+		client:       client,
+		primary:      primary,
+		url:          cfg.URL,
+		sampleRate:   cfg.SampleRate,
+		logResponses: cfg.LogResponses,
+		slots:        make(chan struct{}, cfg.MaxInFlight),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Send shadows a sample of the requests to targetURL, without waiting for
+// the shadow request
+func (s *shadowSender) Send(ctx context.Context, targetURL string, body []byte) {
+	rest, ok := strings.CutPrefix(targetURL, s.primary)
+	if !ok || rand.Float64() >= s.sampleRate {
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.dropped.Add(1)
+		return
+	}
+
+	// The record may be reused once acked, the shadow request keeps a copy.
+	// It keeps the headers of the record but not its deadline.
+	body = bytes.Clone(body)
+	logger := sdk.Logger(ctx)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(s.ctx, cancel)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.slots }()
+		defer cancel()
+		defer stop()
+
+		start := time.Now()
+		resp, err := s.client.Post(ctx, s.url+rest, body)
+		if err != nil {
+			if s.logResponses && s.ctx.Err() == nil {
+				logger.Warn().Err(err).Str("sink", "shadow").Str("requestUrl", s.url+rest).Msg("Shadow request failed")
+			}
+			return
+		}
+		if !s.logResponses {
+			discardBody(resp)
+			return
+		}
+		respBody, err := readBody(resp)
+		event := logger.Info().
+			Str("sink", "shadow").
+			Int("statusCode", resp.StatusCode).
+			Str("requestUrl", s.url+rest).
+			Dur("latency", time.Since(start))
+		if err != nil {
+			event = event.AnErr("bodyError", err)
+		}
+		event.Str("body", string(respBody)).Msg("Shadow response")
+	}()
+}
+
+// Close cancels the shadow requests in flight and waits for them
+func (s *shadowSender) Close(ctx context.Context) {
+	s.cancel()
+	s.wg.Wait()
+	if dropped := s.dropped.Load(); dropped > 0 {
+		sdk.Logger(ctx).Info().Int64("dropped", dropped).Msg("Shadow requests dropped as shadow.maxInFlight were in flight")
+	}
+}
@@ -0,0 +1,90 @@
+package destination
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// writeConcurrently sends the records of a batch with up to concurrency
+// workers. With preserveOrderBy key, records are assigned to workers by the
+// hash of their key so records with the same key are sent in order. Once a
+// record fails no further records are started; the records written are
+// counted up to the first record that wasn't written.
+func (d *Destination) writeConcurrently(ctx context.Context, records []opencdc.Record) (int, error) {
+	workers := min(d.config.Concurrency, len(records))
+	shards := make([][]int, workers)
+	written := make([]bool, len(records))
+	errs := make([]error, len(records))
+
+	for i, record := range records {
+		if d.delivered(ctx, record) {
+			written[i] = true
+			continue
+		}
+		shard := i % workers
+		if d.config.PreserveOrderBy == "key" {
+			shard = keyShard(record, workers)
+		}
+		shards[shard] = append(shards[shard], i)
+	}
+
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for _, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, i := range shard {
+				if failed.Load() {
+					return
+				}
+				if err := d.deliver(ctx, records[i]); err != nil {
+					errs[i] = err
+					failed.Store(true)
+					return
+				}
+				written[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Records written after the first failure are remembered so they aren't
+	// sent again when the batch is redelivered
+	if d.deliveryLog != nil {
+		for i, record := range records {
+			if written[i] && record.Position != nil {
+				d.deliveryLog.Add(record.Position)
+			}
+		}
+	}
+
+	for i := range records {
+		if written[i] {
+			continue
+		}
+		// The first record not written may have been skipped after another failed
+		for _, err := range errs[i:] {
+			if err != nil {
+				return i, d.recordError(err)
+			}
+		}
+	}
+	return len(records), nil
+}
+
+// keyShard returns the worker of the record's key, records without key share a worker
+func keyShard(record opencdc.Record, workers int) int {
+	h := fnv.New32a()
+	if record.Key != nil {
+		h.Write(record.Key.Bytes())
+	}
+	return int(h.Sum32() % uint32(workers))
+}
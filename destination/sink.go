@@ -0,0 +1,229 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	stdhttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/response"
+	"github.com/dev-in-black/connector-http/internal/sqlsink"
+)
+
+// Response sinks besides the message brokers
+const (
+	responseSinkLog      = "log"      // Structured log lines
+	responseSinkDatabase = "database" // Rows of a SQL table
+)
+
+// capturedHeaderPrefix is the prefix of the metadata keys of captured
+// response headers without a name of their own
+const capturedHeaderPrefix = "http.response."
+
+// headerCapture copies a response header to a metadata key
+type headerCapture struct {
+	header string
+	key    string
+}
+
+// parseCaptureHeaders parses captureHeaders entries, Header=key or a header
+// copied to http.response.<header> in lower case
+func parseCaptureHeaders(entries []string) ([]headerCapture, error) {
+	captures := make([]headerCapture, 0, len(entries))
+	for _, entry := range entries {
+		header, key, named := strings.Cut(entry, "=")
+		header, key = strings.TrimSpace(header), strings.TrimSpace(key)
+		if !named {
+			key = capturedHeaderPrefix + strings.ToLower(header)
+		}
+		if header == "" || key == "" {
+			return nil, fmt.Errorf("invalid captureHeaders entry %q, expected Header or Header=key", entry)
+		}
+		captures = append(captures, headerCapture{header: stdhttp.CanonicalHeaderKey(header), key: key})
+	}
+	return captures, nil
+}
+
+// captureResponseHeaders returns the metadata with the captured headers of
+// the response added, headers missing from the response are skipped
+func captureResponseHeaders(captures []headerCapture, metadata opencdc.Metadata, header stdhttp.Header) opencdc.Metadata {
+	captured := make(opencdc.Metadata, len(metadata)+len(captures))
+	maps.Copy(captured, metadata)
+	for _, c := range captures {
+		if value := header.Get(c.header); value != "" {
+			captured[c.key] = value
+		}
+	}
+	return captured
+}
+
+// logResponse writes a successful response as a log line, the same fields as
+// the Kafka response message
+func (d *Destination) logResponse(ctx context.Context, resp *stdhttp.Response, body []byte, method, targetURL string, metadata opencdc.Metadata, attempts int, latency RequestLatency) {
+	headers := make(map[string]string, len(resp.Header))
+	for name, values := range resp.Header {
+		if len(values) > 0 {
+			headers[name] = values[0]
+		}
+	}
+
+	responseBody := d.responseBody(resp.Header, body)
+	event := sdk.Logger(ctx).Info().
+		Str("sink", "response").
+		Int("statusCode", resp.StatusCode).
+		Str("protocol", resp.Proto).
+		Interface("responseHeaders", headers).
+		Interface("body", responseBody.value).
+		Str("requestUrl", targetURL).
+		Str("requestMethod", method).
+		Interface("recordHeaders", map[string]string(metadata)).
+		Int("attempts", attempts).
+		Interface("latency", latency)
+	if responseBody.encoding != "" {
+		event.Str("bodyEncoding", responseBody.encoding)
+	}
+	if responseBody.size > 0 {
+		event.Int("bodySize", responseBody.size)
+	}
+	if responseBody.truncated {
+		event.Bool("bodyTruncated", true)
+	}
+	if responseBody.sha256 != "" {
+		event.Str("bodySha256", responseBody.sha256)
+	}
+	event.Msg("HTTP response")
+}
+
+// logFailure writes the error of a failed record as a log line, the same
+// fields as the JSON record error
+func logFailure(ctx context.Context, recordErr *RecordError, metadata opencdc.Metadata) {
+	event := sdk.Logger(ctx).Error().
+		Str("sink", "response").
+		Str("error", recordErr.Message).
+		Str("requestUrl", recordErr.URL).
+		Interface("recordHeaders", map[string]string(metadata)).
+		Int("attempts", recordErr.Attempts)
+	if recordErr.Status != 0 {
+		event.Int("statusCode", recordErr.Status).Str("action", recordErr.Action).Str("body", recordErr.Body)
+		if recordErr.BodyEncoding != "" {
+			event.Str("bodyEncoding", recordErr.BodyEncoding)
+		}
+	}
+	if recordErr.Category != "" {
+		event.Str("category", recordErr.Category)
+	}
+	if recordErr.Outcomes != nil {
+		event.Interface("outcomes", recordErr.Outcomes)
+	}
+	if recordErr.Latency != nil {
+		event.Interface("latency", recordErr.Latency)
+	}
+	event.Msg("HTTP response failed")
+}
+
+// openResponseDatabase connects to the database of responseSink database
+// and creates its table, with the DSN resolved in credentials
+func (d *Destination) openResponseDatabase(ctx context.Context, credentials Config) error {
+	if d.config.ResponseSink != responseSinkDatabase {
+		return nil
+	}
+	cfg := d.config.ResponseDatabase
+	db, err := sqlsink.Open(ctx, sqlsink.Config{
+		Driver:        cfg.Driver,
+		DSN:           credentials.ResponseDatabase.DSN,
+		Table:         cfg.Table,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open response database: %w", err)
+	}
+	d.responseDB = db
+
+	sdk.Logger(ctx).Info().
+		Str("driver", cfg.Driver).
+		Str("table", cfg.Table).
+		Msg("Response database initialized")
+	return nil
+}
+
+// sinkResponse writes a successful response to the response sink
+func (d *Destination) sinkResponse(ctx context.Context, resp *stdhttp.Response, body []byte, method, targetURL, requestID string, metadata opencdc.Metadata, attempts int, latency RequestLatency) {
+	switch d.config.ResponseSink {
+	case responseSinkLog:
+		d.logResponse(ctx, resp, body, method, targetURL, metadata, attempts, latency)
+	case responseSinkDatabase:
+		d.addResponseRow(ctx, sqlsink.Row{
+			Time:       time.Now(),
+			Outcome:    sqlsink.OutcomeSuccess,
+			StatusCode: resp.StatusCode,
+			RequestID:  requestID,
+			Method:     method,
+			URL:        targetURL,
+			Attempts:   attempts,
+			LatencyMs:  latency.TotalMs,
+			Body:       d.responseBodyText(resp.Header, body),
+			Headers:    response.FlattenHeaders(resp.Header),
+			Metadata:   metadata,
+		})
+	}
+}
+
+// sinkFailure writes the error of a failed record to the response sink
+func (d *Destination) sinkFailure(ctx context.Context, recordErr *RecordError, method string, metadata opencdc.Metadata) {
+	switch d.config.ResponseSink {
+	case responseSinkLog:
+		logFailure(ctx, recordErr, metadata)
+	case responseSinkDatabase:
+		row := sqlsink.Row{
+			Time:       time.Now(),
+			Outcome:    sqlsink.OutcomeFailure,
+			StatusCode: recordErr.Status,
+			Action:     recordErr.Action,
+			Category:   recordErr.Category,
+			Error:      recordErr.Message,
+			RequestID:  recordErr.RequestID,
+			Method:     method,
+			URL:        recordErr.URL,
+			Attempts:   recordErr.Attempts,
+			Body:       recordErr.Body,
+			Metadata:   metadata,
+		}
+		if recordErr.Latency != nil {
+			row.LatencyMs = recordErr.Latency.TotalMs
+		}
+		d.addResponseRow(ctx, row)
+	}
+}
+
+// addResponseRow buffers a row of the response database. Rows that can't be
+// inserted don't fail their records, they are kept for the next flush.
+func (d *Destination) addResponseRow(ctx context.Context, row sqlsink.Row) {
+	if err := d.responseDB.Add(ctx, row); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to write responses to response database")
+	}
+}
+
+// flushResponseDatabase inserts the rows buffered for the response database
+func (d *Destination) flushResponseDatabase(ctx context.Context) {
+	if err := d.responseDB.Flush(ctx); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to write responses to response database")
+	}
+}
+
+// closeResponseDatabase inserts the buffered rows and closes the response
+// database
+func (d *Destination) closeResponseDatabase(ctx context.Context) {
+	if d.responseDB == nil {
+		return
+	}
+	if err := d.responseDB.Close(ctx); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("Failed to close response database")
+	}
+	d.responseDB = nil
+}
@@ -0,0 +1,35 @@
+package destination
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// detectSlowRequest logs a warning and counts the attempt started at start if
+// it took longer than slowRequestThreshold
+func (d *Destination) detectSlowRequest(ctx context.Context, method, targetURL string, attempt int, start time.Time) {
+	threshold := d.config.SlowRequestThreshold
+	if threshold <= 0 {
+		return
+	}
+	latency := time.Since(start)
+	if latency <= threshold {
+		return
+	}
+	d.metrics.slowRequests.Add(1)
+
+	// Credentials of the URL aren't logged
+	if parsed, err := url.Parse(targetURL); err == nil {
+		targetURL = parsed.Redacted()
+	}
+	sdk.Logger(ctx).Warn().
+		Str("method", method).
+		Str("url", targetURL).
+		Dur("latency", latency).
+		Dur("threshold", threshold).
+		Int("attempt", attempt).
+		Msg("Slow HTTP request")
+}
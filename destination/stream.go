@@ -0,0 +1,301 @@
+package destination
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdhttp "net/http"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	connerrors "github.com/dev-in-black/connector-http/internal/errors"
+	"github.com/dev-in-black/connector-http/internal/http"
+)
+
+// streamCloseTimeout bounds the wait for the response of a completed stream
+const streamCloseTimeout = 30 * time.Second
+
+// ndjsonStream is a long-lived chunked request, records are written to its
+// body as lines of NDJSON
+type ndjsonStream struct {
+	pw      *io.PipeWriter
+	w       *bufio.Writer
+	cancel  context.CancelFunc
+	result  chan streamResult // Receives the response once the request ends
+	ended   *streamResult     // Set once the result was received
+	opened  time.Time
+	flushed time.Time
+	lines   int
+}
+
+// streamResult is the outcome of a stream request
+type streamResult struct {
+	resp *stdhttp.Response
+	err  error
+}
+
+// openStream starts a stream request, it is sent while lines are written.
+// The request outlives ctx, it ends when the stream is closed.
+func openStream(ctx context.Context, client *http.Client, method, url string) *ndjsonStream {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	pr, pw := io.Pipe()
+	s := &ndjsonStream{
+		pw:      pw,
+		w:       bufio.NewWriterSize(pw, 64*1024),
+		cancel:  cancel,
+		result:  make(chan streamResult, 1),
+		opened:  time.Now(),
+		flushed: time.Now(),
+	}
+
+	go func() {
+		resp, err := client.Stream(ctx, method, url, pr)
+		// Fail writes once the endpoint ended the request, e.g. with an
+		// error status before the body was complete
+		if err != nil {
+			pr.CloseWithError(err)
+		} else {
+			pr.CloseWithError(fmt.Errorf("stream ended by the endpoint with status %d", resp.StatusCode))
+		}
+		s.result <- streamResult{resp: resp, err: err}
+	}()
+	return s
+}
+
+// writeLine writes a line to the stream, flushing the lines written so far
+// if they are older than flushInterval
+func (s *ndjsonStream) writeLine(line []byte, flushInterval time.Duration) error {
+	if _, err := s.w.Write(line); err != nil {
+		return err
+	}
+	if err := s.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	s.lines++
+	if time.Since(s.flushed) >= flushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush sends the buffered lines to the endpoint
+func (s *ndjsonStream) flush() error {
+	s.flushed = time.Now()
+	return s.w.Flush()
+}
+
+// check fails if the endpoint already ended the request, e.g. rejecting the
+// stream with an error status
+func (s *ndjsonStream) check() error {
+	if s.ended == nil {
+		select {
+		case result := <-s.result:
+			s.ended = &result
+		default:
+			return nil
+		}
+	}
+	if s.ended.err != nil {
+		return s.ended.err
+	}
+	return fmt.Errorf("stream ended by the endpoint with status %d", s.ended.resp.StatusCode)
+}
+
+// close ends the request body and waits for the response of the endpoint
+func (s *ndjsonStream) close() (*stdhttp.Response, error) {
+	defer s.cancel()
+	if err := s.flush(); err != nil {
+		s.pw.CloseWithError(err)
+	} else {
+		s.pw.Close()
+	}
+
+	if s.ended != nil {
+		return s.ended.resp, s.ended.err
+	}
+	select {
+	case result := <-s.result:
+		return result.resp, result.err
+	case <-time.After(streamCloseTimeout):
+		return nil, fmt.Errorf("no response within %s after the stream was closed", streamCloseTimeout)
+	}
+}
+
+// abort cancels the request, its response is discarded once it arrives
+func (s *ndjsonStream) abort() {
+	s.cancel()
+	s.pw.CloseWithError(context.Canceled)
+	if s.ended != nil {
+		if s.ended.resp != nil {
+			closeResponse(s.ended.resp)
+		}
+		return
+	}
+	go func() {
+		if result := <-s.result; result.resp != nil {
+			closeResponse(result.resp)
+		}
+	}()
+}
+
+// writeStream writes a batch of records to the open stream as NDJSON lines.
+// The batch is acked once it was flushed, if the stream fails it is
+// reconnected and the whole batch written again.
+func (d *Destination) writeStream(ctx context.Context, records []opencdc.Record) (int, error) {
+	var lines [][]byte
+	var rows []opencdc.Record
+	var keys []string
+	var prepareErr error
+	n := len(records)
+	for i, record := range records {
+		if d.delivered(ctx, record) {
+			continue
+		}
+		key, ok, err := d.admitRecord(ctx, record)
+		if err != nil {
+			n, prepareErr = i, err
+			break
+		}
+		if !ok {
+			continue
+		}
+		line, err := d.streamLine(ctx, record)
+		if err != nil {
+			n, prepareErr = i, err
+			break
+		}
+		lines = append(lines, line)
+		rows = append(rows, record)
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	// Records before one that can't be rendered are still delivered
+	if len(lines) > 0 {
+		if err := d.streamLines(ctx, lines); err != nil {
+			return 0, d.recordError(err)
+		}
+		for _, key := range keys {
+			d.dedup.Add(key)
+		}
+		if d.deliveryLog != nil {
+			for _, record := range rows {
+				if record.Position != nil {
+					d.deliveryLog.Add(record.Position)
+				}
+			}
+		}
+	}
+
+	if prepareErr != nil {
+		return n, d.recordError(prepareErr)
+	}
+	return n, nil
+}
+
+// streamLine renders the request body of a record as a single line of JSON
+func (d *Destination) streamLine(ctx context.Context, record opencdc.Record) ([]byte, error) {
+	body, err := d.recordBody(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	var line bytes.Buffer
+	if err := json.Compact(&line, body); err != nil {
+		return nil, connerrors.WithCategory(fmt.Errorf("stream mode requires JSON request bodies: %w", err), connerrors.ErrValidation)
+	}
+	return line.Bytes(), nil
+}
+
+// streamLines writes lines to the stream and flushes them, reconnecting up
+// to retry.max times after the backoff if the stream fails
+func (d *Destination) streamLines(ctx context.Context, lines [][]byte) error {
+	logger := sdk.Logger(ctx)
+
+	for attempt := 0; ; attempt++ {
+		err := d.writeLines(ctx, lines)
+		if err == nil {
+			return nil
+		}
+
+		// The lines written before the stream failed may have been
+		// processed, they are written again (at-least-once)
+		d.stream.abort()
+		d.stream = nil
+		if attempt >= d.config.Retry.Max {
+			logger.Error().Err(err).Int("attempts", attempt+1).Msg("Stream failed after retries")
+			return fmt.Errorf("failed to stream records: %w", err)
+		}
+		logger.Warn().Err(err).Dur("backoff", d.config.Stream.ReconnectBackoff).Msg("Stream failed, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.config.Stream.ReconnectBackoff):
+		}
+	}
+}
+
+// writeLines writes lines to the open stream, opening a new one if there is
+// none or the open one reached stream.maxDuration
+func (d *Destination) writeLines(ctx context.Context, lines [][]byte) error {
+	if d.stream != nil && d.config.Stream.MaxDuration > 0 && time.Since(d.stream.opened) >= d.config.Stream.MaxDuration {
+		d.closeStream(ctx)
+	}
+	if d.stream == nil {
+		d.stream = openStream(ctx, d.httpClient, d.config.Method, d.config.URL)
+		sdk.Logger(ctx).Debug().Str("url", d.config.URL).Msg("Stream opened")
+	}
+
+	if err := d.stream.check(); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := d.stream.writeLine(line, d.config.Stream.FlushInterval); err != nil {
+			return err
+		}
+	}
+	if err := d.stream.flush(); err != nil {
+		return err
+	}
+	return d.stream.check()
+}
+
+// closeStream completes the open stream and logs the response of the
+// endpoint. Its records were acked already, a failed response is logged.
+func (d *Destination) closeStream(ctx context.Context) {
+	if d.stream == nil {
+		return
+	}
+	stream := d.stream
+	d.stream = nil
+
+	logger := sdk.Logger(ctx)
+	resp, err := stream.close()
+	if err != nil {
+		logger.Error().Err(err).Int("lines", stream.lines).Msg("Stream failed to complete")
+		return
+	}
+	defer closeResponse(resp)
+
+	switch action := d.statusRules.Classify(resp.StatusCode); action {
+	case http.ActionAck, http.ActionIgnore:
+		logger.Debug().
+			Int("status", resp.StatusCode).
+			Int("lines", stream.lines).
+			Dur("duration", time.Since(stream.opened)).
+			Msg("Stream completed")
+	default:
+		logger.Error().
+			Int("status", resp.StatusCode).
+			Str("action", string(action)).
+			Int("lines", stream.lines).
+			Msg("Stream completed with unsuccessful status")
+	}
+}
@@ -0,0 +1,140 @@
+package destination_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/matryer/is"
+)
+
+// streamEndpoint records the lines of the streamed requests as they arrive
+type streamEndpoint struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	requests     int
+	contentTypes []string
+	lines        []string
+}
+
+func newStreamEndpoint(t *testing.T) *streamEndpoint {
+	e := &streamEndpoint{}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		e.requests++
+		e.contentTypes = append(e.contentTypes, r.Header.Get("Content-Type"))
+		e.mu.Unlock()
+
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			e.mu.Lock()
+			e.lines = append(e.lines, scanner.Text())
+			e.mu.Unlock()
+		}
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// waitForLines waits up to 5 seconds for the endpoint to receive n lines
+func (e *streamEndpoint) waitForLines(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		e.mu.Lock()
+		lines := append([]string(nil), e.lines...)
+		e.mu.Unlock()
+		if len(lines) >= n {
+			return lines
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.lines...)
+}
+
+func TestStreamFraming(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	endpoint := newStreamEndpoint(t)
+	dest := newDestination(t, map[string]string{
+		"url":            endpoint.URL,
+		"stream.enabled": "true",
+	})
+
+	// Bodies spanning several lines are compacted to a single line
+	pretty := testRecord("2")
+	pretty.Payload.After = opencdc.RawData("{\n  \"id\": \"2\"\n}\n")
+	n, err := dest.Write(ctx, []opencdc.Record{testRecord("1"), pretty})
+	is.NoErr(err)
+	is.Equal(n, 2)
+
+	// Batches are flushed before they are acked, the stream stays open
+	is.Equal(endpoint.waitForLines(t, 2), testBodies("1", "2"))
+	n, err = dest.Write(ctx, testRecords("3"))
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(endpoint.waitForLines(t, 3), testBodies("1", "2", "3"))
+
+	is.NoErr(dest.Teardown(ctx))
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	is.Equal(endpoint.requests, 1)
+	is.Equal(endpoint.contentTypes, []string{"application/x-ndjson"})
+}
+
+func TestStreamAcks(t *testing.T) {
+	invalid := testRecord("invalid")
+	invalid.Payload.After = opencdc.RawData("not json")
+
+	testCases := []struct {
+		name    string
+		records []opencdc.Record
+		wantN   int
+		wantErr bool
+		want    []string
+	}{{
+		name:    "all records",
+		records: testRecords("1", "2", "3"),
+		wantN:   3,
+		want:    testBodies("1", "2", "3"),
+	}, {
+		name:    "records before an invalid body",
+		records: []opencdc.Record{testRecord("1"), testRecord("2"), invalid, testRecord("3")},
+		wantN:   2,
+		wantErr: true,
+		want:    testBodies("1", "2"),
+	}, {
+		name:    "invalid first body",
+		records: []opencdc.Record{invalid, testRecord("1")},
+		wantN:   0,
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			ctx := context.Background()
+			endpoint := newStreamEndpoint(t)
+			dest := newDestination(t, map[string]string{
+				"url":            endpoint.URL,
+				"stream.enabled": "true",
+			})
+
+			// Only the records before the failed one are acked, and they
+			// were written to the stream
+			n, err := dest.Write(ctx, tc.records)
+			is.Equal(err != nil, tc.wantErr)
+			is.Equal(n, tc.wantN)
+			is.NoErr(dest.Teardown(ctx))
+			is.Equal(endpoint.waitForLines(t, len(tc.want)), tc.want)
+		})
+	}
+}
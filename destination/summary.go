@@ -0,0 +1,90 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+// batchSummary collects the outcomes of the requests of a batch for the
+// summary published to kafka.batchSummaryTopic
+type batchSummary struct {
+	start time.Time
+
+	mu        sync.Mutex
+	requests  int
+	statuses  map[string]int // By status, "error" for requests without response
+	latencyMs float64        // Sum of the latencies of the requests
+}
+
+// newBatchSummary returns the summary of a batch starting now
+func newBatchSummary() *batchSummary {
+	return &batchSummary{start: time.Now(), statuses: make(map[string]int)}
+}
+
+// batchSummaryKey is the context key of the summary of the batch a request is
+// sent for
+type batchSummaryKey struct{}
+
+// withBatchSummary returns a context whose requests are counted in summary
+func withBatchSummary(ctx context.Context, summary *batchSummary) context.Context {
+	return context.WithValue(ctx, batchSummaryKey{}, summary)
+}
+
+// batchSummaryFrom returns the summary of the batch of ctx, nil if none
+func batchSummaryFrom(ctx context.Context) *batchSummary {
+	summary, _ := ctx.Value(batchSummaryKey{}).(*batchSummary)
+	return summary
+}
+
+// add counts a request with its status, 0 for requests without response, and
+// its latency including retries
+func (s *batchSummary) add(status int, latency time.Duration) {
+	outcome := "error"
+	if status != 0 {
+		outcome = strconv.Itoa(status)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.statuses[outcome]++
+	s.latencyMs += millis(latency)
+}
+
+// publishBatchSummary publishes the summary of a batch of which the first
+// written records were acked. Summaries are for monitoring, a failure to
+// publish one doesn't fail the batch.
+func (d *Destination) publishBatchSummary(ctx context.Context, summary *batchSummary, records []opencdc.Record, written int) {
+	summary.mu.Lock()
+	msg := &response.BatchSummary{
+		Timestamp:        time.Now(),
+		Records:          len(records),
+		Succeeded:        written,
+		Failed:           len(records) - written,
+		Requests:         summary.requests,
+		Statuses:         summary.statuses,
+		DurationMs:       millis(time.Since(summary.start)),
+		RequestLatencyMs: summary.latencyMs,
+	}
+	summary.mu.Unlock()
+	if len(records) > 0 {
+		msg.FirstPosition, _ = http.EncodeBody(records[0].Position)
+		msg.LastPosition, _ = http.EncodeBody(records[len(records)-1].Position)
+	}
+
+	data, err := json.Marshal(msg)
+	if err == nil {
+		err = d.kafkaProducer.PublishTo(context.WithoutCancel(ctx), d.config.Kafka.BatchSummaryTopic, nil, data)
+	}
+	if err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Str("topic", d.config.Kafka.BatchSummaryTopic).Msg("Failed to publish batch summary")
+	}
+}
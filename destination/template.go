@@ -0,0 +1,205 @@
+package destination
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// recordTemplate is a text/template evaluated against a record
+type recordTemplate struct {
+	tmpl *template.Template
+}
+
+// templateData is the data a record template is executed with, e.g.
+// {{.Metadata.tenant}}, {{.Key}}, {{.Payload.id}} or {{.After.user.id}}
+type templateData struct {
+	Operation string
+	Metadata  map[string]string
+	Key       string
+	Payload   any // Decoded payload of the request body, nil if it is not valid JSON
+	Before    any // Decoded Payload.Before
+	After     any // Decoded Payload.After
+}
+
+// templateOptions configures how records are decoded into template data
+type templateOptions struct {
+	// usePayloadAfter decodes Payload from Payload.After, Payload.Before is
+	// the fallback
+	usePayloadAfter bool
+	// rawJSON decodes raw payloads as JSON, otherwise they are strings
+	rawJSON bool
+}
+
+// emptyMissingFunc is the function appended to the actions of record
+// templates, printing missing values as empty strings
+const emptyMissingFunc = "_emptyMissing"
+
+// parseRecordTemplate compiles a record template with the template functions
+func parseRecordTemplate(name, text string, funcs template.FuncMap) (*recordTemplate, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=zero").
+		Funcs(funcs).
+		Funcs(template.FuncMap{emptyMissingFunc: emptyMissing}).
+		Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			emptyMissingActions(t.Tree, t.Tree.Root)
+		}
+	}
+	return &recordTemplate{tmpl: tmpl}, nil
+}
+
+// emptyMissing returns the empty string for missing values, which templates
+// would print as "<no value>"
+func emptyMissing(v any) any {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// emptyMissingActions pipes the value of every action printing a value to
+// emptyMissing, missing map keys evaluate to nil interfaces with missingkey=zero
+func emptyMissingActions(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			emptyMissingActions(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		ident := parse.NewIdentifier(emptyMissingFunc).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{ident}})
+	case *parse.IfNode:
+		emptyMissingActions(tree, n.List)
+		emptyMissingActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		emptyMissingActions(tree, n.List)
+		emptyMissingActions(tree, n.ElseList)
+	case *parse.WithNode:
+		emptyMissingActions(tree, n.List)
+		emptyMissingActions(tree, n.ElseList)
+	}
+}
+
+// Render executes the template against the record
+func (t *recordTemplate) Render(data templateData) (string, error) {
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", t.tmpl.Name(), err)
+	}
+	return sb.String(), nil
+}
+
+// newTemplateData builds the template data of a record. The payload is
+// decoded from the same field used for the request body.
+func newTemplateData(record opencdc.Record, opts templateOptions) templateData {
+	data := templateData{
+		Operation: record.Operation.String(),
+		Metadata:  record.Metadata,
+		Before:    decodePayload(record.Payload.Before, opts.rawJSON),
+		After:     decodePayload(record.Payload.After, opts.rawJSON),
+	}
+	if record.Key != nil {
+		data.Key = string(record.Key.Bytes())
+	}
+
+	data.Payload = data.Before
+	if opts.usePayloadAfter && record.Payload.After != nil {
+		data.Payload = data.After
+	}
+	return data
+}
+
+// decodePayload returns a payload as the values JSON decodes to, so templates
+// and JSONPath treat structured and raw payloads alike. Raw payloads that
+// aren't valid JSON are nil, or strings without rawJSON.
+func decodePayload(payload opencdc.Data, rawJSON bool) any {
+	switch p := payload.(type) {
+	case nil:
+		return nil
+	case opencdc.StructuredData:
+		return jsonValue(map[string]any(p))
+	default:
+		if !rawJSON {
+			return string(p.Bytes())
+		}
+		var decoded any
+		if err := json.Unmarshal(p.Bytes(), &decoded); err != nil {
+			return nil
+		}
+		return decoded
+	}
+}
+
+// jsonValue converts the nested values of structured data to the types JSON
+// decodes to: objects to map[string]any and arrays to []any. Values of other
+// types, e.g. []string or nested opencdc.StructuredData, are converted through
+// their JSON encoding, scalars are kept.
+func jsonValue(v any) any {
+	switch val := v.(type) {
+	case nil, string, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return val
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = jsonValue(item)
+		}
+		return out
+	case opencdc.StructuredData:
+		return jsonValue(map[string]any(val))
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = jsonValue(item)
+		}
+		return out
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return val
+		}
+		var decoded any
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return val
+		}
+		return decoded
+	}
+}
+
+// isJSONContentType reports whether a Content-Type is JSON, e.g.
+// application/json or application/vnd.api+json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// parseRecordTemplates compiles a map of named record templates
+func parseRecordTemplates(templates map[string]string, funcs template.FuncMap) (map[string]*recordTemplate, error) {
+	parsed := make(map[string]*recordTemplate, len(templates))
+	for name, text := range templates {
+		tmpl, err := parseRecordTemplate(name, text, funcs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for %q: %w", name, err)
+		}
+		parsed[name] = tmpl
+	}
+	return parsed, nil
+}
@@ -0,0 +1,124 @@
+package destination
+
+import (
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/matryer/is"
+)
+
+func TestRecordTemplateRender(t *testing.T) {
+	record := opencdc.Record{
+		Operation: opencdc.OperationUpdate,
+		Metadata:  opencdc.Metadata{"tenant": "acme", "note": "<no value>"},
+		Key:       opencdc.RawData("42"),
+		Payload: opencdc.Change{
+			Before: opencdc.StructuredData{"id": 42, "name": "old"},
+			After: opencdc.StructuredData{
+				"id":    42,
+				"name":  "über_éclair naïve",
+				"tags":  []string{"a", "b"},
+				"user":  opencdc.StructuredData{"email": "jo@example.com"},
+				"empty": "",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		opts     templateOptions
+		want     string
+		wantErr  bool
+	}{{
+		name:     "metadata and key",
+		template: "/tenants/{{.Metadata.tenant}}/items/{{.Key}}",
+		want:     "/tenants/acme/items/42",
+	}, {
+		name:     "payload before without usePayloadAfter",
+		template: "{{.Payload.name}}",
+		want:     "old",
+	}, {
+		name:     "payload after with usePayloadAfter",
+		template: "{{.Payload.name}}",
+		opts:     templateOptions{usePayloadAfter: true},
+		want:     "über_éclair naïve",
+	}, {
+		name:     "nested structured data",
+		template: "{{.After.user.email}}",
+		want:     "jo@example.com",
+	}, {
+		name:     "operation",
+		template: "{{.Operation}}",
+		want:     "update",
+	}, {
+		name:     "missing keys render empty",
+		template: "[{{.Metadata.missing}}][{{.After.missing}}][{{.After.user.missing}}]",
+		want:     "[][][]",
+	}, {
+		name:     "missing keys in if, range and with",
+		template: "{{if .After.user}}{{.After.user.phone}}{{end}}{{range .After.tags}}{{.}}{{end}}{{with .After.user}}<{{.fax}}>{{end}}",
+		want:     "ab<>",
+	}, {
+		name:     "literal <no value> in data is kept",
+		template: "{{.Metadata.note}}",
+		want:     "<no value>",
+	}, {
+		name:     "variables",
+		template: "{{$id := .After.id}}{{$id}}",
+		want:     "42",
+	}, {
+		name:     "functions",
+		template: `{{.After.name | camelcase}} {{.After.empty | default "none"}} {{.After.tags | join ","}} {{.After.user | toJson}}`,
+		want:     `überÉclairNaïve none a,b {"email":"jo@example.com"}`,
+	}, {
+		name:     "missing value piped to default",
+		template: `{{.Metadata.missing | default "fallback"}}`,
+		want:     "fallback",
+	}, {
+		name:     "execution error",
+		template: "{{.After.name | b64dec}}",
+		wantErr:  true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			tmpl, err := parseRecordTemplate(tc.name, tc.template, templateFuncs(""))
+			is.NoErr(err)
+			got, err := tmpl.Render(newTemplateData(record, tc.opts))
+			if tc.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(got, tc.want)
+		})
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	testCases := []struct {
+		name    string
+		payload opencdc.Data
+		rawJSON bool
+		want    any
+	}{
+		{name: "nil", payload: nil, want: nil},
+		{name: "raw as string", payload: opencdc.RawData(`{"id":1}`), want: `{"id":1}`},
+		{name: "raw as JSON", payload: opencdc.RawData(`{"id":1}`), rawJSON: true, want: map[string]any{"id": float64(1)}},
+		{name: "raw invalid JSON", payload: opencdc.RawData(`id=1`), rawJSON: true, want: nil},
+		{
+			name:    "structured",
+			payload: opencdc.StructuredData{"ids": []int{1, 2}, "nested": opencdc.StructuredData{"ok": true}},
+			want:    map[string]any{"ids": []any{float64(1), float64(2)}, "nested": map[string]any{"ok": true}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(decodePayload(tc.payload, tc.rawJSON), tc.want)
+		})
+	}
+}
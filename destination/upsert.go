@@ -0,0 +1,83 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	stdhttp "net/http"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// Upsert modes
+const (
+	upsertNone        = "none"
+	upsertUpdateFirst = "update-first"
+	upsertCreateFirst = "create-first"
+)
+
+// upsertFallback is the request resent when the endpoint answers the request
+// of a record with status
+type upsertFallback struct {
+	status int
+	method string
+	url    string
+}
+
+// upsertKey is the context key of the fallback of a request
+type upsertKey struct{}
+
+// withUpsertFallback returns a context resending requests answered with the
+// status of fallback
+func withUpsertFallback(ctx context.Context, fallback *upsertFallback) context.Context {
+	return context.WithValue(ctx, upsertKey{}, fallback)
+}
+
+// upsertFallbackFrom returns the fallback of requests sent with ctx, nil if
+// there is none
+func upsertFallbackFrom(ctx context.Context) *upsertFallback {
+	fallback, _ := ctx.Value(upsertKey{}).(*upsertFallback)
+	return fallback
+}
+
+// upsertRoutes returns the routes of an upserted record, the route it is sent
+// with first and the one it is resent with if the endpoint answers status.
+// Deletes aren't upserted, ok is false for them and without upsert.
+func (d *Destination) upsertRoutes(operation opencdc.Operation) (first, fallback *operationRoute, status int, ok bool) {
+	if operation == opencdc.OperationDelete {
+		return nil, nil, 0, false
+	}
+	create, update := d.routes.get(opencdc.OperationCreate), d.routes.get(opencdc.OperationUpdate)
+	switch d.config.Upsert {
+	case upsertUpdateFirst:
+		return update, create, stdhttp.StatusNotFound, true
+	case upsertCreateFirst:
+		return create, update, stdhttp.StatusConflict, true
+	default:
+		return nil, nil, 0, false
+	}
+}
+
+// get returns the route of operation, an empty route sending requests to the
+// URL of the record with its method if the operation isn't routed
+func (r operationRoutes) get(operation opencdc.Operation) *operationRoute {
+	if route, ok := r[operation]; ok {
+		return route
+	}
+	return &operationRoute{}
+}
+
+// validateUpsert checks that upserted records are sent with different routes
+// for creates and updates
+func (c *Config) validateUpsert() error {
+	switch c.Upsert {
+	case upsertNone:
+		return nil
+	case upsertUpdateFirst, upsertCreateFirst:
+	default:
+		return fmt.Errorf("invalid upsert: %s (must be none, update-first or create-first)", c.Upsert)
+	}
+	if !c.Operations.Create.isSet() && !c.Operations.Update.isSet() {
+		return fmt.Errorf("upsert requires operations.create or operations.update")
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package destination_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+// itemsEndpoint is an API creating items with POST /items and updating them
+// with PUT /items/{id}, it records the requests as "METHOD path"
+type itemsEndpoint struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	items    map[string]string
+	requests []string
+}
+
+func newItemsEndpoint(t *testing.T, existing ...string) *itemsEndpoint {
+	e := &itemsEndpoint{items: make(map[string]string)}
+	for _, id := range existing {
+		e.items[id] = ""
+	}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.requests = append(e.requests, r.Method+" "+r.URL.Path)
+
+		id, byID := strings.CutPrefix(r.URL.Path, "/items/")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/items":
+			// The test records carry their ID in the body
+			id = strings.TrimSuffix(strings.TrimPrefix(string(body), `{"id":"`), `"}`)
+			if _, ok := e.items[id]; ok {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			e.items[id] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && byID:
+			if _, ok := e.items[id]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			e.items[id] = string(body)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// Requests returns the requests received in order
+func (e *itemsEndpoint) Requests() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.requests...)
+}
+
+// Item returns the last body an item was created or updated with
+func (e *itemsEndpoint) Item(id string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.items[id]
+}
+
+func TestUpsert(t *testing.T) {
+	testCases := []struct {
+		name     string
+		upsert   string
+		existing []string
+		want     []string
+	}{{
+		name:   "update first, new item",
+		upsert: "update-first",
+		want:   []string{"PUT /items/1", "POST /items"},
+	}, {
+		name:     "update first, existing item",
+		upsert:   "update-first",
+		existing: []string{"1"},
+		want:     []string{"PUT /items/1"},
+	}, {
+		name:   "create first, new item",
+		upsert: "create-first",
+		want:   []string{"POST /items"},
+	}, {
+		name:     "create first, existing item",
+		upsert:   "create-first",
+		existing: []string{"1"},
+		want:     []string{"POST /items", "PUT /items/1"},
+	}, {
+		name:   "none",
+		upsert: "none",
+		want:   []string{"POST /items"},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			ctx := context.Background()
+			endpoint := newItemsEndpoint(t, tc.existing...)
+			dest := openDestination(t, map[string]string{
+				"url":                      endpoint.URL,
+				"retry.max":                "0",
+				"operations.create.path":   "/items",
+				"operations.update.method": "PUT",
+				"operations.update.path":   "/items/{{.Key | urlPathEncode}}",
+				"upsert":                   tc.upsert,
+			})
+
+			// Records are sent as creates, the fallback is part of their attempt
+			n, err := dest.Write(ctx, testRecords("1"))
+			is.NoErr(err)
+			is.Equal(n, 1)
+			is.Equal(endpoint.Requests(), tc.want)
+			is.Equal(endpoint.Item("1"), testBodies("1")[0])
+		})
+	}
+}
+
+func TestUpsertFallbackFails(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	// The item exists already but can't be updated at its URL
+	endpoint := newItemsEndpoint(t, "1")
+	dest := openDestination(t, map[string]string{
+		"url":                      endpoint.URL,
+		"retry.max":                "0",
+		"operations.create.path":   "/items",
+		"operations.update.method": "PATCH",
+		"operations.update.path":   "/items/{{.Key | urlPathEncode}}",
+		"upsert":                   "create-first",
+	})
+
+	// The response of the fallback is the one checked for errors
+	n, err := dest.Write(ctx, testRecords("1"))
+	is.True(err != nil)
+	is.Equal(n, 0)
+	is.Equal(endpoint.Requests(), []string{"POST /items", "PATCH /items/1"})
+}
@@ -0,0 +1,94 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	stdhttp "net/http"
+
+	"github.com/dev-in-black/connector-http/internal/auth"
+	"github.com/dev-in-black/connector-http/internal/http"
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+// webhookPublisher posts response messages to the response webhook, with its
+// own client, authentication and retries
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+	retry  *http.RetryEngine
+}
+
+// webhookMessage is the body posted to the response webhook, the message
+// with the record metadata
+type webhookMessage struct {
+	*response.Message
+	Metadata map[string]string `json:"metadata"`
+}
+
+// newWebhookPublisher creates the publisher of the response webhook,
+// authenticated with the auth profile of credentials it names
+func (d *Destination) newWebhookPublisher(credentials Config) (*webhookPublisher, error) {
+	cfg := d.config.ResponseWebhook
+	authConfig := auth.Config{Type: "none"}
+	if cfg.AuthProfile != "" {
+		authConfig = credentials.Auth.Profiles[cfg.AuthProfile].authConfig()
+	}
+	authManager, err := auth.NewManager(authConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth manager for responseWebhook: %w", err)
+	}
+	userAgent, err := d.config.userAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.NewClient(http.Config{
+		Timeout:             cfg.Timeout,
+		MaxIdleConns:        d.config.MaxIdleConns,
+		MaxConnsPerHost:     d.config.MaxConnsPerHost,
+		UserAgent:           userAgent,
+		ForceAttemptHTTP2:   d.config.ForceAttemptHTTP2,
+		KeepAlive:           d.config.KeepAlive,
+		IdleConnTimeout:     d.config.IdleConnTimeout,
+		DialTimeout:         d.config.DialTimeout,
+		TLSHandshakeTimeout: d.config.TLS.HandshakeTimeout,
+	}, authManager, cfg.Headers, nil)
+
+	return &webhookPublisher{
+		url:    cfg.URL,
+		client: client,
+		retry: http.NewRetryEngine(http.RetryConfig{
+			MaxRetries:        cfg.Retry.Max,
+			BackoffBase:       cfg.Retry.BackoffBase,
+			BackoffMax:        cfg.Retry.BackoffMax,
+			RetryOn5xx:        true,
+			RetryOn429:        true,
+			RetryOnNetworkErr: true,
+		}),
+	}, nil
+}
+
+// Publish posts the message with the record headers as its metadata, retrying
+// failed requests
+func (w *webhookPublisher) Publish(ctx context.Context, msg *response.Message, recordHeaders map[string]string) error {
+	body, err := json.Marshal(webhookMessage{Message: msg, Metadata: recordHeaders})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook message: %w", err)
+	}
+	resp, err := w.retry.Do(ctx, func() (*stdhttp.Response, error) {
+		return w.client.Post(ctx, w.url, body)
+	})
+	if resp != nil {
+		discardBody(resp)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to post response to webhook: %w", err)
+	}
+	return nil
+}
+
+// Close releases the idle connections of the webhook client
+func (w *webhookPublisher) Close() {
+	w.client.CloseIdleConnections()
+}
@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dev-in-black/connector-http/internal/jsonl"
+)
+
+// maxEntrySize bounds the length of a line of the audit log
+const maxEntrySize = 1 << 20
+
+// Entry is an outbound request in the audit log. Each entry carries the hash
+// of the previous one, so removing or changing an entry breaks the chain.
+type Entry struct {
+	Seq        uint64    `json:"seq"`
+	Time       time.Time `json:"time"` // When the response or error was received
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	BodySHA256 string    `json:"bodySha256"`
+	BodySize   int       `json:"bodySize"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	PrevHash   string    `json:"prevHash"`
+	Hash       string    `json:"hash"`
+}
+
+// BodyHash returns the hex SHA-256 of a request body
+func BodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// computeHash returns the hash of the entry, the SHA-256 of its JSON encoding
+// without the hash itself
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log is an append-only file of hash-chained entries
+type Log struct {
+	mu       sync.Mutex
+	file     *jsonl.File
+	seq      uint64
+	prevHash string
+}
+
+// Open opens the audit log at path and verifies the entries it already holds,
+// new entries continue their chain. An entry torn by a crash is removed
+// first, repaired reports its size. The policy controls how often entries are
+// flushed to disk, with Lines 1 before Append returns.
+func Open(path string, policy jsonl.SyncPolicy) (log *Log, repaired int64, err error) {
+	file, repaired, err := jsonl.Open(path, policy)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	_, last, err := verify(file.Reader())
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to verify audit log %s: %w", path, err)
+	}
+	return &Log{file: file, seq: last.Seq, prevHash: last.Hash}, repaired, nil
+}
+
+// Append chains the entry to the previous one and writes it. Seq, PrevHash
+// and Hash are set by the log.
+func (l *Log) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	e.Time = e.Time.UTC()
+	e.PrevHash = l.prevHash
+	hash, err := e.computeHash()
+	if err != nil {
+		return fmt.Errorf("failed to hash audit entry: %w", err)
+	}
+	e.Hash = hash
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if err := l.file.WriteLine(data); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	l.seq, l.prevHash = e.Seq, e.Hash
+	return nil
+}
+
+// Close closes the audit log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// ErrBrokenChain is returned by Verify for a log that was modified
+var ErrBrokenChain = errors.New("audit log chain is broken")
+
+// Verify checks that the entries of an audit log are intact and complete and
+// returns their number
+func Verify(r io.Reader) (int, error) {
+	n, _, err := verify(r)
+	return n, err
+}
+
+// verify checks the entries of an audit log and returns their number and the
+// last entry
+func verify(r io.Reader) (int, Entry, error) {
+	var n int
+	var prev Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return n, prev, fmt.Errorf("entry %d: invalid entry: %w", n+1, err)
+		}
+
+		hash, err := e.computeHash()
+		if err != nil {
+			return n, prev, fmt.Errorf("entry %d: %w", n+1, err)
+		}
+		switch {
+		case n == 0 && (e.Seq != 1 || e.PrevHash != ""):
+			return n, prev, fmt.Errorf("%w: entries before %d are missing", ErrBrokenChain, e.Seq)
+		case hash != e.Hash:
+			return n, prev, fmt.Errorf("%w: entry %d was modified", ErrBrokenChain, e.Seq)
+		case n > 0 && (e.Seq != prev.Seq+1 || e.PrevHash != prev.Hash):
+			return n, prev, fmt.Errorf("%w: entries between %d and %d are missing or reordered", ErrBrokenChain, prev.Seq, e.Seq)
+		}
+		prev = e
+		n++
+	}
+	return n, prev, scanner.Err()
+}
@@ -0,0 +1,152 @@
+//go:build chaos
+
+package chaos
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dev-in-black/connector-http/pkg/httpclient"
+)
+
+// config is the fault injection configured by the environment
+type config struct {
+	latencyRate float64
+	latencyMax  time.Duration
+	dropRate    float64
+	errorRate   float64
+	errorStatus int
+	seed        int64
+}
+
+// FromEnv returns the fault injection middleware configured by the
+// HTTP_CHAOS_ environment variables, nil if they inject no faults
+func FromEnv() (httpclient.Middleware, error) {
+	cfg, err := configFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.latencyRate == 0 && cfg.dropRate == 0 && cfg.errorRate == 0 {
+		return nil, nil
+	}
+	return middleware(cfg), nil
+}
+
+func configFromEnv() (config, error) {
+	cfg := config{
+		latencyMax:  time.Second,
+		errorStatus: http.StatusServiceUnavailable,
+		seed:        time.Now().UnixNano(),
+	}
+	var err error
+	if cfg.latencyRate, err = rateEnv(EnvLatencyRate); err != nil {
+		return config{}, err
+	}
+	if cfg.dropRate, err = rateEnv(EnvDropRate); err != nil {
+		return config{}, err
+	}
+	if cfg.errorRate, err = rateEnv(EnvErrorRate); err != nil {
+		return config{}, err
+	}
+	if cfg.dropRate+cfg.errorRate > 1 {
+		return config{}, fmt.Errorf("%s and %s must add up to at most 1", EnvDropRate, EnvErrorRate)
+	}
+	if value := os.Getenv(EnvLatencyMax); value != "" {
+		if cfg.latencyMax, err = time.ParseDuration(value); err != nil || cfg.latencyMax <= 0 {
+			return config{}, fmt.Errorf("invalid %s: %s (must be a positive duration)", EnvLatencyMax, value)
+		}
+	}
+	if value := os.Getenv(EnvErrorStatus); value != "" {
+		if cfg.errorStatus, err = strconv.Atoi(value); err != nil || cfg.errorStatus < 400 || cfg.errorStatus > 599 {
+			return config{}, fmt.Errorf("invalid %s: %s (must be between 400 and 599)", EnvErrorStatus, value)
+		}
+	}
+	if value := os.Getenv(EnvSeed); value != "" {
+		if cfg.seed, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return config{}, fmt.Errorf("invalid %s: %s (must be an integer)", EnvSeed, value)
+		}
+	}
+	return cfg, nil
+}
+
+// rateEnv returns the rate of the environment variable, 0 if it isn't set
+func rateEnv(name string) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("invalid %s: %s (must be between 0 and 1)", name, value)
+	}
+	return rate, nil
+}
+
+// middleware delays, drops and fails requests at random. Delays add to the
+// faults, dropped and failed requests never reach the endpoint.
+func middleware(cfg config) httpclient.Middleware {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(cfg.seed))
+	roll := func() (delay time.Duration, drop, fail bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rng.Float64() < cfg.latencyRate {
+			delay = time.Duration(rng.Int63n(int64(cfg.latencyMax)))
+		}
+		fault := rng.Float64()
+		return delay, fault < cfg.dropRate, fault >= cfg.dropRate && fault < cfg.dropRate+cfg.errorRate
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			delay, drop, fail := roll()
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					closeBody(req)
+					return nil, req.Context().Err()
+				}
+			}
+
+			switch {
+			case drop:
+				closeBody(req)
+				return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+			case fail:
+				closeBody(req)
+				return &http.Response{
+					Status:        fmt.Sprintf("%d %s", cfg.errorStatus, http.StatusText(cfg.errorStatus)),
+					StatusCode:    cfg.errorStatus,
+					Proto:         "HTTP/1.1",
+					ProtoMajor:    1,
+					ProtoMinor:    1,
+					Header:        http.Header{"Content-Type": {"text/plain"}},
+					Body:          io.NopCloser(strings.NewReader("fault injected")),
+					ContentLength: int64(len("fault injected")),
+					Request:       req,
+				}, nil
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// closeBody closes the body of a request that isn't sent, as round trippers
+// must
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
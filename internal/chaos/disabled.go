@@ -0,0 +1,11 @@
+//go:build !chaos
+
+package chaos
+
+import "github.com/dev-in-black/connector-http/pkg/httpclient"
+
+// FromEnv returns nil, fault injection is only compiled into builds with the
+// chaos tag
+func FromEnv() (httpclient.Middleware, error) {
+	return nil, nil
+}
@@ -0,0 +1,25 @@
+// Package chaos injects faults into the requests of the HTTP destination, to
+// test the resilience of pipelines: random latencies, dropped connections and
+// error responses. It is only compiled into builds with the chaos tag and
+// configured by environment variables, production builds never inject faults.
+//
+//	go build -tags chaos -o connector-http cmd/connector/main.go
+//	HTTP_CHAOS_ERROR_RATE=0.1 HTTP_CHAOS_DROP_RATE=0.05 conduit run
+package chaos
+
+// The environment variables configuring fault injection
+const (
+	// EnvLatencyRate is the share of requests delayed, between 0 and 1
+	EnvLatencyRate = "HTTP_CHAOS_LATENCY_RATE"
+	// EnvLatencyMax is the longest delay, delays are uniform up to it,
+	// default 1s
+	EnvLatencyMax = "HTTP_CHAOS_LATENCY_MAX"
+	// EnvDropRate is the share of requests failing with a reset connection
+	EnvDropRate = "HTTP_CHAOS_DROP_RATE"
+	// EnvErrorRate is the share of requests answered with EnvErrorStatus
+	EnvErrorRate = "HTTP_CHAOS_ERROR_RATE"
+	// EnvErrorStatus is the status of the error responses, default 503
+	EnvErrorStatus = "HTTP_CHAOS_ERROR_STATUS"
+	// EnvSeed seeds the random faults to reproduce a run
+	EnvSeed = "HTTP_CHAOS_SEED"
+)
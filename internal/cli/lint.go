@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/conduitio/conduit-commons/config"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	connector "github.com/dev-in-black/connector-http"
+	"github.com/dev-in-black/connector-http/destination"
+	"github.com/dev-in-black/connector-http/internal/secrets"
+)
+
+// redacted replaces the values of credentials in effective settings
+const redacted = "[REDACTED]"
+
+// maxSuggestionDistance bounds the edit distance of the known parameter
+// suggested for an unknown one
+const maxSuggestionDistance = 3
+
+// sensitiveHeaders are headers whose values are redacted in effective
+// settings, in lower case
+var sensitiveHeaders = []string{"authorization", "proxy-authorization", "cookie"}
+
+// Finding is a problem of settings found by Lint
+type Finding struct {
+	Param   string
+	Message string
+	// Warning findings don't prevent the destination from opening, e.g.
+	// deprecated parameters
+	Warning bool
+}
+
+// Params returns the parameters of the destination
+func Params() config.Parameters {
+	return connector.Connector.NewSpecification().DestinationParams
+}
+
+// Lint checks the settings of the destination without connecting anywhere:
+// unknown parameters, with the known parameter closest to them, deprecated
+// parameters and the validation of the destination.
+func Lint(ctx context.Context, settings map[string]string) []Finding {
+	params := Params()
+	deprecated := destination.DeprecatedParams()
+
+	var findings []Finding
+	known := make(map[string]string, len(settings))
+	for _, key := range sortedKeys(settings) {
+		name, ok := paramOf(params, key)
+		if !ok {
+			msg := "unknown parameter"
+			if suggestion := closestParam(params, key); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %s?", suggestion)
+			}
+			findings = append(findings, Finding{Param: key, Message: msg})
+			continue
+		}
+		known[key] = settings[key]
+
+		if replacement, ok := deprecated[name]; ok {
+			findings = append(findings, Finding{Param: key, Message: "deprecated, use " + replacement, Warning: true})
+		} else if prefix, ok := deprecatedPrefix(deprecated, name); ok {
+			findings = append(findings, Finding{Param: key, Message: "deprecated, use " + prefix, Warning: true})
+		}
+	}
+
+	// Unknown parameters are reported above, the remaining settings are
+	// validated like the destination does on configure
+	dst := destination.NewDestination()
+	if err := sdk.Util.ParseConfig(ctx, known, dst.Config(), params); err != nil {
+		findings = append(findings, Finding{Message: err.Error()})
+	}
+	return findings
+}
+
+// EffectiveSettings returns the settings with the defaults of the parameters
+// that aren't set, with the values of credentials redacted unless they
+// reference secrets
+func EffectiveSettings(settings map[string]string) map[string]string {
+	params := Params()
+	effective := make(map[string]string, len(params))
+	for name, param := range params {
+		// Defaults of map entries only apply to entries that are set
+		if param.Default != "" && !strings.Contains(name, "*") {
+			effective[name] = param.Default
+		}
+	}
+	for key, value := range settings {
+		effective[key] = value
+	}
+
+	credentials := destination.CredentialParams()
+	for key, value := range effective {
+		if value == "" || secrets.IsReference(value) {
+			continue
+		}
+		if isCredential(credentials, key) {
+			effective[key] = redacted
+		}
+	}
+	return effective
+}
+
+// isCredential reports whether the setting holds a credential or a sensitive
+// header
+func isCredential(credentials []string, key string) bool {
+	for _, pattern := range credentials {
+		if matchParam(pattern, key) {
+			return true
+		}
+	}
+	if strings.Contains(strings.ToLower(key), "headers.") {
+		header := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+		for _, sensitive := range sensitiveHeaders {
+			if header == sensitive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// paramOf returns the name of the parameter of a setting, with * in place of
+// the names of map entries
+func paramOf(params config.Parameters, key string) (string, bool) {
+	if _, ok := params[key]; ok {
+		return key, true
+	}
+	for name := range params {
+		if strings.Contains(name, "*") && matchParam(name, key) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// matchParam reports whether the setting key is of the parameter name, * in
+// name matches a single segment of the key, a trailing * the rest of it
+func matchParam(name, key string) bool {
+	nameSegments := strings.Split(name, ".")
+	keySegments := strings.Split(key, ".")
+	if n := len(nameSegments); nameSegments[n-1] == "*" && len(keySegments) > n {
+		keySegments = append(keySegments[:n-1], strings.Join(keySegments[n-1:], "."))
+	}
+	if len(nameSegments) != len(keySegments) {
+		return false
+	}
+	for i, segment := range nameSegments {
+		if ok, _ := path.Match(segment, keySegments[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// deprecatedPrefix returns the replacement of a deprecated map parameter the
+// parameter name is an entry of
+func deprecatedPrefix(deprecated map[string]string, name string) (string, bool) {
+	for from, to := range deprecated {
+		if strings.HasSuffix(from, ".*") && strings.HasPrefix(name, from+".") {
+			return to + strings.TrimPrefix(name, from), true
+		}
+	}
+	return "", false
+}
+
+// closestParam returns the parameter closest to an unknown setting by edit
+// distance, ignoring case, empty if none is close
+func closestParam(params config.Parameters, key string) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, name := range sortedKeys(params) {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// DecodeRecord decodes the OpenCDC JSON of a record. Missing fields are
+// empty, a missing operation is create.
+func DecodeRecord(data []byte) (opencdc.Record, error) {
+	var raw struct {
+		Position  json.RawMessage `json:"position"`
+		Operation json.RawMessage `json:"operation"`
+		Metadata  json.RawMessage `json:"metadata"`
+		Key       json.RawMessage `json:"key"`
+		Payload   struct {
+			Before json.RawMessage `json:"before"`
+			After  json.RawMessage `json:"after"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return opencdc.Record{}, fmt.Errorf("invalid record: %w", err)
+	}
+	// The OpenCDC decoder requires the data fields
+	for _, field := range []*json.RawMessage{&raw.Position, &raw.Metadata, &raw.Key, &raw.Payload.Before, &raw.Payload.After} {
+		if len(*field) == 0 {
+			*field = json.RawMessage("null")
+		}
+	}
+	if len(raw.Operation) == 0 || string(raw.Operation) == "null" {
+		raw.Operation = json.RawMessage(`"create"`)
+	}
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return opencdc.Record{}, fmt.Errorf("invalid record: %w", err)
+	}
+
+	var record opencdc.Record
+	if err := record.UnmarshalJSON(normalized); err != nil {
+		return opencdc.Record{}, fmt.Errorf("invalid record: %w", err)
+	}
+	return record, nil
+}
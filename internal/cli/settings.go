@@ -0,0 +1,135 @@
+// Package cli holds what the commands running the HTTP destination outside of
+// Conduit share, e.g. reading its settings from a pipeline configuration file
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/dev-in-black/connector-http/destination"
+)
+
+// envPattern matches the environment variable references Conduit expands in
+// pipeline configuration files, ${NAME}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// middlewarePrefix is the prefix of settings of the SDK middleware, they
+// don't configure the destination itself
+const middlewarePrefix = "sdk."
+
+// pipelineFile is the part of a Conduit pipeline configuration file the
+// settings of a destination are read from
+type pipelineFile struct {
+	Pipelines []struct {
+		ID         string `yaml:"id"`
+		Connectors []struct {
+			ID       string            `yaml:"id"`
+			Type     string            `yaml:"type"`
+			Plugin   string            `yaml:"plugin"`
+			Settings map[string]string `yaml:"settings"`
+		} `yaml:"connectors"`
+	} `yaml:"pipelines"`
+}
+
+// LoadSettings reads the settings of the HTTP destination from path, either a
+// Conduit pipeline configuration file or a YAML or JSON map of settings.
+// Environment variable references are expanded like Conduit does. In a
+// pipeline file, connectorID selects the destination, it may be empty if the
+// file holds a single HTTP destination.
+func LoadSettings(path, connectorID string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	data = envPattern.ReplaceAllFunc(data, func(ref []byte) []byte {
+		return []byte(os.Getenv(string(envPattern.FindSubmatch(ref)[1])))
+	})
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	var settings map[string]string
+	if _, ok := doc["pipelines"]; ok {
+		settings, err = pipelineSettings(data, connectorID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s as map of settings: %w", path, err)
+	}
+
+	for key := range settings {
+		if strings.HasPrefix(key, middlewarePrefix) {
+			delete(settings, key)
+		}
+	}
+	return settings, nil
+}
+
+// pipelineSettings returns the settings of the HTTP destination of a pipeline
+// file with the ID connectorID, or of its only HTTP destination
+func pipelineSettings(data []byte, connectorID string) (map[string]string, error) {
+	var file pipelineFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid pipeline configuration: %w", err)
+	}
+
+	var ids []string
+	var settings map[string]string
+	for _, pipeline := range file.Pipelines {
+		for _, conn := range pipeline.Connectors {
+			switch {
+			case connectorID != "" && conn.ID == connectorID:
+				return conn.Settings, nil
+			case connectorID == "" && conn.Type == "destination" && strings.Contains(conn.Plugin, "http"):
+				ids = append(ids, conn.ID)
+				settings = conn.Settings
+			}
+		}
+	}
+	switch {
+	case connectorID != "":
+		return nil, fmt.Errorf("no connector %s", connectorID)
+	case len(ids) == 0:
+		return nil, fmt.Errorf("no HTTP destination")
+	case len(ids) > 1:
+		return nil, fmt.Errorf("several HTTP destinations (%s), select one by ID", strings.Join(ids, ", "))
+	}
+	return settings, nil
+}
+
+// OpenDestination creates the HTTP destination with the settings, validated
+// against its specification, and opens it
+func OpenDestination(ctx context.Context, settings map[string]string) (sdk.Destination, error) {
+	dst := destination.NewDestination()
+	if err := sdk.Util.ParseConfig(ctx, settings, dst.Config(), Params()); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	if err := dst.Open(ctx); err != nil {
+		_ = dst.Teardown(ctx)
+		return nil, fmt.Errorf("failed to open destination: %w", err)
+	}
+	return dst, nil
+}
+
+// NewContext returns a context logging to stderr at the level, e.g. info,
+// canceled on SIGINT or SIGTERM
+func NewContext(level string) (context.Context, context.CancelFunc, error) {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	logger := zerolog.New(os.Stderr).Level(lvl).With().Timestamp().Logger()
+	ctx, stop := signal.NotifyContext(logger.WithContext(context.Background()), os.Interrupt, syscall.SIGTERM)
+	return ctx, stop, nil
+}
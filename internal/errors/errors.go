@@ -0,0 +1,36 @@
+package errors
+
+import "github.com/dev-in-black/connector-http/pkg/httpclient"
+
+// The error categories are defined by pkg/httpclient, whose client and retry
+// engine wrap them. The destination wraps them too, e.g. ErrValidation for
+// records that can't be rendered, so errors returned by Write, logs and DLQ
+// records can be filtered by category.
+
+var (
+	ErrAuth               = httpclient.ErrAuth
+	ErrRateLimited        = httpclient.ErrRateLimited
+	ErrTimeout            = httpclient.ErrTimeout
+	ErrValidation         = httpclient.ErrValidation
+	ErrNonRetryableStatus = httpclient.ErrNonRetryableStatus
+)
+
+var (
+	WithCategory = httpclient.WithCategory
+	Category     = httpclient.Category
+)
+
+// categoryNames are the names of the categories in logs and DLQ records
+var categoryNames = map[error]string{
+	ErrAuth:               "auth",
+	ErrRateLimited:        "rate_limited",
+	ErrTimeout:            "timeout",
+	ErrValidation:         "validation",
+	ErrNonRetryableStatus: "non_retryable_status",
+}
+
+// CategoryName returns the name of the most specific category of err, empty
+// if it has none
+func CategoryName(err error) string {
+	return categoryNames[Category(err)]
+}
@@ -0,0 +1,277 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// URL schemes of gRPC targets
+const (
+	plaintextScheme = "grpc://"
+	tlsScheme       = "grpcs://"
+)
+
+// TargetFromURL returns the host:port of a grpc:// (plaintext) or grpcs://
+// (TLS) URL and whether it uses TLS
+func TargetFromURL(url string) (target string, useTLS bool, ok bool) {
+	switch {
+	case strings.HasPrefix(url, plaintextScheme):
+		target = strings.TrimPrefix(url, plaintextScheme)
+	case strings.HasPrefix(url, tlsScheme):
+		target, useTLS = strings.TrimPrefix(url, tlsScheme), true
+	default:
+		return "", false, false
+	}
+	target, _, _ = strings.Cut(target, "/")
+	target, _, _ = strings.Cut(target, "?")
+	return target, useTLS, target != ""
+}
+
+// Config holds the configuration of the gRPC transport
+type Config struct {
+	// URL is the grpc:// or grpcs:// URL of the server
+	URL string
+	// Method is the full name of the unary method, e.g. orders.v1.OrderService/CreateOrder
+	Method string
+	// DescriptorSet is a file holding a FileDescriptorSet describing the
+	// method, empty resolves it with server reflection
+	DescriptorSet string
+}
+
+// Transport is an http.RoundTripper delivering requests as gRPC unary calls.
+// The JSON request body is the request message, the response message is
+// returned as JSON in a response whose status is mapped from the gRPC status
+// code, so authentication, retries and response routing work unchanged.
+type Transport struct {
+	conn   *grpc.ClientConn
+	method string // /package.Service/Method
+	input  protoreflect.MessageDescriptor
+	output protoreflect.MessageDescriptor
+}
+
+// NewTransport connects to the server and resolves the method descriptor
+func NewTransport(ctx context.Context, cfg Config) (*Transport, error) {
+	target, useTLS, ok := TargetFromURL(cfg.URL)
+	if !ok {
+		return nil, fmt.Errorf("invalid gRPC URL %q (must be grpc://host:port or grpcs://host:port)", cfg.URL)
+	}
+	service, method, err := splitMethod(cfg.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	var desc protoreflect.MethodDescriptor
+	if cfg.DescriptorSet != "" {
+		desc, err = methodFromDescriptorSet(cfg.DescriptorSet, service, method)
+	} else {
+		desc, err = methodFromReflection(ctx, conn, service, method)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if desc.IsStreamingClient() || desc.IsStreamingServer() {
+		conn.Close()
+		return nil, fmt.Errorf("method %s is a streaming method, only unary methods are supported", cfg.Method)
+	}
+
+	return &Transport{
+		conn:   conn,
+		method: "/" + service + "/" + method,
+		input:  desc.Input(),
+		output: desc.Output(),
+	}, nil
+}
+
+// splitMethod splits package.Service/Method into its service and method name
+func splitMethod(fullName string) (string, string, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullName, "/"), "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return "", "", fmt.Errorf("invalid gRPC method %q (must be package.Service/Method)", fullName)
+	}
+	return service, method, nil
+}
+
+// RoundTrip sends the request body as the request message of the method.
+// Requests without body, e.g. connectivity probes, wait for the connection
+// to be ready instead of calling the method.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if req.Method == http.MethodHead || req.Method == http.MethodGet || req.Method == http.MethodOptions {
+		return t.probe(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	in := dynamicpb.NewMessage(t.input)
+	if err := protojson.Unmarshal(body, in); err != nil {
+		return statusResponse(req, status.Newf(codes.InvalidArgument, "request body doesn't match %s: %v", t.input.FullName(), err), nil), nil
+	}
+
+	out := dynamicpb.NewMessage(t.output)
+	var header metadata.MD
+	err := t.conn.Invoke(metadata.NewOutgoingContext(ctx, requestMetadata(req.Header)), t.method, in, out, grpc.Header(&header))
+	if err != nil {
+		// Canceled calls are errors like canceled HTTP requests
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return statusResponse(req, status.Convert(err), header), nil
+	}
+
+	data, err := protojson.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response message: %w", err)
+	}
+	return newResponse(req, http.StatusOK, header, data), nil
+}
+
+// probe reports whether the connection to the server can be established
+func (t *Transport) probe(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	t.conn.Connect()
+	for {
+		state := t.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return newResponse(req, http.StatusOK, nil, nil), nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return statusResponse(req, status.Newf(codes.Unavailable, "connection is %s", state), nil), nil
+		}
+		if !t.conn.WaitForStateChange(ctx, state) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close closes the connection to the server
+func (t *Transport) Close() error {
+	return t.conn.Close()
+}
+
+// skippedHeaders are HTTP headers that aren't sent as gRPC metadata
+var skippedHeaders = map[string]bool{
+	"content-type":      true,
+	"content-length":    true,
+	"connection":        true,
+	"te":                true,
+	"host":              true,
+	"accept-encoding":   true,
+	"transfer-encoding": true,
+}
+
+// requestMetadata converts the request headers, e.g. set by authentication,
+// to gRPC metadata
+func requestMetadata(header http.Header) metadata.MD {
+	md := make(metadata.MD, len(header))
+	for name, values := range header {
+		key := strings.ToLower(name)
+		if skippedHeaders[key] || strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		md[key] = values
+	}
+	return md
+}
+
+// statusResponse returns the response of a failed call, its body holds the
+// gRPC status as JSON
+func statusResponse(req *http.Request, st *status.Status, header metadata.MD) *http.Response {
+	data, _ := json.Marshal(map[string]string{
+		"code":    st.Code().String(),
+		"message": st.Message(),
+	})
+	resp := newResponse(req, HTTPStatus(st.Code()), header, data)
+	resp.Header.Set("Grpc-Status", strconv.Itoa(int(st.Code())))
+	return resp
+}
+
+// newResponse returns a response with a JSON body and the response metadata
+// as headers
+func newResponse(req *http.Request, statusCode int, header metadata.MD, body []byte) *http.Response {
+	h := make(http.Header, len(header)+1)
+	for key, values := range header {
+		for _, value := range values {
+			h.Add(key, value)
+		}
+	}
+	h.Set("Content-Type", "application/json")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// HTTPStatus maps a gRPC status code to the HTTP status with the same
+// meaning, as the gRPC-HTTP gateways do
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default: // Unknown, Internal, DataLoss
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,156 @@
+// Package harness holds what tests of the HTTP destination share: a fake
+// endpoint answering with scripted responses, a Kafka broker to check
+// published responses and the driver of the SDK acceptance tests.
+package harness
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Response is a response of the fake endpoint
+type Response struct {
+	Status  int
+	Headers http.Header
+	Body    string
+	// Delay is waited before responding, e.g. to trigger timeouts
+	Delay time.Duration
+	// Drop closes the connection without responding
+	Drop bool
+}
+
+// Request is a request received by the fake endpoint
+type Request struct {
+	Time    time.Time
+	Method  string
+	Path    string
+	Query   string
+	Headers http.Header
+	Body    []byte
+}
+
+// Endpoint is a fake HTTP endpoint recording the requests it receives and
+// answering with scripted responses, then with its default response
+type Endpoint struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	script   []Response
+	fallback Response
+	required http.Header
+	requests []Request
+}
+
+// NewEndpoint starts a fake endpoint answering 200, closed when the test ends
+func NewEndpoint(tb testing.TB) *Endpoint {
+	tb.Helper()
+	e := &Endpoint{fallback: Response{Status: http.StatusOK}, required: http.Header{}}
+	e.server = httptest.NewServer(http.HandlerFunc(e.serve))
+	tb.Cleanup(e.server.Close)
+	return e
+}
+
+// URL returns the base URL of the endpoint
+func (e *Endpoint) URL() string {
+	return e.server.URL
+}
+
+// Script queues responses answered in order, before the default response,
+// e.g. 503, 503 then 200 to test retries
+func (e *Endpoint) Script(responses ...Response) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.script = append(e.script, responses...)
+}
+
+// SetDefault sets the response answered once the script is exhausted
+func (e *Endpoint) SetDefault(resp Response) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fallback = resp
+}
+
+// RequireHeader answers 401 to requests without the header value, e.g. to
+// test authentication
+func (e *Endpoint) RequireHeader(name, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.required.Set(name, value)
+}
+
+// Requests returns the requests received, in order, including those answered
+// with errors
+func (e *Endpoint) Requests() []Request {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Request(nil), e.requests...)
+}
+
+// Reset clears the script, the required headers and the requests received
+// and restores the default response
+func (e *Endpoint) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.script = nil
+	e.fallback = Response{Status: http.StatusOK}
+	e.required = http.Header{}
+	e.requests = nil
+}
+
+func (e *Endpoint) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	resp := e.record(Request{
+		Time:    time.Now(),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: r.Header.Clone(),
+		Body:    body,
+	})
+
+	if resp.Delay > 0 {
+		select {
+		case <-time.After(resp.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if resp.Drop {
+		if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+			conn.Close()
+		}
+		return
+	}
+	for name, values := range resp.Headers {
+		w.Header()[name] = values
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, resp.Body)
+}
+
+// record records a request and returns its response
+func (e *Endpoint) record(req Request) Response {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, req)
+
+	for name := range e.required {
+		if req.Headers.Get(name) != e.required.Get(name) {
+			return Response{Status: http.StatusUnauthorized}
+		}
+	}
+	if len(e.script) > 0 {
+		resp := e.script[0]
+		e.script = e.script[1:]
+		return resp
+	}
+	return e.fallback
+}
@@ -0,0 +1,99 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tckafka "github.com/testcontainers/testcontainers-go/modules/kafka"
+
+	"github.com/dev-in-black/connector-http/internal/kafka"
+)
+
+// KafkaBrokersEnv is the environment variable holding the comma-separated
+// brokers of the Kafka tests, e.g. started with docker compose
+const KafkaBrokersEnv = "HARNESS_KAFKA_BROKERS"
+
+// kafkaImage is the image of the broker started for the Kafka tests
+const kafkaImage = "confluentinc/confluent-local:7.5.0"
+
+// kafkaReadTimeout bounds reading a topic in tests
+const kafkaReadTimeout = 30 * time.Second
+
+// kafkaStartTimeout bounds starting the broker container
+const kafkaStartTimeout = 2 * time.Minute
+
+// The broker container is started once and shared by the tests of a package,
+// it is removed by the testcontainers reaper once they end
+var (
+	kafkaOnce    sync.Once
+	kafkaStarted []string
+	kafkaErr     error
+)
+
+// KafkaBrokers returns the brokers of the Kafka tests: those of
+// HARNESS_KAFKA_BROKERS, or of a broker started in a container. The test is
+// skipped if neither is set up because Docker isn't available.
+func KafkaBrokers(tb testing.TB) []string {
+	tb.Helper()
+	if brokers := os.Getenv(KafkaBrokersEnv); brokers != "" {
+		return strings.Split(brokers, ",")
+	}
+	if err := dockerHealth(); err != nil {
+		tb.Skipf("%s not set and Docker isn't available, skipping Kafka test: %v", KafkaBrokersEnv, err)
+	}
+
+	kafkaOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), kafkaStartTimeout)
+		defer cancel()
+		container, err := tckafka.Run(ctx, kafkaImage, tckafka.WithClusterID("conduit-http-harness"))
+		if err != nil {
+			kafkaErr = fmt.Errorf("failed to start Kafka container: %w", err)
+			return
+		}
+		kafkaStarted, kafkaErr = container.Brokers(ctx)
+	})
+	if kafkaErr != nil {
+		tb.Fatal(kafkaErr)
+	}
+	return kafkaStarted
+}
+
+// dockerHealth returns why containers can't be started, nil if they can
+func dockerHealth() (err error) {
+	// The provider panics if no Docker host can be found
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(context.Background())
+}
+
+// ReadTopic returns the messages of a topic, failing the test if it can't be
+// read
+func ReadTopic(tb testing.TB, brokers []string, topic string) []kafka.Message {
+	tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaReadTimeout)
+	defer cancel()
+
+	var msgs []kafka.Message
+	err := kafka.ReadTopic(ctx, kafka.Config{Brokers: brokers, Topic: topic, ClientID: "conduit-http-harness"}, func(msg kafka.Message) error {
+		msgs = append(msgs, msg)
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("failed to read topic %s: %v", topic, err)
+	}
+	return msgs
+}
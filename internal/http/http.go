@@ -0,0 +1,90 @@
+package http
+
+import "github.com/dev-in-black/connector-http/pkg/httpclient"
+
+// The HTTP stack is implemented by pkg/httpclient so other connectors can
+// reuse it, this package keeps the names the connector uses.
+
+type (
+	Client              = httpclient.Client
+	Config              = httpclient.Config
+	RedirectConfig      = httpclient.RedirectConfig
+	GuardConfig         = httpclient.GuardConfig
+	CoolDownConfig      = httpclient.CoolDownConfig
+	FailoverConfig      = httpclient.FailoverConfig
+	BalanceConfig       = httpclient.BalanceConfig
+	EndpointStats       = httpclient.EndpointStats
+	PoolStats           = httpclient.PoolStats
+	SigningConfig       = httpclient.SigningConfig
+	RequestBuilder      = httpclient.RequestBuilder
+	RetryConfig         = httpclient.RetryConfig
+	RetryEngine         = httpclient.RetryEngine
+	RetryHooks          = httpclient.RetryHooks
+	RetryHookFuncs      = httpclient.RetryHookFuncs
+	Hedger              = httpclient.Hedger
+	Action              = httpclient.Action
+	StatusRule          = httpclient.StatusRule
+	StatusRules         = httpclient.StatusRules
+	StatusRange         = httpclient.StatusRange
+	StatusRanges        = httpclient.StatusRanges
+	BodyPredicate       = httpclient.BodyPredicate
+	BodyPredicateConfig = httpclient.BodyPredicateConfig
+	RequestStats        = httpclient.RequestStats
+	Attempt             = httpclient.Attempt
+	Timing              = httpclient.Timing
+	Exchange            = httpclient.Exchange
+	CapturedRequest     = httpclient.CapturedRequest
+	Capturer            = httpclient.Capturer
+	Middleware          = httpclient.Middleware
+	RequestLogger       = httpclient.RequestLogger
+	Auditor             = httpclient.Auditor
+)
+
+const (
+	ActionAck    = httpclient.ActionAck
+	ActionRetry  = httpclient.ActionRetry
+	ActionFail   = httpclient.ActionFail
+	ActionDLQ    = httpclient.ActionDLQ
+	ActionIgnore = httpclient.ActionIgnore
+
+	InterceptorHeaders        = httpclient.InterceptorHeaders
+	InterceptorAuth           = httpclient.InterceptorAuth
+	InterceptorRequestBuilder = httpclient.InterceptorRequestBuilder
+	InterceptorSign           = httpclient.InterceptorSign
+	InterceptorCapture        = httpclient.InterceptorCapture
+	InterceptorLogging        = httpclient.InterceptorLogging
+	InterceptorAudit          = httpclient.InterceptorAudit
+
+	BodyEncodingBase64 = httpclient.BodyEncodingBase64
+)
+
+var DefaultInterceptors = httpclient.DefaultInterceptors
+
+var (
+	ErrRequestBodyTooLarge  = httpclient.ErrRequestBodyTooLarge
+	ErrResponseBodyTooLarge = httpclient.ErrResponseBodyTooLarge
+	ErrBodyPredicateFailed  = httpclient.ErrBodyPredicateFailed
+	ErrTargetNotAllowed     = httpclient.ErrTargetNotAllowed
+)
+
+var (
+	NewClient          = httpclient.NewClient
+	NewRetryEngine     = httpclient.NewRetryEngine
+	NewHedger          = httpclient.NewHedger
+	ParseStatusRules   = httpclient.ParseStatusRules
+	ParseStatusRanges  = httpclient.ParseStatusRanges
+	NewBodyPredicate   = httpclient.NewBodyPredicate
+	LoadRequestBuilder = httpclient.LoadRequestBuilder
+	UnixSocketFromURL  = httpclient.UnixSocketFromURL
+	IsAmbiguous        = httpclient.IsAmbiguous
+	IsOutage           = httpclient.IsOutage
+	WithRecord         = httpclient.WithRecord
+	WithHeaders        = httpclient.WithHeaders
+	WithStats          = httpclient.WithStats
+	WithCapture        = httpclient.WithCapture
+	CheckInterceptors  = httpclient.CheckInterceptors
+	CheckAllowlist     = httpclient.CheckAllowlist
+	EncodeBody         = httpclient.EncodeBody
+	MultiRetryHooks    = httpclient.MultiRetryHooks
+	LocalIP            = httpclient.LocalIP
+)
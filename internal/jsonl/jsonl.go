@@ -0,0 +1,183 @@
+package jsonl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// tailSize is how much of the end of a file is read to find its last line
+const tailSize = 64 * 1024
+
+// SyncPolicy configures how often lines are flushed to disk. The zero value
+// leaves it to the operating system.
+type SyncPolicy struct {
+	// Lines flushes after this many lines, 1 flushes every line
+	Lines int
+	// Interval flushes lines written at most this long ago
+	Interval time.Duration
+}
+
+// File is an append-only file of JSON lines written by a single process. A
+// line is written with a single write and removed again if the write fails,
+// and a line torn by a crash is removed when the file is opened, so readers
+// only ever see complete lines.
+type File struct {
+	mu      sync.Mutex
+	file    *os.File
+	size    int64 // Size after the last complete line
+	policy  SyncPolicy
+	pending int         // Lines written since the last sync
+	timer   *time.Timer // Syncs pending lines after the interval
+	closed  bool
+}
+
+// Open opens the file at path for appending, creating it if needed. A torn
+// last line is truncated, repaired reports how many bytes were removed.
+func Open(path string, policy SyncPolicy) (f *File, repaired int64, err error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, repaired, err := repair(file)
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to repair %s: %w", path, err)
+	}
+	return &File{file: file, size: size, policy: policy}, repaired, nil
+}
+
+// repair truncates the file after its last newline and returns the new size
+// and the number of bytes removed
+func repair(file *os.File) (int64, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return 0, 0, nil
+	}
+
+	// Search backwards for the last newline, a torn line can span chunks
+	end := size
+	buf := make([]byte, tailSize)
+	for end > 0 {
+		start := max(end-tailSize, 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil && !errors.Is(err, io.EOF) {
+			return 0, 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+	if end == size {
+		return size, 0, nil
+	}
+	if err := file.Truncate(end); err != nil {
+		return 0, 0, err
+	}
+	return end, size - end, file.Sync()
+}
+
+// Reader returns a reader of the complete lines of the file, e.g. to verify
+// them before appending
+func (f *File) Reader() io.Reader {
+	return io.NewSectionReader(f.file, 0, f.size)
+}
+
+// WriteLine appends data and a newline. data must not contain newlines.
+func (f *File) WriteLine(data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+
+	line := make([]byte, 0, len(data)+1)
+	line = append(append(line, data...), '\n')
+	n, err := f.file.WriteAt(line, f.size)
+	if err == nil && n < len(line) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		// Remove what was written of the line, so the next one doesn't
+		// continue it
+		if n > 0 {
+			if truncErr := f.file.Truncate(f.size); truncErr != nil {
+				return fmt.Errorf("%w (removing the partial line failed: %v)", err, truncErr)
+			}
+		}
+		return err
+	}
+	f.size += int64(n)
+	f.pending++
+
+	switch {
+	case f.policy.Lines > 0 && f.pending >= f.policy.Lines:
+		return f.syncLocked()
+	case f.policy.Interval > 0 && f.timer == nil:
+		f.timer = time.AfterFunc(f.policy.Interval, f.syncPending)
+	}
+	return nil
+}
+
+// Sync flushes the lines written so far to disk
+func (f *File) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.syncLocked()
+}
+
+// syncPending flushes the pending lines once the interval elapsed, errors
+// are reported by the next Sync or Close
+func (f *File) syncPending() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.timer = nil
+	if !f.closed && f.pending > 0 {
+		_ = f.syncLocked()
+	}
+}
+
+// syncLocked flushes the file, the caller holds mu
+func (f *File) syncLocked() error {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	if f.pending == 0 {
+		return nil
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+	f.pending = 0
+	return nil
+}
+
+// Close flushes pending lines and closes the file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	syncErr := f.syncLocked()
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
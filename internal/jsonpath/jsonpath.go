@@ -0,0 +1,200 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// segment is a single step of a parsed path, either an object key or an
+// array index
+type segment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// Path is a compiled JSONPath expression supporting the dot and bracket
+// notations, e.g. $.data.items[0].id or $['data']['items'][0]
+type Path struct {
+	raw      string
+	segments []segment
+}
+
+// Parse compiles a JSONPath expression
+func Parse(expr string) (*Path, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	// Allow paths without a leading "$." such as "data.id"
+	rest := strings.TrimPrefix(expr, "$")
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+
+	p := &Path{raw: expr}
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty key", expr)
+			}
+			p.segments = append(p.segments, segment{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid path %q: unclosed bracket", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p.segments = append(p.segments, segment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: invalid index %q", expr, inner)
+			}
+			p.segments = append(p.segments, segment{index: idx, isIdx: true})
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", expr, rest[0])
+		}
+	}
+
+	return p, nil
+}
+
+// String returns the original expression
+func (p *Path) String() string {
+	return p.raw
+}
+
+// Get returns the value at the path in a decoded JSON document
+func (p *Path) Get(doc any) (any, bool) {
+	current := doc
+	for _, seg := range p.segments {
+		if seg.isIdx {
+			arr, ok := current.([]any)
+			if !ok {
+				return nil, false
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			current = arr[idx]
+			continue
+		}
+
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[seg.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Update replaces the value at the path in a decoded JSON document with the
+// result of fn, or removes it if fn returns false. Objects and arrays are
+// modified in place. It returns the updated document and whether the path
+// exists; the root can't be updated.
+func (p *Path) Update(doc any, fn func(v any) (any, bool)) (any, bool) {
+	if len(p.segments) == 0 {
+		return doc, false
+	}
+	return update(doc, p.segments, fn)
+}
+
+// update applies fn to the value at segments below current
+func update(current any, segments []segment, fn func(v any) (any, bool)) (any, bool) {
+	seg, last := segments[0], len(segments) == 1
+
+	if seg.isIdx {
+		arr, ok := current.([]any)
+		if !ok {
+			return current, false
+		}
+		idx := seg.index
+		if idx < 0 {
+			idx += len(arr)
+		}
+		if idx < 0 || idx >= len(arr) {
+			return current, false
+		}
+		if last {
+			v, keep := fn(arr[idx])
+			if !keep {
+				return append(arr[:idx], arr[idx+1:]...), true
+			}
+			arr[idx] = v
+			return arr, true
+		}
+		child, ok := update(arr[idx], segments[1:], fn)
+		arr[idx] = child
+		return arr, ok
+	}
+
+	obj, ok := current.(map[string]any)
+	if !ok {
+		return current, false
+	}
+	value, ok := obj[seg.key]
+	if !ok {
+		return current, false
+	}
+	if last {
+		v, keep := fn(value)
+		if !keep {
+			delete(obj, seg.key)
+			return obj, true
+		}
+		obj[seg.key] = v
+		return obj, true
+	}
+	child, ok := update(value, segments[1:], fn)
+	obj[seg.key] = child
+	return obj, ok
+}
+
+// Lookup decodes a raw JSON document and returns the value at the path
+func (p *Path) Lookup(data []byte) (any, bool) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false
+	}
+	return p.Get(doc)
+}
+
+// Stringify returns a string representation of a value returned by Get.
+// Strings are returned as-is, other values are JSON encoded.
+func Stringify(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
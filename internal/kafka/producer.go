@@ -3,13 +3,17 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl/plain"
 	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/dev-in-black/connector-http/internal/response"
 )
 
 // Config holds the configuration for the Kafka producer
@@ -24,22 +28,30 @@ type Config struct {
 	SASLUsername      string
 	SASLPassword      string
 	TLSEnabled        bool
+	// Partitioner is one of the Partitioner constants, hash if empty
+	Partitioner string
+	// PartitionHeader is the record header holding the partition of response
+	// messages with PartitionerManual
+	PartitionHeader string
+	// KeyHeader is the record header keying response messages, they are
+	// keyed by request URL and time if empty or missing
+	KeyHeader string
 }
 
+// Partitioners of response messages
+const (
+	PartitionerHash       = "hash"
+	PartitionerSticky     = "sticky"
+	PartitionerRoundRobin = "round-robin"
+	PartitionerManual     = "manual"
+)
+
 // Producer wraps the Kafka producer client
 type Producer struct {
-	client *kgo.Client
-	topic  string
-}
-
-// ResponseMessage represents the HTTP response to be published to Kafka
-type ResponseMessage struct {
-	StatusCode      int               `json:"status_code"`
-	ResponseHeaders map[string]string `json:"response_headers"`
-	Body            string            `json:"body"`
-	RequestURL      string            `json:"request_url"`
-	RequestMethod   string            `json:"request_method"`
-	Timestamp       time.Time         `json:"timestamp"`
+	client          *kgo.Client
+	topic           string
+	partitionHeader string // Set with PartitionerManual
+	keyHeader       string
 }
 
 // NewProducer creates a new Kafka producer
@@ -66,12 +78,59 @@ func NewProducer(ctx context.Context, cfg Config) (*Producer, error) {
 		opts = append(opts, kgo.ProducerBatchCompression(kgo.SnappyCompression()))
 	}
 
+	// Set partitioner, murmur2 hashes keys like the Java client
+	switch cfg.Partitioner {
+	case PartitionerHash, "":
+		opts = append(opts, kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)))
+	case PartitionerSticky:
+		opts = append(opts, kgo.RecordPartitioner(kgo.StickyPartitioner()))
+	case PartitionerRoundRobin:
+		opts = append(opts, kgo.RecordPartitioner(kgo.RoundRobinPartitioner()))
+	case PartitionerManual:
+		opts = append(opts, kgo.RecordPartitioner(kgo.ManualPartitioner()))
+	default:
+		return nil, fmt.Errorf("unsupported partitioner: %s", cfg.Partitioner)
+	}
+
 	// Enable idempotent producer
 	if cfg.EnableIdempotence {
 		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
 		opts = append(opts, kgo.DisableIdempotentWrite())
 	}
 
+	connOpts, err := connectionOpts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, connOpts...)
+
+	// Create Kafka client
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+
+	// Ping to verify connection
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Kafka brokers: %w", err)
+	}
+
+	p := &Producer{
+		client:    client,
+		topic:     cfg.Topic,
+		keyHeader: cfg.KeyHeader,
+	}
+	if cfg.Partitioner == PartitionerManual {
+		p.partitionHeader = cfg.PartitionHeader
+	}
+	return p, nil
+}
+
+// connectionOpts returns the client options authenticating and encrypting
+// the connections to the brokers
+func connectionOpts(cfg Config) ([]kgo.Opt, error) {
+	var opts []kgo.Opt
 	// Configure SASL authentication
 	if cfg.SASLEnabled {
 		switch cfg.SASLMechanism {
@@ -103,72 +162,83 @@ func NewProducer(ctx context.Context, cfg Config) (*Producer, error) {
 			MinVersion: tls.VersionTLS12,
 		}))
 	}
+	return opts, nil
+}
 
-	// Create Kafka client
-	client, err := kgo.NewClient(opts...)
+// CheckTopic verifies that the topic exists and the client is authorized to describe it
+func (p *Producer) CheckTopic(ctx context.Context) error {
+	topic := kmsg.NewMetadataRequestTopic()
+	topic.Topic = kmsg.StringPtr(p.topic)
+
+	req := kmsg.NewPtrMetadataRequest()
+	req.Topics = append(req.Topics, topic)
+	req.AllowAutoTopicCreation = false
+
+	resp, err := req.RequestWith(ctx, p.client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+		return fmt.Errorf("failed to request metadata of topic %s: %w", p.topic, err)
 	}
-
-	// Ping to verify connection
-	if err := client.Ping(ctx); err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to connect to Kafka brokers: %w", err)
+	for _, t := range resp.Topics {
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			return fmt.Errorf("topic %s: %w", p.topic, err)
+		}
 	}
-
-	return &Producer{
-		client: client,
-		topic:  cfg.Topic,
-	}, nil
+	return nil
 }
 
-// PublishResponse publishes an HTTP response to Kafka
-func (p *Producer) PublishResponse(ctx context.Context, statusCode int, responseHeaders map[string][]string, body []byte, requestURL, requestMethod string, recordHeaders map[string]string) error {
-	// Convert HTTP response headers to map[string]string for JSON serialization
-	flatResponseHeaders := make(map[string]string)
-	for key, values := range responseHeaders {
-		if len(values) > 0 {
-			flatResponseHeaders[key] = values[0] // Take first value for simplicity
+// Publish publishes a response message to the topic of the producer, with the
+// record headers as Kafka headers
+func (p *Producer) Publish(ctx context.Context, msg *response.Message, recordHeaders map[string]string) error {
+	return response.Encode(msg, func(value []byte) error {
+		record, err := p.newRecord(msg, recordHeaders, value)
+		if err != nil {
+			return err
 		}
-	}
+		// Value only references the encoder buffer until ProduceSync returns
+		if err := p.client.ProduceSync(ctx, record).FirstErr(); err != nil {
+			return fmt.Errorf("failed to produce message to Kafka: %w", err)
+		}
+		return nil
+	})
+}
 
-	// Create response message (record headers go to Kafka headers, not JSON body)
-	msg := ResponseMessage{
-		StatusCode:      statusCode,
-		ResponseHeaders: flatResponseHeaders,
-		Body:            string(body),
-		RequestURL:      requestURL,
-		RequestMethod:   requestMethod,
-		Timestamp:       time.Now(),
+// newRecord returns the Kafka record of an encoded response message, keyed
+// and partitioned by the record headers
+func (p *Producer) newRecord(msg *response.Message, recordHeaders map[string]string, value []byte) (*kgo.Record, error) {
+	record := &kgo.Record{
+		Topic:   p.topic,
+		Value:   value,
+		Headers: make([]kgo.RecordHeader, 0, len(recordHeaders)+2),
 	}
-
-	// Serialize to JSON
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal response message: %w", err)
+	if key, ok := recordHeaders[p.keyHeader]; ok && p.keyHeader != "" {
+		record.Key = []byte(key)
+	} else {
+		key := make([]byte, 0, len(msg.RequestURL)+21)
+		key = append(append(key, msg.RequestURL...), '-')
+		record.Key = strconv.AppendInt(key, time.Now().UnixNano(), 10)
 	}
-
-	// Create Kafka record with record headers as Kafka headers
-	record := &kgo.Record{
-		Topic: p.topic,
-		Value: data,
-		Key:   []byte(fmt.Sprintf("%s-%d", requestURL, time.Now().UnixNano())),
+	if p.partitionHeader != "" {
+		partition, err := strconv.ParseInt(recordHeaders[p.partitionHeader], 10, 32)
+		if err != nil || partition < 0 {
+			return nil, fmt.Errorf("invalid partition %q in record header %s", recordHeaders[p.partitionHeader], p.partitionHeader)
+		}
+		record.Partition = int32(partition)
 	}
 
 	// Add record headers as Kafka record headers for easier filtering
-	for key, value := range recordHeaders {
-		record.Headers = append(record.Headers, kgo.RecordHeader{
-			Key:   key,
-			Value: []byte(value),
-		})
-	}
+	response.EachHeader(msg, recordHeaders, func(key, value string) {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+	})
+	return record, nil
+}
 
-	// Produce record
-	results := p.client.ProduceSync(ctx, record)
-	if err := results.FirstErr(); err != nil {
+// PublishTo produces a message to topic. With PartitionerManual it is produced
+// to partition 0.
+func (p *Producer) PublishTo(ctx context.Context, topic string, key, value []byte) error {
+	record := &kgo.Record{Topic: topic, Key: key, Value: value}
+	if err := p.client.ProduceSync(ctx, record).FirstErr(); err != nil {
 		return fmt.Errorf("failed to produce message to Kafka: %w", err)
 	}
-
 	return nil
 }
 
@@ -0,0 +1,115 @@
+package kafka
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+func TestProducerNewRecord(t *testing.T) {
+	is := is.New(t)
+	p := Producer{topic: "responses"}
+	msg := &response.Message{RequestURL: "https://api.example.com/users", Protocol: "HTTP/3.0", Attempts: 2, Latency: response.Latency{TotalMs: 1.5}}
+
+	record, err := p.newRecord(msg, map[string]string{"opencdc.collection": "users"}, []byte("{}"))
+	is.NoErr(err)
+	is.Equal(record.Topic, "responses")
+	is.Equal(string(record.Value), "{}")
+	// Messages are keyed by the request URL and the time they were produced
+	is.True(len(record.Key) > len("https://api.example.com/users-"))
+	is.Equal(string(record.Key[:len("https://api.example.com/users-")]), "https://api.example.com/users-")
+	is.True(hasHeader(record, "opencdc.collection", "users"))
+	is.True(hasHeader(record, response.AttemptsHeader, "2"))
+	is.True(hasHeader(record, response.TotalLatencyHeader, "1.5"))
+	is.True(hasHeader(record, response.ProtocolHeader, "HTTP/3.0"))
+}
+
+func TestProducerNewRecordKeyAndPartition(t *testing.T) {
+	msg := &response.Message{RequestURL: "https://api.example.com/users", Attempts: 2}
+
+	testCases := []struct {
+		name     string
+		producer Producer
+		headers  map[string]string
+		wantKey  string
+		wantPart int32
+		wantErr  bool
+	}{{
+		name:     "keyed by metadata",
+		producer: Producer{topic: "responses", keyHeader: "kafka.key"},
+		headers:  map[string]string{"kafka.key": "user-1"},
+		wantKey:  "user-1",
+	}, {
+		name:     "manual partition",
+		producer: Producer{topic: "responses", keyHeader: "kafka.key", partitionHeader: "kafka.partition"},
+		headers:  map[string]string{"kafka.key": "user-1", "kafka.partition": "3"},
+		wantKey:  "user-1",
+		wantPart: 3,
+	}, {
+		name:     "invalid partition",
+		producer: Producer{topic: "responses", partitionHeader: "kafka.partition"},
+		headers:  map[string]string{"kafka.partition": "-1"},
+		wantErr:  true,
+	}, {
+		name:     "missing partition",
+		producer: Producer{topic: "responses", partitionHeader: "kafka.partition"},
+		wantErr:  true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			record, err := tc.producer.newRecord(msg, tc.headers, []byte("{}"))
+			if tc.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(string(record.Key), tc.wantKey)
+			is.Equal(record.Partition, tc.wantPart)
+			is.True(hasHeader(record, response.AttemptsHeader, "2"))
+		})
+	}
+}
+
+func hasHeader(record *kgo.Record, key, value string) bool {
+	for _, h := range record.Headers {
+		if h.Key == key && string(h.Value) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// BenchmarkProducerNewRecord encodes a small response message and builds its
+// Kafka record, all Publish does besides producing it
+func BenchmarkProducerNewRecord(b *testing.B) {
+	p := Producer{topic: "responses"}
+	headers := map[string]string{"opencdc.collection": "users", "opencdc.createdAt": "1700000000000000000"}
+	msg := &response.Message{
+		StatusCode:      http.StatusCreated,
+		ResponseHeaders: response.FlattenHeaders(http.Header{"Content-Type": {"application/json"}}),
+		Body:            `{"id":42}`,
+		RequestURL:      "https://api.example.com/users",
+		RequestMethod:   http.MethodPost,
+		Timestamp:       time.Now(),
+		Attempts:        1,
+		Latency:         response.Latency{TTFBMs: 1.5, TotalMs: 2.25},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		err := response.Encode(msg, func(value []byte) error {
+			_, err := p.newRecord(msg, headers, value)
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
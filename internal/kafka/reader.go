@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// readIdleTimeout ends reading once no messages arrived for this long, e.g.
+// if the end offset of a partition is a transaction marker
+const readIdleTimeout = 10 * time.Second
+
+// Message is a message read from a topic
+type Message struct {
+	Partition int32
+	Offset    int64
+	Value     []byte
+}
+
+// ReadTopic reads the messages of cfg.Topic from the start of its partitions
+// up to their end when reading started, e.g. to replay a dead-letter topic.
+// It stops at the first error of fn.
+func ReadTopic(ctx context.Context, cfg Config, fn func(Message) error) error {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ClientID(cfg.ClientID),
+	}
+	connOpts, err := connectionOpts(cfg)
+	if err != nil {
+		return err
+	}
+	client, err := kgo.NewClient(append(opts, connOpts...)...)
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+	defer client.Close()
+
+	ends, err := endOffsets(ctx, client, cfg.Topic)
+	if err != nil {
+		return err
+	}
+	partitions := make(map[int32]kgo.Offset, len(ends))
+	for partition, end := range ends {
+		if end > 0 {
+			partitions[partition] = kgo.NewOffset().AtStart()
+		}
+	}
+	if len(partitions) == 0 {
+		return nil
+	}
+	client.AddConsumePartitions(map[string]map[int32]kgo.Offset{cfg.Topic: partitions})
+
+	for len(partitions) > 0 {
+		pollCtx, cancel := context.WithTimeout(ctx, readIdleTimeout)
+		fetches := client.PollFetches(pollCtx)
+		cancel()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, fetchErr := range fetches.Errors() {
+			if !errors.Is(fetchErr.Err, context.DeadlineExceeded) {
+				return fmt.Errorf("failed to read partition %d of topic %s: %w", fetchErr.Partition, fetchErr.Topic, fetchErr.Err)
+			}
+		}
+		if fetches.NumRecords() == 0 {
+			return nil
+		}
+
+		var err error
+		fetches.EachRecord(func(r *kgo.Record) {
+			if _, reading := partitions[r.Partition]; !reading || err != nil {
+				return
+			}
+			err = fn(Message{Partition: r.Partition, Offset: r.Offset, Value: r.Value})
+			if r.Offset+1 >= ends[r.Partition] {
+				delete(partitions, r.Partition)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endOffsets returns the offsets after the last message of the partitions of
+// the topic
+func endOffsets(ctx context.Context, client *kgo.Client, topic string) (map[int32]int64, error) {
+	metaTopic := kmsg.NewMetadataRequestTopic()
+	metaTopic.Topic = kmsg.StringPtr(topic)
+	metaReq := kmsg.NewPtrMetadataRequest()
+	metaReq.Topics = append(metaReq.Topics, metaTopic)
+
+	metaResp, err := metaReq.RequestWith(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request metadata of topic %s: %w", topic, err)
+	}
+	listTopic := kmsg.NewListOffsetsRequestTopic()
+	listTopic.Topic = topic
+	for _, t := range metaResp.Topics {
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			return nil, fmt.Errorf("topic %s: %w", topic, err)
+		}
+		for _, p := range t.Partitions {
+			partition := kmsg.NewListOffsetsRequestTopicPartition()
+			partition.Partition = p.Partition
+			partition.Timestamp = -1 // Latest
+			listTopic.Partitions = append(listTopic.Partitions, partition)
+		}
+	}
+
+	// The client sends the request to the leaders of the partitions
+	listReq := kmsg.NewPtrListOffsetsRequest()
+	listReq.Topics = append(listReq.Topics, listTopic)
+	listResp, err := listReq.RequestWith(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of topic %s: %w", topic, err)
+	}
+	ends := make(map[int32]int64)
+	for _, t := range listResp.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("partition %d of topic %s: %w", p.Partition, topic, err)
+			}
+			ends[p.Partition] = p.Offset
+		}
+	}
+	return ends, nil
+}
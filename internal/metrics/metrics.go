@@ -0,0 +1,98 @@
+// Package metrics exposes counters and gauges of the destination as expvar
+// JSON over HTTP, for monitoring without a metrics pipeline
+package metrics
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Path is the path metrics are served at, the path of expvar
+const Path = "/debug/vars"
+
+// Registry holds the variables of a destination. Unlike the variables of
+// expvar they aren't global, so several destinations in a process don't
+// collide.
+type Registry struct {
+	vars *expvar.Map
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{vars: new(expvar.Map).Init()}
+}
+
+// Counter registers a counter named name
+func (r *Registry) Counter(name string) *expvar.Int {
+	v := new(expvar.Int)
+	r.vars.Set(name, v)
+	return v
+}
+
+// Counters registers a set of counters named name keyed by e.g. a category
+func (r *Registry) Counters(name string) *expvar.Map {
+	v := new(expvar.Map).Init()
+	r.vars.Set(name, v)
+	return v
+}
+
+// Gauge registers a gauge named name whose value fn returns when the
+// variables are read
+func (r *Registry) Gauge(name string, fn func() any) {
+	r.vars.Set(name, expvar.Func(fn))
+}
+
+// ServeHTTP writes the variables as JSON object
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = io.WriteString(w, r.vars.String())
+}
+
+// Server serves a registry until Close
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+	done     chan struct{}
+}
+
+// Serve listens on address and serves the registry at Path
+func Serve(address string, r *Registry) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, r)
+	s := &Server{
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server, waiting for requests being served until ctx is
+// done
+func (s *Server) Close(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	<-s.done
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to stop metrics server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package nats
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/dev-in-black/connector-http/internal/response"
+)
+
+// Config holds the configuration for the NATS JetStream producer
+type Config struct {
+	URL        string
+	Subject    string
+	ClientName string
+	Username   string
+	Password   string
+	Token      string
+	TLSEnabled bool
+}
+
+// Producer publishes response messages to a JetStream subject
+type Producer struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject string
+}
+
+// NewProducer connects to the NATS servers and checks that a stream captures
+// the subject
+func NewProducer(ctx context.Context, cfg Config) (*Producer, error) {
+	opts := []nats.Option{
+		nats.Name(cfg.ClientName),
+		// Publishing fails while disconnected, reconnecting keeps retrying
+		nats.MaxReconnects(-1),
+	}
+	switch {
+	case cfg.Token != "":
+		opts = append(opts, nats.Token(cfg.Token))
+	case cfg.Username != "":
+		opts = append(opts, nats.UserInfo(cfg.Username, cfg.Password))
+	}
+	if cfg.TLSEnabled {
+		opts = append(opts, nats.Secure(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+
+	conn, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	// Messages to a subject no stream captures aren't acknowledged
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := js.StreamNameBySubject(ctx, cfg.Subject); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no JetStream stream captures subject %s: %w", cfg.Subject, err)
+	}
+
+	return &Producer{conn: conn, js: js, subject: cfg.Subject}, nil
+}
+
+// Publish publishes a response message to the subject of the producer, with
+// the record headers as NATS headers, and waits for the stream to store it
+func (p *Producer) Publish(ctx context.Context, msg *response.Message, recordHeaders map[string]string) error {
+	return response.Encode(msg, func(data []byte) error {
+		m := nats.NewMsg(p.subject)
+		// The encoder buffer is reused once Publish returns, the connection
+		// may still buffer the message
+		m.Data = append([]byte(nil), data...)
+		response.EachHeader(msg, recordHeaders, m.Header.Set)
+
+		if _, err := p.js.PublishMsg(ctx, m); err != nil {
+			return fmt.Errorf("failed to publish message to NATS: %w", err)
+		}
+		return nil
+	})
+}
+
+// Close drains the connection
+func (p *Producer) Close() {
+	if p.conn != nil {
+		_ = p.conn.Drain()
+	}
+}
@@ -0,0 +1,98 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+)
+
+// Decode decodes a JSON or XML response body by its Content-Type into a value
+// that serializes to structured JSON: JSON bodies are kept as they are, XML
+// bodies become objects. It reports false for other bodies and bodies that
+// don't parse as their Content-Type, which are published as text.
+func Decode(contentType string, body []byte) (any, bool) {
+	if len(body) == 0 {
+		return nil, false
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if json.Valid(body) {
+			return json.RawMessage(body), true
+		}
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		if v, err := decodeXML(body); err == nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// decodeXML converts an XML document into an object of its root element.
+// Elements become objects of their attributes, prefixed with @, and child
+// elements, repeated children arrays. Text of elements with attributes or
+// children is kept as #text, elements with text only become strings.
+func decodeXML(body []byte) (map[string]any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("no root element")
+		} else if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			v, err := decodeXMLElement(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{start.Name.Local: v}, nil
+		}
+	}
+}
+
+// decodeXMLElement decodes the element started by start up to its end
+func decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	fields := make(map[string]any)
+	for _, attr := range start.Attr {
+		fields["@"+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+			name := tok.Name.Local
+			switch existing := fields[name].(type) {
+			case nil:
+				fields[name] = child
+			case []any:
+				fields[name] = append(existing, child)
+			default:
+				fields[name] = []any{existing, child}
+			}
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			if len(fields) == 0 {
+				return value, nil
+			}
+			if value != "" {
+				fields["#text"] = value
+			}
+			return fields, nil
+		}
+	}
+}
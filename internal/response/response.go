@@ -0,0 +1,146 @@
+// Package response holds the messages HTTP responses are published as to
+// message brokers
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Publisher publishes response messages to a message broker
+type Publisher interface {
+	// Publish publishes the message with the record headers as message
+	// headers, returning once the broker accepted it
+	Publish(ctx context.Context, msg *Message, recordHeaders map[string]string) error
+	// Close releases the connection to the broker
+	Close()
+}
+
+// Message represents the HTTP response to be published
+type Message struct {
+	StatusCode      int               `json:"status_code"`
+	Protocol        string            `json:"protocol,omitempty"` // e.g. HTTP/1.1, HTTP/2.0 or HTTP/3.0
+	ResponseHeaders map[string]string `json:"response_headers"`
+	Body            any               `json:"body"`                    // String, or the value of Decode
+	BodyEncoding    string            `json:"body_encoding,omitempty"` // base64 for binary bodies
+	BodyTruncated   bool              `json:"body_truncated,omitempty"`
+	BodySize        int               `json:"body_size,omitempty"`   // Size of bodies beyond the limit
+	BodySHA256      string            `json:"body_sha256,omitempty"` // Hash of bodies replaced by it
+	RequestURL      string            `json:"request_url"`
+	RequestMethod   string            `json:"request_method"`
+	RequestID       string            `json:"request_id,omitempty"`
+	Timestamp       time.Time         `json:"timestamp"`
+	Attempts        int               `json:"attempts"`
+	Latency         Latency           `json:"latency"`
+	OriginalRecord  json.RawMessage   `json:"original_record,omitempty"`
+
+	// The record the request was sent for, unset for batches
+	RecordPosition  string `json:"record_position,omitempty"` // Base64-encoded unless UTF-8
+	RecordKey       any    `json:"record_key,omitempty"`      // Object of structured keys
+	RecordOperation string `json:"record_operation,omitempty"`
+}
+
+// Latency is the latency breakdown of the request in milliseconds. DNS,
+// connect, TLS and TTFB are of the last attempt, total spans all attempts.
+type Latency struct {
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	TLSMs     float64 `json:"tls_ms"`
+	TTFBMs    float64 `json:"ttfb_ms"`
+	TotalMs   float64 `json:"total_ms"`
+}
+
+// FlattenHeaders converts HTTP response headers to the first value of each
+// for JSON serialization
+func FlattenHeaders(headers map[string][]string) map[string]string {
+	flat := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if len(values) > 0 {
+			flat[key] = values[0] // Take first value for simplicity
+		}
+	}
+	return flat
+}
+
+// Headers added to every message besides the record headers
+const (
+	AttemptsHeader     = "http.attempts"
+	TotalLatencyHeader = "http.latency.total_ms"
+	ProtocolHeader     = "http.protocol" // Unless the protocol is unknown
+)
+
+// Headers added to the messages of a record's request, structured keys are
+// JSON
+const (
+	RecordPositionHeader  = "http.record.position"
+	RecordKeyHeader       = "http.record.key"
+	RecordOperationHeader = "http.record.operation"
+)
+
+// EachHeader calls fn with the record headers and the headers added to every
+// message
+func EachHeader(msg *Message, recordHeaders map[string]string, fn func(key, value string)) {
+	for key, value := range recordHeaders {
+		fn(key, value)
+	}
+	fn(AttemptsHeader, strconv.Itoa(msg.Attempts))
+	fn(TotalLatencyHeader, strconv.FormatFloat(msg.Latency.TotalMs, 'f', -1, 64))
+	if msg.Protocol != "" {
+		fn(ProtocolHeader, msg.Protocol)
+	}
+	if msg.RecordPosition != "" {
+		fn(RecordPositionHeader, msg.RecordPosition)
+	}
+	switch key := msg.RecordKey.(type) {
+	case nil:
+	case string:
+		fn(RecordKeyHeader, key)
+	default:
+		if data, err := json.Marshal(key); err == nil {
+			fn(RecordKeyHeader, string(data))
+		}
+	}
+	if msg.RecordOperation != "" {
+		fn(RecordOperationHeader, msg.RecordOperation)
+	}
+}
+
+// encoder encodes messages into a reusable buffer
+type encoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// maxPooledMessageSize bounds the buffers kept for reuse, so a single large
+// response doesn't pin its memory
+const maxPooledMessageSize = 1 << 20
+
+// encoders are reused across messages
+var encoders = sync.Pool{
+	New: func() any {
+		e := &encoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		return e
+	},
+}
+
+// Encode serializes the message to JSON and calls fn with it. The data is
+// only valid until fn returns.
+func Encode(msg *Message, fn func(data []byte) error) error {
+	e := encoders.Get().(*encoder)
+	defer func() {
+		if e.buf.Cap() <= maxPooledMessageSize {
+			encoders.Put(e)
+		}
+	}()
+	e.buf.Reset()
+	if err := e.enc.Encode(msg); err != nil {
+		return fmt.Errorf("failed to marshal response message: %w", err)
+	}
+	return fn(bytes.TrimSuffix(e.buf.Bytes(), []byte("\n")))
+}
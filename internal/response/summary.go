@@ -0,0 +1,20 @@
+package response
+
+import "time"
+
+// BatchSummary summarizes the requests of a batch of records, published
+// besides the messages of the responses for lightweight monitoring
+type BatchSummary struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Records       int       `json:"records"`
+	Succeeded     int       `json:"succeeded"` // Records acked
+	Failed        int       `json:"failed"`    // Records not acked
+	FirstPosition string    `json:"first_position,omitempty"`
+	LastPosition  string    `json:"last_position,omitempty"`
+	Requests      int       `json:"requests"`
+	// Statuses counts the requests by the status of their last response,
+	// "error" for requests without response
+	Statuses         map[string]int `json:"statuses"`
+	DurationMs       float64        `json:"duration_ms"`        // Time the batch took
+	RequestLatencyMs float64        `json:"request_latency_ms"` // Sum of the latencies of the requests
+}
@@ -0,0 +1,218 @@
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+const (
+	// k8sPrefix references a key of a Kubernetes Secret: k8s-secret:namespace/name/key
+	k8sPrefix = "k8s-secret:"
+	// awsPrefix references an AWS Secrets Manager secret: aws-sm:arn-or-name#field
+	awsPrefix = "aws-sm:"
+
+	// k8sServiceAccountDir holds the credentials of the pod's service account
+	k8sServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+)
+
+// IsReference reports whether value references a secret instead of being a literal
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, k8sPrefix) || strings.HasPrefix(value, awsPrefix)
+}
+
+// Resolver resolves secret references from Kubernetes Secrets and AWS Secrets
+// Manager. Clients are created on first use.
+type Resolver struct {
+	mu        sync.Mutex
+	k8sClient *http.Client
+	k8sHost   string
+	awsConfig *aws.Config
+}
+
+// NewResolver creates a new secret resolver
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+// Resolve returns the secret referenced by value, or value itself if it is not a reference
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, k8sPrefix):
+		return r.resolveK8s(ctx, strings.TrimPrefix(value, k8sPrefix))
+	case strings.HasPrefix(value, awsPrefix):
+		return r.resolveAWS(ctx, strings.TrimPrefix(value, awsPrefix))
+	default:
+		return value, nil
+	}
+}
+
+// resolveK8s reads a key of a Secret through the API server, authenticated
+// with the pod's service account
+func (r *Resolver) resolveK8s(ctx context.Context, ref string) (string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", fmt.Errorf("invalid Kubernetes secret reference %q (must be %snamespace/name/key)", ref, k8sPrefix)
+	}
+	namespace, name, key := parts[0], parts[1], parts[2]
+
+	client, host, err := r.k8s()
+	if err != nil {
+		return "", err
+	}
+
+	// Projected service account tokens are rotated, read it for every request
+	token, err := os.ReadFile(k8sServiceAccountDir + "token")
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	u := fmt.Sprintf("https://%s/api/v1/namespaces/%s/secrets/%s", host, url.PathEscape(namespace), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Kubernetes secret %s/%s: %w", namespace, name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read Kubernetes secret %s/%s: %w", namespace, name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get Kubernetes secret %s/%s: status %d", namespace, name, resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("failed to parse Kubernetes secret %s/%s: %w", namespace, name, err)
+	}
+	encoded, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in Kubernetes secret %s/%s", key, namespace, name)
+	}
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid value of key %q in Kubernetes secret %s/%s: %w", key, namespace, name, err)
+	}
+	return string(value), nil
+}
+
+// k8s returns the in-cluster API server client
+func (r *Resolver) k8s() (*http.Client, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.k8sClient != nil {
+		return r.k8sClient, r.k8sHost, nil
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, "", fmt.Errorf("Kubernetes secrets can only be resolved in a cluster (KUBERNETES_SERVICE_HOST is not set)")
+	}
+
+	ca, err := os.ReadFile(k8sServiceAccountDir + "ca.crt")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, "", fmt.Errorf("invalid cluster CA")
+	}
+
+	r.k8sClient = &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+	r.k8sHost = net.JoinHostPort(host, port)
+	return r.k8sClient, r.k8sHost, nil
+}
+
+// resolveAWS reads a secret from AWS Secrets Manager. With a field, the secret
+// string is parsed as JSON and the field's value is returned.
+func (r *Resolver) resolveAWS(ctx context.Context, ref string) (string, error) {
+	id, field, _ := strings.Cut(ref, "#")
+	if id == "" {
+		return "", fmt.Errorf("invalid AWS secret reference %q (must be %sarn-or-name#field)", ref, awsPrefix)
+	}
+
+	cfg, err := r.aws(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		// Secrets in other regions are referenced by ARN: arn:aws:secretsmanager:region:account:secret:name
+		if arn := strings.Split(id, ":"); len(arn) >= 7 && arn[0] == "arn" {
+			o.Region = arn[3]
+		}
+	})
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get AWS secret %s: %w", id, err)
+	}
+
+	var value string
+	switch {
+	case out.SecretString != nil:
+		value = *out.SecretString
+	default:
+		value = string(out.SecretBinary)
+	}
+	if field == "" {
+		return value, nil
+	}
+
+	path, err := jsonpath.Parse(field)
+	if err != nil {
+		return "", fmt.Errorf("invalid field %q of AWS secret %s: %w", field, id, err)
+	}
+	v, ok := path.Lookup([]byte(value))
+	if !ok {
+		return "", fmt.Errorf("field %q not found in AWS secret %s", field, id)
+	}
+	return jsonpath.Stringify(v), nil
+}
+
+// aws returns the AWS configuration from the default credential chain
+func (r *Resolver) aws(ctx context.Context) (aws.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.awsConfig != nil {
+		return *r.awsConfig, nil
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	r.awsConfig = &cfg
+	return cfg, nil
+}
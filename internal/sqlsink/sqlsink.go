@@ -0,0 +1,295 @@
+// Package sqlsink writes the outcomes of requests to a SQL database table in
+// batches
+package sqlsink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Database drivers, registered as pgx and sqlite
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Drivers of the databases rows are written to
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Outcomes of the requests of rows
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// maxPendingBatches bounds the rows kept while the database is unavailable,
+// older rows are dropped beyond it
+const maxPendingBatches = 10
+
+// Config holds the configuration of the writer
+type Config struct {
+	Driver        string // postgres or sqlite
+	DSN           string
+	Table         string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Row is the outcome of a record's request
+type Row struct {
+	Time       time.Time
+	Outcome    string // success or failure
+	StatusCode int    // 0 for requests without response
+	Action     string
+	Category   string
+	Error      string
+	RequestID  string
+	Method     string
+	URL        string
+	Attempts   int
+	LatencyMs  float64
+	Body       string
+	Headers    map[string]string // Response headers
+	Metadata   map[string]string // Record metadata
+}
+
+// columns are the columns rows are inserted into, in the order of Row.values
+var columns = []string{
+	"time", "outcome", "status_code", "action", "category", "error", "request_id",
+	"method", "url", "attempts", "latency_ms", "body", "headers", "metadata",
+}
+
+// values returns the values of the columns of the row
+func (r Row) values() ([]any, error) {
+	headers, err := json.Marshal(r.Headers)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := json.Marshal(r.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return []any{
+		r.Time.UTC(), r.Outcome, r.StatusCode, r.Action, r.Category, r.Error, r.RequestID,
+		r.Method, r.URL, r.Attempts, r.LatencyMs, r.Body, string(headers), string(metadata),
+	}, nil
+}
+
+// dialect holds the SQL differences of a driver
+type dialect struct {
+	driverName string // Name the driver is registered as
+	createSQL  string // Table definition, %s is the table
+	// placeholder returns the placeholder of the n-th parameter, from 1
+	placeholder func(n int) string
+}
+
+var dialects = map[string]dialect{
+	DriverPostgres: {
+		driverName: "pgx",
+		createSQL: `CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL PRIMARY KEY,
+	time TIMESTAMPTZ NOT NULL,
+	outcome TEXT NOT NULL,
+	status_code INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	category TEXT NOT NULL,
+	error TEXT NOT NULL,
+	request_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	url TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	latency_ms DOUBLE PRECISION NOT NULL,
+	body TEXT NOT NULL,
+	headers JSONB NOT NULL,
+	metadata JSONB NOT NULL
+)`,
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	},
+	DriverSQLite: {
+		driverName: "sqlite",
+		createSQL: `CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	time TIMESTAMP NOT NULL,
+	outcome TEXT NOT NULL,
+	status_code INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	category TEXT NOT NULL,
+	error TEXT NOT NULL,
+	request_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	url TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	latency_ms REAL NOT NULL,
+	body TEXT NOT NULL,
+	headers TEXT NOT NULL,
+	metadata TEXT NOT NULL
+)`,
+		placeholder: func(int) string { return "?" },
+	},
+}
+
+// tablePattern matches table names, optionally qualified by a schema
+var tablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// CheckTable checks that table is a valid, unquoted table name
+func CheckTable(table string) error {
+	if !tablePattern.MatchString(table) {
+		return fmt.Errorf("%q is not a table name of letters, digits and underscores, optionally qualified by a schema", table)
+	}
+	return nil
+}
+
+// Writer buffers rows and inserts them into the table in batches, once a
+// batch is full, the oldest row waited for the flush interval, or on Flush
+type Writer struct {
+	db        *sql.DB
+	dialect   dialect
+	table     string
+	batchSize int
+	interval  time.Duration
+
+	mu      sync.Mutex
+	pending []Row
+	oldest  time.Time // When the oldest pending row was added
+	dropped int       // Rows dropped since the last successful flush
+}
+
+// Open connects to the database and creates the table if it doesn't exist
+func Open(ctx context.Context, cfg Config) (*Writer, error) {
+	d, ok := dialects[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported driver %s", cfg.Driver)
+	}
+	if err := CheckTable(cfg.Table); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(d.driverName, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(d.createSQL, cfg.Table)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create table %s: %w", cfg.Table, err)
+	}
+	return &Writer{
+		db:        db,
+		dialect:   d,
+		table:     cfg.Table,
+		batchSize: cfg.BatchSize,
+		interval:  cfg.FlushInterval,
+	}, nil
+}
+
+// Add buffers a row, flushing the pending rows if the batch is full or the
+// flush interval elapsed. A failed flush keeps the rows for the next one.
+func (w *Writer) Add(ctx context.Context, row Row) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) == 0 {
+		w.oldest = time.Now()
+	}
+	w.pending = append(w.pending, row)
+	if len(w.pending) < w.batchSize && time.Since(w.oldest) < w.interval {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+// Flush inserts the pending rows
+func (w *Writer) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush(ctx)
+}
+
+// flush inserts the pending rows in batches in a transaction, the caller
+// holds mu
+func (w *Writer) flush(ctx context.Context) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	if err := w.insert(ctx, w.pending); err != nil {
+		// The oldest rows are dropped while the database is unavailable
+		if limit := maxPendingBatches * w.batchSize; len(w.pending) > limit {
+			excess := len(w.pending) - limit
+			w.pending = append(w.pending[:0], w.pending[excess:]...)
+			w.dropped += excess
+		}
+		if w.dropped > 0 {
+			return fmt.Errorf("%w (%d rows dropped)", err, w.dropped)
+		}
+		return err
+	}
+	w.pending = w.pending[:0]
+	w.dropped = 0
+	return nil
+}
+
+// insert inserts the rows with a statement per batch
+func (w *Writer) insert(ctx context.Context, rows []Row) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // No-op after Commit
+
+	for start := 0; start < len(rows); start += w.batchSize {
+		batch := rows[start:min(start+w.batchSize, len(rows))]
+		query, args, err := w.insertStatement(batch)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to insert %d rows into %s: %w", len(batch), w.table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %d rows: %w", len(rows), err)
+	}
+	return nil
+}
+
+// insertStatement returns the multi-row INSERT statement of the rows and its
+// parameters
+func (w *Writer) insertStatement(rows []Row) (string, []any, error) {
+	var query strings.Builder
+	fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES ", w.table, strings.Join(columns, ", "))
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		values, err := row.values()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to encode row: %w", err)
+		}
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteByte('(')
+		for j := range values {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString(w.dialect.placeholder(len(args) + j + 1))
+		}
+		query.WriteByte(')')
+		args = append(args, values...)
+	}
+	return query.String(), args, nil
+}
+
+// Close flushes the pending rows and closes the database
+func (w *Writer) Close(ctx context.Context) error {
+	err := w.Flush(ctx)
+	if closeErr := w.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
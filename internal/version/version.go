@@ -0,0 +1,20 @@
+// Package version holds the version of the connector
+package version
+
+import "runtime/debug"
+
+// Version is set at build time with ldflags (see Makefile), empty keeps the
+// version of connector.yaml
+var Version = ""
+
+// Get returns the version of the connector, the module version of the build
+// if it wasn't set at build time, (devel) for builds of a checkout
+func Get() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
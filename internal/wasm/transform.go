@@ -0,0 +1,102 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Transformer runs response bodies through a WASM module. The module must
+// export its memory and the functions
+//
+//	alloc(size u32) -> ptr u32
+//	transform(ptr u32, len u32) -> u64
+//
+// where the result of transform packs the pointer of the output in the upper
+// 32 bits and its length in the lower 32 bits. A zero result signals an error.
+type Transformer struct {
+	runtime   wazero.Runtime
+	module    api.Module
+	alloc     api.Function
+	transform api.Function
+
+	// WASM modules are single threaded
+	mu sync.Mutex
+}
+
+// NewTransformer compiles and instantiates the WASM module at path
+func NewTransformer(ctx context.Context, path string) (*Transformer, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WASM module: %w", err)
+	}
+
+	runtime := wazero.NewRuntime(ctx)
+	// Modules built with TinyGo or Rust targeting WASI import these functions
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	module, err := runtime.InstantiateWithConfig(ctx, code, wazero.NewModuleConfig().
+		WithStartFunctions("_initialize").
+		WithStderr(os.Stderr))
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASM module: %w", err)
+	}
+
+	t := &Transformer{
+		runtime:   runtime,
+		module:    module,
+		alloc:     module.ExportedFunction("alloc"),
+		transform: module.ExportedFunction("transform"),
+	}
+	if t.alloc == nil || t.transform == nil || module.Memory() == nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("WASM module must export memory, alloc and transform")
+	}
+
+	return t, nil
+}
+
+// Transform passes body to the module and returns the transformed body
+func (t *Transformer) Transform(ctx context.Context, body []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	results, err := t.alloc.Call(ctx, uint64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc failed: %w", err)
+	}
+	ptr := uint32(results[0])
+
+	memory := t.module.Memory()
+	if !memory.Write(ptr, body) {
+		return nil, fmt.Errorf("alloc returned out of range pointer %d", ptr)
+	}
+
+	results, err = t.transform.Call(ctx, uint64(ptr), uint64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("transform failed: %w", err)
+	}
+	if results[0] == 0 {
+		return nil, fmt.Errorf("transform returned an error")
+	}
+
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	out, ok := memory.Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("transform returned out of range result %d+%d", outPtr, outLen)
+	}
+
+	// The view into module memory is invalidated by the next call
+	return append([]byte(nil), out...), nil
+}
+
+// Close releases the WASM runtime
+func (t *Transformer) Close(ctx context.Context) error {
+	return t.runtime.Close(ctx)
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// APIKeyAuth sends a static API key in a request header
+type APIKeyAuth struct {
+	header string
+	key    string
+}
+
+// NewAPIKeyAuth creates a new API key authenticator
+func NewAPIKeyAuth(header, key string) *APIKeyAuth {
+	return &APIKeyAuth{
+		header: header,
+		key:    key,
+	}
+}
+
+// Authenticate adds the API key header to the request
+func (a *APIKeyAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	req.Header.Set(a.header, a.key)
+	return nil
+}
+
+// Refresh does nothing for a static API key
+func (a *APIKeyAuth) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// Type returns the auth type
+func (a *APIKeyAuth) Type() string {
+	return "apikey"
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Manager handles authentication for HTTP requests
+type Manager interface {
+	// Authenticate adds authentication to the request
+	Authenticate(ctx context.Context, req *http.Request) error
+
+	// Refresh discards cached credentials and fetches new ones, e.g. after the
+	// server rejected them with 401 Unauthorized
+	Refresh(ctx context.Context) error
+
+	// Type returns the authentication type
+	Type() string
+}
+
+// Config holds authentication configuration
+type Config struct {
+	Type          string
+	BasicUsername string
+	BasicPassword string
+	BearerToken   string
+	OAuth2Config  *OAuth2Config
+
+	// API key header
+	APIKeyHeader string
+	APIKey       string
+
+	// Azure Shared Key (Storage) and master key (Cosmos DB)
+	AzureAccountName string
+	AzureAccountKey  string
+
+	// Digest
+	DigestUsername string
+	DigestPassword string
+
+	// NTLM (username may include the domain, e.g. DOMAIN\user)
+	NTLMUsername string
+	NTLMPassword string
+
+	// Negotiate (Kerberos/SPNEGO)
+	KerberosConfig *KerberosConfig
+
+	// GCP identity tokens
+	GCPAudience                  string
+	GCPImpersonateServiceAccount string
+
+	// Session cookie login
+	SessionConfig *SessionConfig
+}
+
+// OAuth2Config holds OAuth2 client credentials configuration
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+	Scopes       []string
+
+	// RefreshMargin renews tokens this long before they expire
+	RefreshMargin time.Duration
+}
+
+// NewManager creates an authentication manager based on the config. A
+// comma-separated type such as "apikey,bearer" chains several authenticators.
+func NewManager(cfg Config) (Manager, error) {
+	if strings.Contains(cfg.Type, ",") {
+		var managers []Manager
+		for _, t := range strings.Split(cfg.Type, ",") {
+			single := cfg
+			single.Type = strings.TrimSpace(t)
+			m, err := NewManager(single)
+			if err != nil {
+				return nil, err
+			}
+			managers = append(managers, m)
+		}
+		return NewChainAuth(managers...), nil
+	}
+
+	switch cfg.Type {
+	case "none":
+		return &NoneAuth{}, nil
+	case "basic":
+		if cfg.BasicUsername == "" || cfg.BasicPassword == "" {
+			return nil, fmt.Errorf("basic auth requires username and password")
+		}
+		return NewBasicAuth(cfg.BasicUsername, cfg.BasicPassword), nil
+	case "bearer":
+		if cfg.BearerToken == "" {
+			return nil, fmt.Errorf("bearer auth requires token")
+		}
+		return NewBearerAuth(cfg.BearerToken), nil
+	case "oauth2":
+		if cfg.OAuth2Config == nil {
+			return nil, fmt.Errorf("oauth2 auth requires OAuth2Config")
+		}
+		return NewOAuth2Auth(cfg.OAuth2Config)
+	case "azure-shared-key":
+		if cfg.AzureAccountName == "" || cfg.AzureAccountKey == "" {
+			return nil, fmt.Errorf("azure-shared-key auth requires account name and key")
+		}
+		return NewAzureSharedKeyAuth(cfg.AzureAccountName, cfg.AzureAccountKey)
+	case "azure-cosmos":
+		if cfg.AzureAccountKey == "" {
+			return nil, fmt.Errorf("azure-cosmos auth requires key")
+		}
+		return NewAzureCosmosAuth(cfg.AzureAccountKey)
+	case "apikey":
+		if cfg.APIKeyHeader == "" || cfg.APIKey == "" {
+			return nil, fmt.Errorf("apikey auth requires header and key")
+		}
+		return NewAPIKeyAuth(cfg.APIKeyHeader, cfg.APIKey), nil
+	case "digest":
+		if cfg.DigestUsername == "" || cfg.DigestPassword == "" {
+			return nil, fmt.Errorf("digest auth requires username and password")
+		}
+		return NewDigestAuth(cfg.DigestUsername, cfg.DigestPassword), nil
+	case "ntlm":
+		if cfg.NTLMUsername == "" || cfg.NTLMPassword == "" {
+			return nil, fmt.Errorf("ntlm auth requires username and password")
+		}
+		return NewNTLMAuth(cfg.NTLMUsername, cfg.NTLMPassword), nil
+	case "negotiate":
+		if cfg.KerberosConfig == nil {
+			return nil, fmt.Errorf("negotiate auth requires KerberosConfig")
+		}
+		return NewNegotiateAuth(cfg.KerberosConfig)
+	case "gcp-id-token":
+		if cfg.GCPAudience == "" {
+			return nil, fmt.Errorf("gcp-id-token auth requires audience")
+		}
+		return NewGCPIDTokenAuth(cfg.GCPAudience, cfg.GCPImpersonateServiceAccount), nil
+	case "session":
+		if cfg.SessionConfig == nil {
+			return nil, fmt.Errorf("session auth requires SessionConfig")
+		}
+		return NewSessionAuth(cfg.SessionConfig)
+	default:
+		return nil, fmt.Errorf("unsupported auth type: %s", cfg.Type)
+	}
+}
+
+// NoneAuth is a no-op authenticator
+type NoneAuth struct{}
+
+// Authenticate does nothing for none auth
+func (a *NoneAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	return nil
+}
+
+// Refresh does nothing for none auth
+func (a *NoneAuth) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// Type returns the auth type
+func (a *NoneAuth) Type() string {
+	return "none"
+}
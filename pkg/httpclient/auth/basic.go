@@ -25,6 +25,11 @@ func (a *BasicAuth) Authenticate(ctx context.Context, req *http.Request) error {
 	return nil
 }
 
+// Refresh does nothing for static credentials
+func (a *BasicAuth) Refresh(ctx context.Context) error {
+	return nil
+}
+
 // Type returns the auth type
 func (a *BasicAuth) Type() string {
 	return "basic"
@@ -23,6 +23,11 @@ func (a *BearerAuth) Authenticate(ctx context.Context, req *http.Request) error
 	return nil
 }
 
+// Refresh does nothing for a static token
+func (a *BearerAuth) Refresh(ctx context.Context) error {
+	return nil
+}
+
 // Type returns the auth type
 func (a *BearerAuth) Type() string {
 	return "bearer"
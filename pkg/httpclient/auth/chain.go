@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ChainAuth applies several authenticators to each request in order, e.g. an
+// API key header followed by a request signature
+type ChainAuth struct {
+	managers []Manager
+}
+
+// NewChainAuth creates a new chained authenticator
+func NewChainAuth(managers ...Manager) *ChainAuth {
+	return &ChainAuth{managers: managers}
+}
+
+// Authenticate applies every authenticator of the chain
+func (a *ChainAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	for _, m := range a.managers {
+		if err := m.Authenticate(ctx, req); err != nil {
+			return fmt.Errorf("%s: %w", m.Type(), err)
+		}
+	}
+	return nil
+}
+
+// WrapTransport wraps the transport with the handshakes of every authenticator of the chain
+func (a *ChainAuth) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	for _, m := range a.managers {
+		if wrapper, ok := m.(TransportWrapper); ok {
+			rt = wrapper.WrapTransport(rt)
+		}
+	}
+	return rt
+}
+
+// Refresh refreshes every authenticator of the chain
+func (a *ChainAuth) Refresh(ctx context.Context) error {
+	for _, m := range a.managers {
+		if err := m.Refresh(ctx); err != nil {
+			return fmt.Errorf("%s: %w", m.Type(), err)
+		}
+	}
+	return nil
+}
+
+// Type returns the auth types of the chain, comma-separated
+func (a *ChainAuth) Type() string {
+	types := make([]string, len(a.managers))
+	for i, m := range a.managers {
+		types[i] = m.Type()
+	}
+	return strings.Join(types, ",")
+}
+
+// profileKey is the context key of the auth profile selected for a request
+type profileKey struct{}
+
+// WithProfile returns a context selecting the named auth profile for requests
+func WithProfile(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, profileKey{}, name)
+}
+
+// ProfileAuth authenticates each request with the profile selected by
+// WithProfile, falling back to a default authenticator
+type ProfileAuth struct {
+	fallback Manager
+	profiles map[string]Manager
+}
+
+// NewProfileAuth creates a new authenticator selecting between named profiles
+func NewProfileAuth(fallback Manager, profiles map[string]Manager) *ProfileAuth {
+	return &ProfileAuth{fallback: fallback, profiles: profiles}
+}
+
+// Authenticate applies the selected profile to the request
+func (a *ProfileAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	m, err := a.selected(ctx)
+	if err != nil {
+		return err
+	}
+	return m.Authenticate(ctx, req)
+}
+
+// WrapTransport returns a transport performing the handshake of the profile selected by the request context
+func (a *ProfileAuth) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	wrap := func(m Manager) http.RoundTripper {
+		if wrapper, ok := m.(TransportWrapper); ok {
+			return wrapper.WrapTransport(rt)
+		}
+		return rt
+	}
+
+	t := &profileTransport{
+		auth:     a,
+		fallback: wrap(a.fallback),
+		profiles: make(map[string]http.RoundTripper, len(a.profiles)),
+	}
+	for name, m := range a.profiles {
+		t.profiles[name] = wrap(m)
+	}
+	return t
+}
+
+// Refresh refreshes the profile selected in the context
+func (a *ProfileAuth) Refresh(ctx context.Context) error {
+	m, err := a.selected(ctx)
+	if err != nil {
+		return err
+	}
+	return m.Refresh(ctx)
+}
+
+// Type returns the auth type
+func (a *ProfileAuth) Type() string {
+	return "profiles"
+}
+
+// selected returns the authenticator of the profile selected in the context
+func (a *ProfileAuth) selected(ctx context.Context) (Manager, error) {
+	name, ok := ctx.Value(profileKey{}).(string)
+	if !ok || name == "" {
+		return a.fallback, nil
+	}
+	m, ok := a.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown auth profile %q", name)
+	}
+	return m, nil
+}
+
+// profileTransport dispatches requests to the transport of their auth profile
+type profileTransport struct {
+	auth     *ProfileAuth
+	fallback http.RoundTripper
+	profiles map[string]http.RoundTripper
+}
+
+// RoundTrip sends the request over the transport of the selected profile
+func (t *profileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, _ := req.Context().Value(profileKey{}).(string)
+	if rt, ok := t.profiles[name]; ok {
+		return rt.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
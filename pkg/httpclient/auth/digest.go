@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestHashes maps the supported RFC 7616 algorithms to their hash functions
+var digestHashes = map[string]func() hash.Hash{
+	"MD5":         md5.New,
+	"SHA-256":     sha256.New,
+	"SHA-512-256": sha512.New512_256,
+}
+
+// digestChallenge is a parsed WWW-Authenticate Digest challenge
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string // Without the -sess suffix
+	session   bool
+	qop       string // Empty if the server doesn't support auth
+	userhash  bool
+	stale     bool
+}
+
+// DigestAuth implements HTTP Digest authentication (RFC 7616). The first
+// request of a nonce is answered with a challenge that the wrapped transport
+// responds to; later requests reuse the nonce with an incremented count.
+type DigestAuth struct {
+	username string
+	password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        uint32
+}
+
+// NewDigestAuth creates a new Digest authenticator
+func NewDigestAuth(username, password string) *DigestAuth {
+	return &DigestAuth{
+		username: username,
+		password: password,
+	}
+}
+
+// Authenticate adds a Digest Authorization header if a nonce is known
+func (a *DigestAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.challenge == nil {
+		return nil
+	}
+	return a.authorize(req)
+}
+
+// WrapTransport returns a transport answering Digest challenges
+func (a *DigestAuth) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &digestTransport{auth: a, next: rt}
+}
+
+// Refresh discards the cached nonce so the next request is challenged again
+func (a *DigestAuth) Refresh(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.challenge = nil
+	a.nc = 0
+	return nil
+}
+
+// Type returns the auth type
+func (a *DigestAuth) Type() string {
+	return "digest"
+}
+
+// authorize sets the Authorization header for the current challenge, the lock must be held
+func (a *DigestAuth) authorize(req *http.Request) error {
+	c := a.challenge
+	newHash := digestHashes[c.algorithm]
+	h := func(s string) string {
+		hh := newHash()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	cnonceBytes := make([]byte, 16)
+	if _, err := rand.Read(cnonceBytes); err != nil {
+		return fmt.Errorf("failed to generate cnonce: %w", err)
+	}
+	cnonce := hex.EncodeToString(cnonceBytes)
+
+	a.nc++
+	nc := fmt.Sprintf("%08x", a.nc)
+	uri := req.URL.RequestURI()
+
+	ha1 := h(a.username + ":" + c.realm + ":" + a.password)
+	if c.session {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+
+	var response string
+	if c.qop != "" {
+		response = h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+
+	username := a.username
+	if c.userhash {
+		username = h(a.username + ":" + c.realm)
+	}
+
+	algorithm := c.algorithm
+	if c.session {
+		algorithm += "-sess"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `Digest username="%s", realm="%s", uri="%s", algorithm=%s, nonce="%s"`,
+		quoteEscape(username), quoteEscape(c.realm), quoteEscape(uri), algorithm, quoteEscape(c.nonce))
+	if c.qop != "" {
+		fmt.Fprintf(&sb, `, nc=%s, cnonce="%s", qop=%s`, nc, cnonce, c.qop)
+	}
+	fmt.Fprintf(&sb, `, response="%s"`, response)
+	if c.opaque != "" {
+		fmt.Fprintf(&sb, `, opaque="%s"`, quoteEscape(c.opaque))
+	}
+	if c.userhash {
+		sb.WriteString(", userhash=true")
+	}
+
+	req.Header.Set("Authorization", sb.String())
+	return nil
+}
+
+// digestTransport answers Digest challenges by resending the request with credentials
+type digestTransport struct {
+	auth *DigestAuth
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request, responding once to a Digest challenge
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil {
+		return resp, nil
+	}
+
+	// A rejected request that already used a fresh nonce has wrong credentials
+	if strings.HasPrefix(req.Header.Get("Authorization"), "Digest ") && !challenge.stale {
+		t.auth.mu.Lock()
+		known := t.auth.challenge != nil && t.auth.challenge.nonce == challenge.nonce
+		t.auth.mu.Unlock()
+		if known {
+			return resp, nil
+		}
+	}
+
+	// The body must be replayable to resend the request
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+
+	t.auth.mu.Lock()
+	t.auth.challenge = challenge
+	t.auth.nc = 0
+	err = t.auth.authorize(retry)
+	t.auth.mu.Unlock()
+	if err != nil {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	return t.next.RoundTrip(retry)
+}
+
+// parseDigestChallenge returns the first supported Digest challenge
+func parseDigestChallenge(headers []string) *digestChallenge {
+	for _, header := range headers {
+		scheme, params, ok := strings.Cut(strings.TrimSpace(header), " ")
+		if !ok || !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		values := parseAuthParams(params)
+		c := &digestChallenge{
+			realm:     values["realm"],
+			nonce:     values["nonce"],
+			opaque:    values["opaque"],
+			algorithm: strings.ToUpper(values["algorithm"]),
+			userhash:  strings.EqualFold(values["userhash"], "true"),
+			stale:     strings.EqualFold(values["stale"], "true"),
+		}
+		if c.algorithm == "" {
+			c.algorithm = "MD5"
+		}
+		if strings.HasSuffix(c.algorithm, "-SESS") {
+			c.algorithm = strings.TrimSuffix(c.algorithm, "-SESS")
+			c.session = true
+		}
+		if _, ok := digestHashes[c.algorithm]; !ok || c.nonce == "" {
+			continue
+		}
+
+		if qop, ok := values["qop"]; ok {
+			supported := false
+			for _, q := range strings.Split(qop, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					supported = true
+				}
+			}
+			// auth-int only challenges are not supported
+			if !supported {
+				continue
+			}
+			c.qop = "auth"
+		}
+
+		return c
+	}
+	return nil
+}
+
+// parseAuthParams parses comma-separated auth-params, unquoting quoted values
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " ")
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				sb.WriteByte(rest[i])
+			}
+			value = sb.String()
+			if i < len(rest) {
+				i++
+			}
+			s = rest[i:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end == -1 {
+				end = len(rest)
+			}
+			value = strings.TrimSpace(rest[:end])
+			s = rest[end:]
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// quoteEscape escapes a value for use in a quoted-string
+func quoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
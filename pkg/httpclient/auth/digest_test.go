@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParseDigestChallenge(t *testing.T) {
+	testCases := []struct {
+		name    string
+		headers []string
+		want    *digestChallenge
+	}{{
+		name:    "RFC 7616 example",
+		headers: []string{`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`},
+		want: &digestChallenge{
+			realm:     "http-auth@example.org",
+			nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+			opaque:    "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+			algorithm: "SHA-256",
+			qop:       "auth",
+		},
+	}, {
+		name:    "MD5 by default, without qop",
+		headers: []string{`Digest realm="api", nonce="abc"`},
+		want:    &digestChallenge{realm: "api", nonce: "abc", algorithm: "MD5"},
+	}, {
+		name:    "session algorithm",
+		headers: []string{`Digest realm="api", nonce="abc", algorithm=md5-sess, qop=auth`},
+		want:    &digestChallenge{realm: "api", nonce: "abc", algorithm: "MD5", session: true, qop: "auth"},
+	}, {
+		name:    "stale and userhash",
+		headers: []string{`Digest realm="api", nonce="abc", algorithm=SHA-512-256, stale=TRUE, userhash=true`},
+		want:    &digestChallenge{realm: "api", nonce: "abc", algorithm: "SHA-512-256", stale: true, userhash: true},
+	}, {
+		name:    "escaped quotes and commas in values",
+		headers: []string{`Digest realm="a \"quoted\", realm", nonce="n,1"`},
+		want:    &digestChallenge{realm: `a "quoted", realm`, nonce: "n,1", algorithm: "MD5"},
+	}, {
+		name:    "scheme is case insensitive",
+		headers: []string{`digest REALM="api", Nonce="abc"`},
+		want:    &digestChallenge{realm: "api", nonce: "abc", algorithm: "MD5"},
+	}, {
+		name:    "first supported challenge",
+		headers: []string{`Basic realm="api"`, `Digest realm="api", nonce="abc", algorithm=SHA-1`, `Digest realm="api", nonce="def"`},
+		want:    &digestChallenge{realm: "api", nonce: "def", algorithm: "MD5"},
+	}, {
+		name:    "auth-int only",
+		headers: []string{`Digest realm="api", nonce="abc", qop="auth-int"`},
+		want:    nil,
+	}, {
+		name:    "missing nonce",
+		headers: []string{`Digest realm="api"`},
+		want:    nil,
+	}, {
+		name:    "other schemes",
+		headers: []string{`Bearer realm="api"`, `Negotiate`},
+		want:    nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(parseDigestChallenge(tc.headers), tc.want)
+		})
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params string
+		want   map[string]string
+	}{
+		{name: "empty", params: "", want: map[string]string{}},
+		{name: "token values", params: "algorithm=MD5, stale=false", want: map[string]string{"algorithm": "MD5", "stale": "false"}},
+		{name: "quoted values", params: `realm="a b", nonce="c"`, want: map[string]string{"realm": "a b", "nonce": "c"}},
+		{name: "escapes", params: `realm="a\\b\"c"`, want: map[string]string{"realm": `a\b"c`}},
+		{name: "keys lower case", params: `Realm="api"`, want: map[string]string{"realm": "api"}},
+		{name: "spaces around", params: ` realm = "api" ,nonce=abc `, want: map[string]string{"realm": "api", "nonce": "abc"}},
+		{name: "unterminated quote", params: `realm="api`, want: map[string]string{"realm": "api"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(parseAuthParams(tc.params), tc.want)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// gcpMetadataURL is the service account endpoint of the GCE metadata server
+	gcpMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/"
+	// gcpIAMCredentialsURL is the endpoint generating tokens for impersonated service accounts
+	gcpIAMCredentialsURL = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/"
+)
+
+// GCPIDTokenAuth authenticates with Google-signed identity tokens, as
+// required by Cloud Run and Cloud Functions endpoints. Tokens are obtained
+// from the metadata server, optionally impersonating another service account.
+type GCPIDTokenAuth struct {
+	source      *gcpIDTokenSource
+	tokenSource oauth2.TokenSource
+	mu          sync.RWMutex
+}
+
+// NewGCPIDTokenAuth creates a new GCP identity token authenticator for the audience
+func NewGCPIDTokenAuth(audience, impersonateServiceAccount string) *GCPIDTokenAuth {
+	src := &gcpIDTokenSource{
+		client:                    &http.Client{Timeout: 10 * time.Second},
+		audience:                  audience,
+		impersonateServiceAccount: impersonateServiceAccount,
+	}
+	return &GCPIDTokenAuth{
+		source: src,
+		// Reuse tokens until shortly before they expire
+		tokenSource: oauth2.ReuseTokenSource(nil, src),
+	}
+}
+
+// Authenticate adds the identity token as Bearer authentication to the request
+func (a *GCPIDTokenAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	a.mu.RLock()
+	tokenSource := a.tokenSource
+	a.mu.RUnlock()
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get GCP identity token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return nil
+}
+
+// Refresh fetches a new identity token, discarding the cached one
+func (a *GCPIDTokenAuth) Refresh(ctx context.Context) error {
+	token, err := a.source.Token()
+	if err != nil {
+		return fmt.Errorf("failed to refresh GCP identity token: %w", err)
+	}
+
+	a.mu.Lock()
+	a.tokenSource = oauth2.ReuseTokenSource(token, a.source)
+	a.mu.Unlock()
+	return nil
+}
+
+// Type returns the auth type
+func (a *GCPIDTokenAuth) Type() string {
+	return "gcp-id-token"
+}
+
+// gcpIDTokenSource fetches new identity tokens
+type gcpIDTokenSource struct {
+	client                    *http.Client
+	audience                  string
+	impersonateServiceAccount string
+}
+
+// Token fetches a new identity token, returning it as the access token
+func (s *gcpIDTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var idToken string
+	var err error
+	if s.impersonateServiceAccount != "" {
+		idToken, err = s.impersonatedToken(ctx)
+	} else {
+		idToken, err = s.metadata(ctx, "identity?format=full&audience="+url.QueryEscape(s.audience))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expiry, err := jwtExpiry(idToken)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: idToken, TokenType: "Bearer", Expiry: expiry}, nil
+}
+
+// impersonatedToken generates an identity token for the impersonated service
+// account, authorized by the access token of the default service account
+func (s *gcpIDTokenSource) impersonatedToken(ctx context.Context) (string, error) {
+	raw, err := s.metadata(ctx, "token")
+	if err != nil {
+		return "", err
+	}
+	var accessToken struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal([]byte(raw), &accessToken); err != nil {
+		return "", fmt.Errorf("failed to parse access token: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]any{"audience": s.audience, "includeEmail": true})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		gcpIAMCredentialsURL+url.PathEscape(s.impersonateServiceAccount)+":generateIdToken", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken.AccessToken)
+
+	body, err := s.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate identity token for %s: %w", s.impersonateServiceAccount, err)
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse identity token response: %w", err)
+	}
+	return resp.Token, nil
+}
+
+// metadata queries the default service account on the metadata server
+func (s *gcpIDTokenSource) metadata(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataURL+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	body, err := s.do(req)
+	if err != nil {
+		return "", fmt.Errorf("metadata server request failed: %w", err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// do executes the request and returns the body of a successful response
+func (s *gcpIDTokenSource) do(req *http.Request) ([]byte, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// jwtExpiry returns the expiry of a JWT without verifying its signature
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid identity token payload: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("invalid identity token claims: %w", err)
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
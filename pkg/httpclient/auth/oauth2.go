@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
@@ -12,9 +13,10 @@ import (
 
 // OAuth2Auth implements OAuth2 Client Credentials flow
 type OAuth2Auth struct {
-	config      *clientcredentials.Config
-	tokenSource oauth2.TokenSource
-	mu          sync.RWMutex
+	config        *clientcredentials.Config
+	refreshMargin time.Duration
+	tokenSource   oauth2.TokenSource
+	mu            sync.RWMutex
 }
 
 // NewOAuth2Auth creates a new OAuth2 authenticator with token caching
@@ -34,21 +36,30 @@ func NewOAuth2Auth(cfg *OAuth2Config) (*OAuth2Auth, error) {
 		Scopes:       cfg.Scopes,
 	}
 
-	// Create token source with automatic caching and refresh
-	// TokenSource is thread-safe and handles token expiration automatically
-	tokenSource := config.TokenSource(context.Background())
+	a := &OAuth2Auth{
+		config:        config,
+		refreshMargin: cfg.RefreshMargin,
+	}
+	a.tokenSource = a.newTokenSource(nil)
+
+	return a, nil
+}
 
-	return &OAuth2Auth{
-		config:      config,
-		tokenSource: tokenSource,
-	}, nil
+// newTokenSource creates a thread-safe token source that caches tokens and
+// renews them refreshMargin before they expire
+func (a *OAuth2Auth) newTokenSource(token *oauth2.Token) oauth2.TokenSource {
+	return oauth2.ReuseTokenSourceWithExpiry(token, a.config.TokenSource(context.Background()), a.refreshMargin)
 }
 
 // Authenticate adds OAuth2 Bearer token authentication to the request
 func (a *OAuth2Auth) Authenticate(ctx context.Context, req *http.Request) error {
+	a.mu.RLock()
+	tokenSource := a.tokenSource
+	a.mu.RUnlock()
+
 	// Token() is thread-safe and returns cached token if valid
 	// Automatically requests new token if expired
-	token, err := a.tokenSource.Token()
+	token, err := tokenSource.Token()
 	if err != nil {
 		return fmt.Errorf("failed to get OAuth2 token: %w", err)
 	}
@@ -58,6 +69,19 @@ func (a *OAuth2Auth) Authenticate(ctx context.Context, req *http.Request) error
 	return nil
 }
 
+// Refresh requests a new token, discarding the cached one
+func (a *OAuth2Auth) Refresh(ctx context.Context) error {
+	token, err := a.config.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh OAuth2 token: %w", err)
+	}
+
+	a.mu.Lock()
+	a.tokenSource = a.newTokenSource(token)
+	a.mu.Unlock()
+	return nil
+}
+
 // Type returns the auth type
 func (a *OAuth2Auth) Type() string {
 	return "oauth2"
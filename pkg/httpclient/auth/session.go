@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxLoginResponseSize bounds the login response body read for the success check
+const maxLoginResponseSize = 1 << 20
+
+// SessionConfig holds session cookie login configuration
+type SessionConfig struct {
+	LoginURL    string
+	Method      string
+	Body        []byte
+	ContentType string
+
+	// Jar receives the session cookies, the HTTP client sending records must
+	// use the same jar
+	Jar http.CookieJar
+
+	// Timeout is the timeout of the login request
+	Timeout time.Duration
+
+	// Validate checks the login response, nil accepts any 2xx response
+	Validate func(status int, body []byte) error
+}
+
+// SessionAuth logs in at a login endpoint whose response sets session
+// cookies. The cookies are stored in the shared jar and sent with requests by
+// the HTTP client, Refresh logs in again.
+type SessionAuth struct {
+	config SessionConfig
+	client *http.Client
+	mu     sync.Mutex // Serializes logins
+}
+
+// NewSessionAuth creates a new session cookie authenticator
+func NewSessionAuth(cfg *SessionConfig) (*SessionAuth, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("SessionConfig is required")
+	}
+	if cfg.LoginURL == "" || cfg.Jar == nil {
+		return nil, fmt.Errorf("session auth requires login URL and cookie jar")
+	}
+
+	return &SessionAuth{
+		config: *cfg,
+		client: &http.Client{
+			Jar:     cfg.Jar,
+			Timeout: cfg.Timeout,
+		},
+	}, nil
+}
+
+// Authenticate does nothing, the session cookies are added from the jar
+func (a *SessionAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	return nil
+}
+
+// Refresh logs in, replacing the session cookies in the jar
+func (a *SessionAuth) Refresh(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var body io.Reader
+	if len(a.config.Body) > 0 {
+		body = bytes.NewReader(a.config.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, a.config.Method, a.config.LoginURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create login request: %w", err)
+	}
+	if body != nil && a.config.ContentType != "" {
+		req.Header.Set("Content-Type", a.config.ContentType)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxLoginResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read login response: %w", err)
+	}
+
+	if a.config.Validate != nil {
+		if err := a.config.Validate(resp.StatusCode, respBody); err != nil {
+			return fmt.Errorf("login failed: %w", err)
+		}
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("login failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Type returns the auth type
+func (a *SessionAuth) Type() string {
+	return "session"
+}
@@ -0,0 +1,205 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BalanceConfig configures distributing requests across several endpoints,
+// e.g. the shards of an ingestion API
+type BalanceConfig struct {
+	// URLs are the endpoints. Requests to a URL starting with the first are
+	// distributed across all of them, with the rest of their URL kept. Fewer
+	// than two URLs disable load balancing.
+	URLs []string
+	// Weights are the relative shares of the endpoints, in the order of URLs.
+	// nil distributes requests evenly.
+	Weights []int
+	// BreakerThreshold is the number of consecutive failures, connection
+	// failures or 5xx responses, that open the circuit breaker of an
+	// endpoint. 0 disables the breakers.
+	BreakerThreshold int
+	// BreakerOpenDuration is how long an open breaker keeps requests away
+	// from its endpoint before a single trial request is let through
+	BreakerOpenDuration time.Duration
+	// OnBreaker is called when the breaker of an endpoint opens or closes,
+	// e.g. to log it
+	OnBreaker func(url string, open bool, reason error)
+}
+
+// EndpointStats are the counters of an endpoint of a load-balanced client
+type EndpointStats struct {
+	URL      string
+	Requests int64         // Requests sent
+	Failures int64         // Connection failures and 5xx responses
+	Latency  time.Duration // Total duration of the requests
+	Open     bool          // Whether the breaker is open
+	Opened   int64         // How often the breaker opened
+}
+
+// endpoint is the state of an endpoint of a balancer
+type endpoint struct {
+	weight  int
+	current int // Smooth weighted round-robin counter
+
+	failures  int       // Consecutive failures
+	openUntil time.Time // Zero while the breaker is closed
+	trial     bool      // Whether the trial request of a half-open breaker is in flight
+
+	stats EndpointStats
+}
+
+// balancer distributes requests across endpoints by weight, skipping those
+// whose breaker is open
+type balancer struct {
+	config BalanceConfig
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+}
+
+// newBalancer returns the balancer of a client, nil if disabled
+func newBalancer(cfg BalanceConfig) *balancer {
+	if len(cfg.URLs) < 2 {
+		return nil
+	}
+	b := &balancer{config: cfg}
+	for i, u := range cfg.URLs {
+		weight := 1
+		if i < len(cfg.Weights) && cfg.Weights[i] > 0 {
+			weight = cfg.Weights[i]
+		}
+		b.endpoints = append(b.endpoints, &endpoint{weight: weight, stats: EndpointStats{URL: u}})
+	}
+	return b
+}
+
+// pick selects the endpoint of the next request by smooth weighted
+// round-robin among the endpoints whose breaker lets it through. If all
+// breakers are open, the one closing first is used.
+func (b *balancer) pick() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	best, total := -1, 0
+	for i, e := range b.endpoints {
+		if !e.available(now) {
+			continue
+		}
+		e.current += e.weight
+		total += e.weight
+		if best < 0 || e.current > b.endpoints[best].current {
+			best = i
+		}
+	}
+	if best < 0 {
+		for i, e := range b.endpoints {
+			if best < 0 || e.openUntil.Before(b.endpoints[best].openUntil) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	e := b.endpoints[best]
+	e.current -= total
+	if !e.openUntil.IsZero() {
+		e.trial = true
+	}
+	return best
+}
+
+// available reports whether the breaker of the endpoint lets a request
+// through: it is closed, or half-open without trial in flight
+func (e *endpoint) available(now time.Time) bool {
+	if e.openUntil.IsZero() {
+		return true
+	}
+	return !now.Before(e.openUntil) && !e.trial
+}
+
+// roundTrip sends the request to the next endpoint
+func (b *balancer) roundTrip(req *http.Request, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	rest, ok := strings.CutPrefix(req.URL.String(), b.config.URLs[0])
+	if !ok {
+		return do(req)
+	}
+
+	i := b.pick()
+	attempt := req
+	if i != 0 {
+		target, err := req.URL.Parse(b.config.URLs[i] + rest)
+		if err != nil {
+			b.done(i, 0, nil)
+			return nil, fmt.Errorf("invalid load balancing url: %w", err)
+		}
+		attempt = req.Clone(req.Context())
+		attempt.URL = target
+		attempt.Host = ""
+	}
+
+	start := time.Now()
+	resp, err := do(attempt)
+	var reason error
+	switch {
+	case req.Context().Err() != nil:
+	case resp != nil && resp.StatusCode >= 500:
+		reason = fmt.Errorf("status %d", resp.StatusCode)
+	case resp == nil && err != nil:
+		reason = err
+	}
+	b.done(i, time.Since(start), reason)
+	return resp, err
+}
+
+// done records the outcome of a request to endpoint i and opens or closes
+// its breaker
+func (b *balancer) done(i int, latency time.Duration, reason error) {
+	b.mu.Lock()
+	e := b.endpoints[i]
+	wasOpen := !e.openUntil.IsZero()
+	e.trial = false
+	e.stats.Requests++
+	e.stats.Latency += latency
+
+	var changed bool
+	if reason == nil {
+		e.failures = 0
+		e.openUntil = time.Time{}
+		changed = wasOpen
+	} else {
+		e.stats.Failures++
+		e.failures++
+		// A failed trial opens the breaker again
+		if b.config.BreakerThreshold > 0 && (wasOpen || e.failures >= b.config.BreakerThreshold) {
+			e.openUntil = time.Now().Add(b.config.BreakerOpenDuration)
+			if !wasOpen {
+				e.stats.Opened++
+				changed = true
+			}
+		}
+	}
+	open := !e.openUntil.IsZero()
+	b.mu.Unlock()
+
+	if changed && b.config.OnBreaker != nil {
+		b.config.OnBreaker(b.config.URLs[i], open, reason)
+	}
+}
+
+// stats returns the counters of the endpoints
+func (b *balancer) stats() []EndpointStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := make([]EndpointStats, len(b.endpoints))
+	for i, e := range b.endpoints {
+		stats[i] = e.stats
+		stats[i].Open = !e.openUntil.IsZero()
+	}
+	return stats
+}
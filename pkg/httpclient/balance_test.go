@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestBalancerDistribution(t *testing.T) {
+	testCases := []struct {
+		name    string
+		urls    int
+		weights []int
+		want    []int // Requests per endpoint out of 120
+	}{
+		{name: "even", urls: 3, want: []int{40, 40, 40}},
+		{name: "weighted", urls: 2, weights: []int{3, 1}, want: []int{90, 30}},
+		{name: "missing weights are 1", urls: 3, weights: []int{4}, want: []int{80, 20, 20}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			urls := make([]string, tc.urls)
+			for i := range urls {
+				urls[i] = "https://api.example.com"
+			}
+			b := newBalancer(BalanceConfig{URLs: urls, Weights: tc.weights})
+
+			got := make([]int, tc.urls)
+			for range 120 {
+				got[b.pick()]++
+			}
+			is.Equal(got, tc.want)
+		})
+	}
+}
+
+func TestBalancerInterleavesEndpoints(t *testing.T) {
+	is := is.New(t)
+	b := newBalancer(BalanceConfig{URLs: []string{"a", "b", "c"}, Weights: []int{5, 1, 1}})
+
+	// Smooth weighted round-robin doesn't send bursts to the heaviest endpoint
+	var got []int
+	for range 7 {
+		got = append(got, b.pick())
+	}
+	is.Equal(got, []int{0, 0, 1, 0, 2, 0, 0})
+}
+
+func TestBalancerSkipsUnhealthyEndpoints(t *testing.T) {
+	is := is.New(t)
+	var requests [3]atomic.Int32
+	urls := make([]string, 3)
+	for i := range urls {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests[i].Add(1)
+			if i == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		t.Cleanup(srv.Close)
+		urls[i] = srv.URL
+	}
+
+	var opened []string
+	b := newBalancer(BalanceConfig{
+		URLs:                urls,
+		BreakerThreshold:    2,
+		BreakerOpenDuration: time.Minute,
+		OnBreaker: func(url string, open bool, _ error) {
+			if open {
+				opened = append(opened, url)
+			}
+		},
+	})
+
+	for range 30 {
+		req, err := http.NewRequest(http.MethodGet, urls[0]+"/users", nil)
+		is.NoErr(err)
+		resp, err := b.roundTrip(req, http.DefaultClient.Do)
+		is.NoErr(err)
+		resp.Body.Close()
+	}
+
+	// The failing endpoint gets requests until its breaker opens
+	is.Equal(requests[1].Load(), int32(2))
+	is.Equal(requests[0].Load()+requests[2].Load(), int32(28))
+	is.Equal(opened, []string{urls[1]})
+	stats := b.stats()
+	is.True(stats[1].Open)
+	is.Equal(stats[1].Failures, int64(2))
+}
+
+func TestBalancerBreakerTrial(t *testing.T) {
+	is := is.New(t)
+	var events []bool
+	b := newBalancer(BalanceConfig{
+		URLs:                []string{"a", "b"},
+		BreakerThreshold:    1,
+		BreakerOpenDuration: 50 * time.Millisecond,
+		OnBreaker:           func(_ string, open bool, _ error) { events = append(events, open) },
+	})
+
+	b.done(1, 0, errors.New("connection refused"))
+	for range 4 {
+		is.Equal(b.pick(), 0)
+	}
+
+	// Once the breaker is half-open a single trial request is let through
+	time.Sleep(60 * time.Millisecond)
+	picked := 0
+	for range 4 {
+		if b.pick() == 1 {
+			picked++
+		}
+	}
+	is.Equal(picked, 1)
+
+	// The successful trial closes the breaker
+	b.done(1, 0, nil)
+	is.Equal(events, []bool{true, false})
+	is.True(!b.stats()[1].Open)
+}
@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// Exchange is a captured request attempt and its response
+type Exchange struct {
+	Time     time.Time         `json:"time"`
+	Request  CapturedRequest   `json:"request"`
+	Response *CapturedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// CapturedRequest is a request as sent, with headers and authentication applied
+type CapturedRequest struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Headers       http.Header `json:"headers"`
+	Body          string      `json:"body"`
+	BodyEncoding  string      `json:"bodyEncoding,omitempty"` // BodyEncodingBase64 for binary bodies
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+// CapturedResponse is a received response
+type CapturedResponse struct {
+	Status        int         `json:"status"`
+	Headers       http.Header `json:"headers"`
+	Body          string      `json:"body"`
+	BodyEncoding  string      `json:"bodyEncoding,omitempty"` // BodyEncodingBase64 for binary bodies
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+// BodyEncodingBase64 marks bodies that aren't UTF-8 text, kept base64-encoded
+// so they survive JSON
+const BodyEncodingBase64 = "base64"
+
+// EncodeBody returns a body as a string, base64-encoded with its encoding
+// BodyEncodingBase64 unless it is UTF-8 text
+func EncodeBody(data []byte) (body, encoding string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return base64.StdEncoding.EncodeToString(data), BodyEncodingBase64
+}
+
+// Capturer receives the exchanges of requests sent with WithCapture. It owns
+// the exchange and may modify it.
+type Capturer func(ctx context.Context, ex *Exchange)
+
+// captureKey is the context key marking requests to capture
+type captureKey struct{}
+
+// WithCapture returns a context whose requests are passed to the capturer of
+// the client, e.g. for a sampled record
+func WithCapture(ctx context.Context) context.Context {
+	return context.WithValue(ctx, captureKey{}, true)
+}
+
+// captureFromContext reports whether WithCapture marked the context
+func captureFromContext(ctx context.Context) bool {
+	capture, _ := ctx.Value(captureKey{}).(bool)
+	return capture
+}
+
+// captureRequest captures the request as sent, bodies are cut at maxBody bytes
+func captureRequest(req *http.Request, body []byte, maxBody int) *Exchange {
+	reqBody, truncated := truncate(body, maxBody)
+	ex := &Exchange{
+		Time: time.Now(),
+		Request: CapturedRequest{
+			Method:        req.Method,
+			URL:           req.URL.String(),
+			Headers:       req.Header.Clone(),
+			BodyTruncated: truncated,
+		},
+	}
+	ex.Request.Body, ex.Request.BodyEncoding = EncodeBody(reqBody)
+	return ex
+}
+
+// captureResponse adds the response to the exchange. The captured part of the
+// body is read ahead and put back, so the caller reads the full body.
+func (ex *Exchange) captureResponse(resp *http.Response, maxBody int) {
+	captured := &CapturedResponse{
+		Status:  resp.StatusCode,
+		Headers: resp.Header.Clone(),
+	}
+	ex.Response = captured
+	if resp.Body == nil {
+		return
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBody)+1))
+	resp.Body = &prefixedBody{
+		Reader:     io.MultiReader(bytes.NewReader(prefix), resp.Body),
+		ReadCloser: resp.Body,
+	}
+	if err != nil {
+		ex.Error = "failed to read response body: " + err.Error()
+	}
+	body, truncated := truncate(prefix, maxBody)
+	captured.Body, captured.BodyEncoding = EncodeBody(body)
+	captured.BodyTruncated = truncated
+}
+
+// truncate cuts data at max bytes, before a UTF-8 character it would split
+func truncate(data []byte, max int) ([]byte, bool) {
+	if len(data) <= max {
+		return data, false
+	}
+	cut := data[:max]
+	for i := 1; i < utf8.UTFMax && i <= len(cut); i++ {
+		if utf8.RuneStart(cut[len(cut)-i]) {
+			if !utf8.FullRune(cut[len(cut)-i:]) {
+				cut = cut[:len(cut)-i]
+			}
+			break
+		}
+	}
+	return cut, true
+}
+
+// prefixedBody is a response body whose beginning was read ahead
+type prefixedBody struct {
+	io.Reader
+	io.ReadCloser
+}
+
+// Read reads the read-ahead bytes, then the rest of the body
+func (b *prefixedBody) Read(p []byte) (int, error) {
+	return b.Reader.Read(p)
+}
@@ -0,0 +1,512 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/dev-in-black/connector-http/pkg/httpclient/auth"
+)
+
+// ErrRequestBodyTooLarge is returned when a request body exceeds the configured limit
+var ErrRequestBodyTooLarge = errors.New("request body too large")
+
+// ErrResponseBodyTooLarge is returned when reading a response body beyond the configured limit
+var ErrResponseBodyTooLarge = errors.New("response body too large")
+
+// Config holds HTTP client configuration
+type Config struct {
+	Timeout             time.Duration
+	TimeoutPerMB        time.Duration // Added to Timeout per MiB of request body, 0 disables it
+	MaxTimeout          time.Duration // Caps the scaled timeout, 0 means no cap
+	MaxIdleConns        int
+	MaxConnsPerHost     int    // Connections per host, idle or in use, 0 means unlimited
+	MaxRequestBodySize  int64  // 0 means unlimited
+	MaxBytesPerSecond   int64  // Bytes per second of all request bodies, 0 means unlimited
+	MaxResponseBodySize int64  // 0 means unlimited
+	UserAgent           string // User-Agent of requests unless headers set one, empty keeps Go's
+	Accept              string // Accept header of requests unless headers set one, empty sends none
+
+	// DisableDecompression keeps encoded response bodies as received, by
+	// default requests accept gzip, deflate and zstd and responses are
+	// decompressed
+	DisableDecompression bool
+
+	// Transport tuning
+	ForceAttemptHTTP2     bool
+	DisableKeepAlives     bool
+	KeepAlive             time.Duration
+	IdleConnTimeout       time.Duration
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	ExpectContinueTimeout time.Duration
+	DNSCacheTTL           time.Duration       // 0 disables the DNS cache
+	HostAliases           map[string][]string // Addresses host names are dialed at instead of resolving them
+	DNSServer             string              // host:port of the name server used instead of the system resolver
+	IPFamily              string              // ipv4 or ipv6 to only connect over that family, empty for both
+	LocalAddr             net.IP              // Address connections are made from, nil to let the system choose
+	ProxyFromEnvironment  bool                // Send requests through the proxy of HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	HTTP3                 bool                // Send HTTPS requests over HTTP/3, falling back to HTTP/2 or HTTP/1.1
+
+	// Redirect configures how redirects are followed
+	Redirect RedirectConfig
+
+	// Guard restricts the hosts and addresses requests may be sent to
+	Guard GuardConfig
+
+	// CoolDown pauses the requests to hosts that keep answering 429 or 503
+	CoolDown CoolDownConfig
+
+	// Failover sends requests to the first healthy of several endpoints
+	Failover FailoverConfig
+
+	// Balance distributes requests across several endpoints
+	Balance BalanceConfig
+
+	// CookieJar stores cookies of responses and sends them with later
+	// requests, nil disables cookies
+	CookieJar http.CookieJar
+
+	// Capture receives requests sent with WithCapture and their responses,
+	// with bodies cut at CaptureBodySize bytes
+	Capture         Capturer
+	CaptureBodySize int
+
+	// ContentType is the Content-Type of request bodies, defaults to application/json
+	ContentType string
+
+	// UnixSocketPath routes all connections to a Unix domain socket
+	UnixSocketPath string
+
+	// RequestBuilder customizes each request after headers and authentication are applied
+	RequestBuilder RequestBuilder
+
+	// Signing signs request bodies if the sign interceptor is enabled
+	Signing SigningConfig
+
+	// Logger receives every request if the logging interceptor is enabled
+	Logger RequestLogger
+
+	// Auditor receives every request if the audit interceptor is enabled
+	Auditor Auditor
+
+	// Interceptors are the names of the interceptors applied to each request,
+	// the first is the outermost. nil means DefaultInterceptors.
+	Interceptors []string
+
+	// Middlewares wrap the transport, they see every request sent, including
+	// redirects and the requests of authentication handshakes
+	Middlewares []Middleware
+
+	// Transport replaces the HTTP transport, e.g. to deliver requests over
+	// another protocol. The transport tuning settings don't apply to it.
+	Transport http.RoundTripper
+}
+
+// unixScheme is the URL scheme for targets exposed over a Unix domain socket
+const unixScheme = "unix://"
+
+// UnixSocketFromURL returns the socket path of a unix:///path/to.sock URL
+func UnixSocketFromURL(url string) (string, bool) {
+	if !strings.HasPrefix(url, unixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, unixScheme), true
+}
+
+// Client wraps an HTTP client with authentication and header management
+type Client struct {
+	config        Config
+	transport     *http.Transport
+	h3            *h3Transport // nil unless requests are sent over HTTP/3
+	pool          *connPool
+	bandwidth     *rate.Limiter // Bytes per second of request bodies, nil without limit
+	guard         *urlGuard     // nil without restrictions
+	coolDown      *coolDown     // nil without cool-down
+	failover      *failover     // nil without failover
+	balancer      *balancer     // nil without load balancing
+	staticHeaders map[string]string
+	envHeaders    map[string]string
+
+	mu    sync.RWMutex // Guards the interceptor chain, which holds the auth manager
+	chain http.RoundTripper
+}
+
+// NewClient creates a new HTTP client with the given configuration
+func NewClient(cfg Config, authMgr auth.Manager, staticHeaders, envHeaders map[string]string) *Client {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	// Invalid allowlists are reported by CheckAllowlist, here they fail closed
+	var guard *urlGuard
+	if cfg.Guard.enabled() {
+		var err error
+		if guard, err = newURLGuard(cfg.Guard); err != nil {
+			guard = &urlGuard{config: cfg.Guard}
+		}
+		dialer.ControlContext = guard.control
+	}
+	if cfg.LocalAddr != nil && cfg.UnixSocketPath == "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: cfg.LocalAddr}
+	}
+	resolver := net.DefaultResolver
+	if cfg.DNSServer != "" {
+		resolver = newResolver(cfg.DNSServer, cfg.DialTimeout)
+		dialer.Resolver = resolver
+	}
+	dialContext := dialFunc(dialer.DialContext)
+	if network := familyNetwork(cfg.IPFamily); network != "" {
+		dialContext = restrictFamily(dialContext, network)
+	}
+	switch {
+	case cfg.UnixSocketPath != "":
+		// Ignore the target address and always connect to the socket
+		dialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", cfg.UnixSocketPath)
+		}
+	case cfg.DNSCacheTTL > 0:
+		dialContext = newDNSCache(resolver, cfg.DNSCacheTTL).wrap(dialContext)
+	}
+	if cfg.UnixSocketPath == "" && len(cfg.HostAliases) > 0 {
+		dialContext = newHostAliases(cfg.HostAliases).wrap(dialContext)
+	}
+	pool := newConnPool()
+	dialContext = pool.wrapDial(dialContext)
+
+	transport := &http.Transport{
+		DialContext:           dialContext,
+		ForceAttemptHTTP2:     cfg.ForceAttemptHTTP2,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: cfg.ExpectContinueTimeout,
+		// Responses are decompressed by decompressTransport
+		DisableCompression: true,
+	}
+
+	if cfg.ProxyFromEnvironment && cfg.UnixSocketPath == "" {
+		transport.Proxy = http.ProxyFromEnvironment
+		if guard != nil {
+			guard.proxy, guard.resolver = transport.Proxy, resolver
+		}
+	}
+
+	c := &Client{
+		config:        cfg,
+		transport:     transport,
+		pool:          pool,
+		guard:         guard,
+		coolDown:      newCoolDown(cfg.CoolDown),
+		failover:      newFailover(cfg.Failover),
+		balancer:      newBalancer(cfg.Balance),
+		staticHeaders: staticHeaders,
+		envHeaders:    envHeaders,
+	}
+	if cfg.MaxBytesPerSecond > 0 {
+		c.bandwidth = newBandwidthLimiter(cfg.MaxBytesPerSecond)
+	}
+	if cfg.HTTP3 && cfg.UnixSocketPath == "" {
+		c.h3 = newH3Transport(cfg, resolver, guard, poolTransport{next: transport, pool: pool})
+	}
+	c.SetAuthManager(authMgr)
+	return c
+}
+
+// SetAuthManager replaces the auth manager, e.g. after credentials were
+// rotated. Requests in flight complete with the previous one.
+func (c *Client) SetAuthManager(authMgr auth.Manager) {
+	// The guard checks requests as they reach the transport
+	middlewares := c.config.Middlewares
+	if c.guard != nil {
+		middlewares = append(slices.Clip(middlewares), c.guard.middleware)
+	}
+	var transport http.RoundTripper = poolTransport{next: c.transport, pool: c.pool}
+	if c.h3 != nil {
+		transport = c.h3
+	}
+	if c.config.Transport != nil {
+		transport = c.config.Transport
+	}
+	if c.bandwidth != nil {
+		transport = throttleTransport{next: transport, limiter: c.bandwidth}
+	}
+	if !c.config.DisableDecompression {
+		transport = decompressTransport{next: transport}
+	}
+	roundTripper := Chain(transport, middlewares...)
+
+	// Connection-based auth schemes such as NTLM handshake in the transport
+	if wrapper, ok := authMgr.(auth.TransportWrapper); ok {
+		roundTripper = wrapper.WrapTransport(roundTripper)
+	}
+
+	httpClient := &http.Client{
+		Transport:     roundTripper,
+		Jar:           c.config.CookieJar,
+		CheckRedirect: c.config.Redirect.checkRedirect,
+		Timeout:       c.config.Timeout,
+	}
+	// Streams stay open as long as their body, the timeout would cut them
+	streamClient := *httpClient
+	streamClient.Timeout = 0
+	do := func(req *http.Request) (*http.Response, error) {
+		client := httpClient
+		switch {
+		case streamFromContext(req.Context()):
+			client = &streamClient
+		case c.config.TimeoutPerMB > 0:
+			scaled := *httpClient
+			scaled.Timeout = c.config.requestTimeout(req.ContentLength)
+			client = &scaled
+		}
+		// Cool-downs are waited for outside the client, its timeout applies
+		// to the request only
+		if c.coolDown != nil {
+			if err := c.coolDown.wait(req.Context(), req.URL.Host); err != nil {
+				return nil, fmt.Errorf("request canceled while %s cools down: %w", req.URL.Host, err)
+			}
+		}
+		resp, err := client.Do(req)
+		if c.coolDown != nil {
+			c.coolDown.observe(req.URL.Host, resp)
+		}
+		return resp, err
+	}
+	send := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		var resp *http.Response
+		var err error
+		switch {
+		case c.failover != nil:
+			resp, err = c.failover.roundTrip(req, do)
+		case c.balancer != nil:
+			resp, err = c.balancer.roundTrip(req, do)
+		default:
+			resp, err = do(req)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		return resp, nil
+	})
+	chain := Chain(send, c.interceptors(authMgr)...)
+
+	c.mu.Lock()
+	c.chain = chain
+	c.mu.Unlock()
+}
+
+// requestTimeout returns the timeout of a request with a body of size bytes,
+// Timeout plus TimeoutPerMB for every MiB up to MaxTimeout
+func (cfg Config) requestTimeout(size int64) time.Duration {
+	if size <= 0 {
+		return cfg.Timeout
+	}
+	timeout := cfg.Timeout + time.Duration(float64(cfg.TimeoutPerMB)*float64(size)/(1<<20))
+	if cfg.MaxTimeout > 0 && timeout > cfg.MaxTimeout {
+		return cfg.MaxTimeout
+	}
+	return timeout
+}
+
+// EndpointStats returns the counters of the endpoints requests are
+// distributed across, nil without load balancing
+func (c *Client) EndpointStats() []EndpointStats {
+	if c.balancer == nil {
+		return nil
+	}
+	return c.balancer.stats()
+}
+
+// PoolStats returns the connections of the transport per host, nil if the
+// transport is replaced
+func (c *Client) PoolStats() []PoolStats {
+	if c.config.Transport != nil {
+		return nil
+	}
+	return c.pool.stats()
+}
+
+// CloseIdleConnections closes the connections of the client that are not in use
+func (c *Client) CloseIdleConnections() {
+	c.transport.CloseIdleConnections()
+	if c.h3 != nil {
+		c.h3.closeIdleConnections()
+	}
+}
+
+// Post sends an HTTP POST request with authentication and custom headers
+func (c *Client) Post(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	return c.do(ctx, http.MethodPost, url, body)
+}
+
+// Do sends a request with the method, authentication and custom headers
+func (c *Client) Do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	return c.do(ctx, method, url, body)
+}
+
+// Probe sends a request without body, with the same headers and authentication
+// as records, to verify that the endpoint is reachable and accepts the credentials
+func (c *Client) Probe(ctx context.Context, method, url string) (*http.Response, error) {
+	return c.do(ctx, method, url, nil)
+}
+
+// do sends a request with authentication and custom headers
+func (c *Client) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	if c.config.MaxRequestBodySize > 0 && int64(len(body)) > c.config.MaxRequestBodySize {
+		err := fmt.Errorf("%w: %d bytes exceeds maxRequestBodySize of %d bytes", ErrRequestBodyTooLarge, len(body), c.config.MaxRequestBodySize)
+		return nil, WithCategory(err, ErrValidation)
+	}
+
+	// The body is streamed from the record bytes without copying them
+	return c.roundTrip(ctx, method, url, bytes.NewReader(body))
+}
+
+// NewRequest returns the request Do sends, with the content type, the default
+// headers and the static, environment and context headers. Authentication, the
+// request builder plugin and signing are applied as it is sent.
+func (c *Client) NewRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	req, err := c.newRequest(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+	return req, nil
+}
+
+// newRequest creates a request with the content type and the default headers
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	// Unix socket targets are addressed with a placeholder host, the dialer
+	// connects to the socket
+	if _, ok := UnixSocketFromURL(url); ok {
+		target := "http://localhost/"
+		if _, query, ok := strings.Cut(url, "?"); ok {
+			target += "?" + query
+		}
+		url = target
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if streamFromContext(ctx) {
+		// Sent chunked, the length is unknown until the body is closed
+		req.ContentLength = -1
+	}
+
+	// Set content type, headers may override it
+	contentType := c.config.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
+	if c.config.Accept != "" {
+		req.Header.Set("Accept", c.config.Accept)
+	}
+	if !c.config.DisableDecompression {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return req, nil
+}
+
+// roundTrip sends a request through the interceptor chain
+func (c *Client) roundTrip(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	// Measure the phases of the request if the caller collects stats
+	stats := statsFromContext(ctx)
+	var tracer *requestTracer
+	if stats != nil {
+		tracer = &requestTracer{}
+		ctx = tracer.trace(ctx)
+	}
+
+	req, err := c.newRequest(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	chain := c.chain
+	c.mu.RUnlock()
+
+	// Headers, authentication, the request builder and capture are applied
+	// by the interceptor chain
+	resp, err := chain.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if tracer != nil {
+		stats.record(tracer.timing())
+	}
+
+	// Guard against unbounded response bodies
+	if c.config.MaxResponseBodySize > 0 && resp.Body != nil {
+		resp.Body = &limitedBody{
+			ReadCloser: resp.Body,
+			reader:     io.LimitReader(resp.Body, c.config.MaxResponseBodySize+1),
+			limit:      c.config.MaxResponseBodySize,
+		}
+	}
+
+	return resp, nil
+}
+
+// limitedBody wraps a response body and fails once more than limit bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	reader io.Reader
+	limit  int64
+	read   int64
+}
+
+// Read reads from the underlying body, returning ErrResponseBodyTooLarge when the limit is exceeded
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n, fmt.Errorf("%w: exceeds response.maxBodySize of %d bytes", ErrResponseBodyTooLarge, b.limit)
+	}
+	return n, err
+}
+
+// headersKey is the context key of headers added to a single request
+type headersKey struct{}
+
+// WithHeaders returns a context adding headers to the requests sent with it,
+// e.g. headers derived from the record. They override static and environment
+// headers, and headers added to ctx before.
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if previous := headersFromContext(ctx); len(previous) > 0 {
+		merged := make(map[string]string, len(previous)+len(headers))
+		maps.Copy(merged, previous)
+		maps.Copy(merged, headers)
+		headers = merged
+	}
+	return context.WithValue(ctx, headersKey{}, headers)
+}
+
+// headersFromContext returns the headers stored by WithHeaders
+func headersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
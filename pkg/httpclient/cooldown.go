@@ -0,0 +1,128 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CoolDownConfig configures pausing all requests to a host that keeps
+// answering 429 or 503, instead of retrying each request independently
+type CoolDownConfig struct {
+	// Threshold is the number of consecutive 429 or 503 responses of a host
+	// that start a cool-down, 0 disables it
+	Threshold int
+	// Duration is the first cool-down, doubled with every further 429 or 503
+	Duration time.Duration
+	// MaxDuration caps the cool-down, including one requested by Retry-After
+	MaxDuration time.Duration
+	// OnCoolDown is called when a host starts cooling down, e.g. to log it
+	OnCoolDown func(host string, wait time.Duration)
+}
+
+// hostCoolDown is the state of a host
+type hostCoolDown struct {
+	overloaded int       // Consecutive 429 and 503 responses
+	until      time.Time // Requests wait until then
+}
+
+// coolDown holds back the requests to hosts that are overloaded
+type coolDown struct {
+	config CoolDownConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostCoolDown
+}
+
+// newCoolDown returns the cool-down of a client, nil if disabled
+func newCoolDown(cfg CoolDownConfig) *coolDown {
+	if cfg.Threshold <= 0 {
+		return nil
+	}
+	return &coolDown{config: cfg, hosts: make(map[string]*hostCoolDown)}
+}
+
+// wait blocks until the host cooled down or ctx is done
+func (c *coolDown) wait(ctx context.Context, host string) error {
+	c.mu.Lock()
+	var until time.Time
+	if h := c.hosts[host]; h != nil {
+		until = h.until
+	}
+	c.mu.Unlock()
+
+	wait := time.Until(until)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return categorize(ctx.Err(), nil)
+	}
+}
+
+// observe updates the state of the host with the response of a request, a
+// request that failed without response leaves it unchanged
+func (c *coolDown) observe(host string, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	c.mu.Lock()
+	h := c.hosts[host]
+	if h == nil {
+		h = &hostCoolDown{}
+		c.hosts[host] = h
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		h.overloaded = 0
+		c.mu.Unlock()
+		return
+	}
+	h.overloaded++
+	if h.overloaded < c.config.Threshold {
+		c.mu.Unlock()
+		return
+	}
+
+	// Exponential in the responses beyond the threshold, unless the host
+	// asks for a longer pause
+	shift := min(h.overloaded-c.config.Threshold, 30)
+	wait := c.config.Duration << shift
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && retryAfter > wait {
+		wait = retryAfter
+	}
+	if c.config.MaxDuration > 0 && (wait > c.config.MaxDuration || wait < 0) {
+		wait = c.config.MaxDuration
+	}
+	until := time.Now().Add(wait)
+	started := until.After(h.until)
+	if started {
+		h.until = until
+	}
+	c.mu.Unlock()
+
+	if started && c.config.OnCoolDown != nil {
+		c.config.OnCoolDown(host, wait)
+	}
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+	return 0, false
+}
@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// overloaded returns a response of an overloaded host
+func overloaded(status int, retryAfter string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: make(http.Header)}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	return resp
+}
+
+// waited returns how long wait blocked for host
+func waited(t *testing.T, c *coolDown, host string) time.Duration {
+	t.Helper()
+	start := time.Now()
+	if err := c.wait(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+	return time.Since(start)
+}
+
+func TestCoolDownSkipsOverloadedHost(t *testing.T) {
+	is := is.New(t)
+	var cooled []string
+	c := newCoolDown(CoolDownConfig{
+		Threshold:  2,
+		Duration:   100 * time.Millisecond,
+		OnCoolDown: func(host string, _ time.Duration) { cooled = append(cooled, host) },
+	})
+
+	// A single overloaded response doesn't pause the host
+	c.observe("a.example.com", overloaded(http.StatusServiceUnavailable, ""))
+	is.True(waited(t, c, "a.example.com") < 50*time.Millisecond)
+
+	// Requests to the host wait once the threshold is reached, others don't
+	c.observe("a.example.com", overloaded(http.StatusTooManyRequests, ""))
+	is.Equal(cooled, []string{"a.example.com"})
+	is.True(waited(t, c, "b.example.com") < 50*time.Millisecond)
+	is.True(waited(t, c, "a.example.com") >= 50*time.Millisecond)
+
+	// After the window the host takes requests again
+	is.True(waited(t, c, "a.example.com") < 50*time.Millisecond)
+}
+
+func TestCoolDownResets(t *testing.T) {
+	is := is.New(t)
+	c := newCoolDown(CoolDownConfig{Threshold: 2, Duration: time.Minute})
+
+	// A successful response resets the consecutive count
+	c.observe("a.example.com", overloaded(http.StatusServiceUnavailable, ""))
+	c.observe("a.example.com", &http.Response{StatusCode: http.StatusOK})
+	c.observe("a.example.com", overloaded(http.StatusServiceUnavailable, ""))
+	c.observe("a.example.com", nil)
+	is.True(waited(t, c, "a.example.com") < 50*time.Millisecond)
+}
+
+func TestCoolDownDuration(t *testing.T) {
+	testCases := []struct {
+		name        string
+		responses   []*http.Response
+		maxDuration time.Duration
+		want        []time.Duration
+	}{{
+		name: "doubled",
+		responses: []*http.Response{
+			overloaded(http.StatusServiceUnavailable, ""),
+			overloaded(http.StatusServiceUnavailable, ""),
+			overloaded(http.StatusServiceUnavailable, ""),
+		},
+		want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+	}, {
+		name:      "longer retry after",
+		responses: []*http.Response{overloaded(http.StatusTooManyRequests, "30")},
+		want:      []time.Duration{30 * time.Second},
+	}, {
+		name:      "shorter retry after",
+		responses: []*http.Response{overloaded(http.StatusTooManyRequests, "0")},
+		want:      []time.Duration{time.Second},
+	}, {
+		name: "capped",
+		responses: []*http.Response{
+			overloaded(http.StatusServiceUnavailable, ""),
+			overloaded(http.StatusServiceUnavailable, ""),
+			overloaded(http.StatusTooManyRequests, "3600"),
+		},
+		maxDuration: 3 * time.Second,
+		want:        []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			var got []time.Duration
+			c := newCoolDown(CoolDownConfig{
+				Threshold:   1,
+				Duration:    time.Second,
+				MaxDuration: tc.maxDuration,
+				OnCoolDown:  func(_ string, wait time.Duration) { got = append(got, wait) },
+			})
+			for _, resp := range tc.responses {
+				c.observe("a.example.com", resp)
+			}
+			is.Equal(got, tc.want)
+		})
+	}
+}
+
+func TestCoolDownWaitCanceled(t *testing.T) {
+	is := is.New(t)
+	c := newCoolDown(CoolDownConfig{Threshold: 1, Duration: time.Minute})
+	c.observe("a.example.com", overloaded(http.StatusServiceUnavailable, ""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	is.True(c.wait(ctx, "a.example.com") != nil)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	testCases := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: "-1", want: -time.Second, wantOK: false},
+		{value: "soon", wantOK: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			is := is.New(t)
+			got, ok := parseRetryAfter(tc.value)
+			is.Equal(ok, tc.wantOK)
+			is.Equal(got, tc.want)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding is the Accept-Encoding of requests, the encodings response
+// bodies are decompressed from
+const acceptEncoding = "gzip, deflate, zstd"
+
+// decompressTransport decompresses response bodies encoded with gzip, deflate
+// or zstd, so every interceptor and the caller read them decoded
+type decompressTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request and replaces an encoded response body with its
+// decompressed content
+func (t decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "zstd":
+	default:
+		return resp, nil
+	}
+	resp.Body = &decompressedBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decompressedBody decompresses a response body. The decompressor is created
+// on the first read, so empty bodies, e.g. of HEAD requests, don't fail.
+type decompressedBody struct {
+	body     io.ReadCloser
+	encoding string
+	reader   io.Reader
+	close    func()
+	err      error
+}
+
+// Read reads decompressed bytes
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.close, b.err = newDecompressor(b.encoding, b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+// Close releases the decompressor and closes the body
+func (b *decompressedBody) Close() error {
+	if b.close != nil {
+		b.close()
+	}
+	return b.body.Close()
+}
+
+// newDecompressor returns a reader decompressing r, and a function releasing
+// it. Deflate bodies are accepted with and without the zlib wrapper.
+func newDecompressor(encoding string, r io.Reader) (io.Reader, func(), error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress gzip response: %w", err)
+		}
+		return zr, func() { _ = zr.Close() }, nil
+	case "deflate":
+		br := bufio.NewReader(r)
+		header, _ := br.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decompress deflate response: %w", err)
+			}
+			return zr, func() { _ = zr.Close() }, nil
+		}
+		fr := flate.NewReader(br)
+		return fr, func() { _ = fr.Close() }, nil
+	default:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress zstd response: %w", err)
+		}
+		return zr, zr.Close, nil
+	}
+}
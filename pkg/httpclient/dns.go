@@ -0,0 +1,136 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dialFunc matches the signature of net.Dialer.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dnsEntry holds cached addresses for a host
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// dnsCache is an in-process DNS cache with a fixed TTL
+type dnsCache struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+// newDNSCache creates a DNS cache using the given resolver
+func newDNSCache(resolver *net.Resolver, ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		resolver: resolver,
+		ttl:      ttl,
+		entries:  make(map[string]dnsEntry),
+	}
+}
+
+// lookup returns the addresses for host, resolving them if the cache entry is missing or expired
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for host %s", host)
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return addrs, nil
+}
+
+// wrap returns a dial function that resolves hosts through the cache
+func (c *dnsCache) wrap(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		// IP addresses don't need to be resolved
+		if net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		addrs, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		return dialEach(ctx, dial, network, addrs, port)
+	}
+}
+
+// dialEach tries each address until one connects
+func dialEach(ctx context.Context, dial dialFunc, network string, addrs []string, port string) (net.Conn, error) {
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// hostAliases are the addresses host names are dialed at instead of
+// resolving them, like /etc/hosts. Keys are lower case.
+type hostAliases map[string][]string
+
+// newHostAliases returns the aliases with host names in lower case
+func newHostAliases(aliases map[string][]string) hostAliases {
+	a := make(hostAliases, len(aliases))
+	for host, addrs := range aliases {
+		a[strings.ToLower(host)] = addrs
+	}
+	return a
+}
+
+// wrap returns a dial function that dials aliased hosts at their addresses,
+// other hosts are passed to dial
+func (a hostAliases) wrap(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, ok := a[strings.ToLower(host)]
+		if !ok {
+			return dial(ctx, network, addr)
+		}
+		return dialEach(ctx, dial, network, addrs, port)
+	}
+}
+
+// newResolver returns a resolver querying the name server at server, a
+// host:port, instead of the ones of the system
+func newResolver(server string, timeout time.Duration) *net.Resolver {
+	dialer := &net.Dialer{Timeout: timeout}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
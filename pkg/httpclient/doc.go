@@ -0,0 +1,23 @@
+// Package httpclient is the HTTP stack of the connector: a client applying
+// headers and authentication, a retry engine with status code rules, body
+// predicates and hooks observing its attempts, request hedging, and the auth
+// managers in the auth subpackage.
+//
+// Each request passes an ordered chain of interceptors, see
+// Config.Interceptors: headers, auth, the request builder, capture, logging
+// and audit. They run once per request, while Config.Middlewares wrap the
+// transport and see every hop of redirects.
+//
+// Errors of failed requests wrap their category, ErrAuth, ErrRateLimited,
+// ErrTimeout, ErrValidation or ErrNonRetryableStatus, see Category.
+//
+// The pieces can also be composed as round tripper middleware around any
+// transport:
+//
+//	manager, _ := auth.NewManager(auth.Config{Type: "bearer", BearerToken: token})
+//	engine := httpclient.NewRetryEngine(httpclient.RetryConfig{MaxRetries: 3, BackoffBase: time.Second, BackoffMax: 30 * time.Second, RetryOn5xx: true})
+//	client := &http.Client{Transport: httpclient.Chain(http.DefaultTransport,
+//		httpclient.Retry(engine),
+//		httpclient.Authenticate(manager),
+//	)}
+package httpclient
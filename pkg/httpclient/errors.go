@@ -0,0 +1,102 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Categories of failed requests. Errors of the client and the retry engine
+// wrap the categories that apply, so callers can tell failures apart with
+// errors.Is instead of matching messages.
+var (
+	// ErrAuth marks credentials that couldn't be obtained or were rejected
+	// with 401 or 403
+	ErrAuth = errors.New("authentication failed")
+	// ErrRateLimited marks requests answered with 429
+	ErrRateLimited = errors.New("rate limited")
+	// ErrTimeout marks requests that timed out, including the retry budget
+	ErrTimeout = errors.New("timeout")
+	// ErrValidation marks requests refused before they were sent, e.g. a
+	// body that can't be rendered or is too large
+	ErrValidation = errors.New("validation failed")
+	// ErrNonRetryableStatus marks responses whose status isn't retried
+	ErrNonRetryableStatus = errors.New("non-retryable status")
+)
+
+// categories are the error categories, the most specific first
+var categories = []error{ErrAuth, ErrRateLimited, ErrTimeout, ErrValidation, ErrNonRetryableStatus}
+
+// categorizedError adds categories to an error without changing its message
+type categorizedError struct {
+	err        error
+	categories []error
+}
+
+// Error returns the message of the wrapped error
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error and the categories
+func (e *categorizedError) Unwrap() []error {
+	return append([]error{e.err}, e.categories...)
+}
+
+// WithCategory marks err with the categories, e.g. ErrValidation, keeping its
+// message. It returns err unchanged if it is nil or has no new category.
+func WithCategory(err error, categories ...error) error {
+	if err == nil {
+		return nil
+	}
+	var added []error
+	for _, category := range categories {
+		if !errors.Is(err, category) {
+			added = append(added, category)
+		}
+	}
+	if len(added) == 0 {
+		return err
+	}
+	return &categorizedError{err: err, categories: added}
+}
+
+// Category returns the most specific category of err, nil if it has none
+func Category(err error) error {
+	for _, category := range categories {
+		if errors.Is(err, category) {
+			return category
+		}
+	}
+	return nil
+}
+
+// categorize marks the error of a failed attempt with the categories of its
+// response status, or ErrTimeout if it timed out without a response
+func categorize(err error, resp *http.Response) error {
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return WithCategory(err, ErrAuth)
+		case http.StatusTooManyRequests:
+			return WithCategory(err, ErrRateLimited)
+		case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+			return WithCategory(err, ErrTimeout)
+		}
+		return err
+	}
+	if isTimeout(err) {
+		return WithCategory(err, ErrTimeout)
+	}
+	return err
+}
+
+// isTimeout reports whether err is a deadline or a network timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,175 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverConfig configures sending requests to the first healthy of several
+// endpoints, e.g. the regions of an API
+type FailoverConfig struct {
+	// URLs are the endpoints in priority order. Requests to a URL starting
+	// with the first are sent to the first healthy one instead, with the rest
+	// of their URL kept. Fewer than two URLs disable failover.
+	URLs []string
+	// StatusCodes fail over like connection failures, e.g. 502 and 503
+	StatusCodes []int
+	// FailBackAfter is how long a failed endpoint is skipped, requests are
+	// then sent to it again
+	FailBackAfter time.Duration
+	// OnSwitch is called when requests move to another endpoint, with the
+	// failure of the previous one or nil on fail-back, e.g. to log it
+	OnSwitch func(from, to string, reason error)
+}
+
+// failover tracks the health of the endpoints of a client
+type failover struct {
+	config FailoverConfig
+
+	mu        sync.Mutex
+	downUntil []time.Time // Time each endpoint is skipped until
+	reasons   []error     // Last failure of each endpoint
+	active    int         // Endpoint of the last successful request
+}
+
+// newFailover returns the failover of a client, nil if disabled
+func newFailover(cfg FailoverConfig) *failover {
+	if len(cfg.URLs) < 2 {
+		return nil
+	}
+	return &failover{
+		config:    cfg,
+		downUntil: make([]time.Time, len(cfg.URLs)),
+		reasons:   make([]error, len(cfg.URLs)),
+	}
+}
+
+// order returns the endpoints in the order they are tried: the healthy ones
+// by priority, then the failed ones by priority as a last resort
+func (f *failover) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	order := make([]int, 0, len(f.downUntil))
+	var down []int
+	for i, until := range f.downUntil {
+		if now.Before(until) {
+			down = append(down, i)
+			continue
+		}
+		order = append(order, i)
+	}
+	return append(order, down...)
+}
+
+// roundTrip sends the request to the endpoints in order until one of them
+// doesn't fail. Requests whose body can't be resent are only sent once.
+func (f *failover) roundTrip(req *http.Request, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	rest, ok := strings.CutPrefix(req.URL.String(), f.config.URLs[0])
+	if !ok {
+		return do(req)
+	}
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	order := f.order()
+	for n, i := range order {
+		attempt, err := f.request(req, i, rest, n > 0)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := do(attempt)
+		reason := f.failure(req, resp, err)
+		if reason == nil {
+			f.succeeded(i)
+			return resp, err
+		}
+		f.failed(i, reason)
+		if n == len(order)-1 || !rewindable {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+	// Failover has at least two endpoints, the loop returns
+	return nil, fmt.Errorf("failover: no endpoint to send the request to")
+}
+
+// request returns the request for endpoint i, rest is the URL after the
+// primary endpoint
+func (f *failover) request(req *http.Request, i int, rest string, resend bool) (*http.Request, error) {
+	if i == 0 && !resend {
+		return req, nil
+	}
+	attempt := req.Clone(req.Context())
+	if i != 0 {
+		target, err := req.URL.Parse(f.config.URLs[i] + rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid failover url: %w", err)
+		}
+		attempt.URL = target
+		attempt.Host = ""
+	}
+	if resend && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		attempt.Body = body
+	}
+	return attempt, nil
+}
+
+// failure returns why an endpoint failed a request, nil if it didn't. Only
+// connection failures and the configured statuses count, a request that
+// reached the endpoint may have been processed.
+func (f *failover) failure(req *http.Request, resp *http.Response, err error) error {
+	if req.Context().Err() != nil {
+		return nil
+	}
+	if resp != nil {
+		if slices.Contains(f.config.StatusCodes, resp.StatusCode) {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return err
+	}
+	return nil
+}
+
+// failed skips endpoint i for FailBackAfter
+func (f *failover) failed(i int, reason error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.downUntil[i] = time.Now().Add(f.config.FailBackAfter)
+	f.reasons[i] = reason
+}
+
+// succeeded marks endpoint i healthy and reports if requests moved to it
+func (f *failover) succeeded(i int) {
+	f.mu.Lock()
+	f.downUntil[i] = time.Time{}
+	previous := f.active
+	f.active = i
+	// Moving to an endpoint of lower priority is a failover caused by the
+	// previous one, moving back to one of higher priority a fail-back
+	var reason error
+	if i > previous {
+		reason = f.reasons[previous]
+	}
+	f.mu.Unlock()
+
+	if previous != i && f.config.OnSwitch != nil {
+		f.config.OnSwitch(f.config.URLs[previous], f.config.URLs[i], reason)
+	}
+}
@@ -0,0 +1,163 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// failoverEndpoint answers with status and counts the requests it receives
+type failoverEndpoint struct {
+	*httptest.Server
+	status   atomic.Int32
+	requests atomic.Int32
+	path     atomic.Value
+}
+
+func newFailoverEndpoint(t *testing.T) *failoverEndpoint {
+	e := &failoverEndpoint{}
+	e.status.Store(http.StatusOK)
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.requests.Add(1)
+		e.path.Store(r.URL.Path)
+		w.WriteHeader(int(e.status.Load()))
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// switchRecorder records the switches between endpoints
+type switchRecorder struct {
+	switches []string
+}
+
+func (r *switchRecorder) onSwitch(from, to string, reason error) {
+	s := from + " -> " + to
+	if reason != nil {
+		s += ": " + reason.Error()
+	}
+	r.switches = append(r.switches, s)
+}
+
+// sendFailover sends a request with a body through f
+func sendFailover(t *testing.T, f *failover, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := f.roundTrip(req, http.DefaultClient.Do)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestFailoverSwitchesToSecondary(t *testing.T) {
+	testCases := []struct {
+		name       string
+		primary    func(*failoverEndpoint)
+		wantReason string
+	}{{
+		name:       "failover status",
+		primary:    func(e *failoverEndpoint) { e.status.Store(http.StatusServiceUnavailable) },
+		wantReason: "status 503",
+	}, {
+		name:       "connection refused",
+		primary:    func(e *failoverEndpoint) { e.Close() },
+		wantReason: "connection refused",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			primary, secondary := newFailoverEndpoint(t), newFailoverEndpoint(t)
+			var recorder switchRecorder
+			f := newFailover(FailoverConfig{
+				URLs:          []string{primary.URL, secondary.URL},
+				StatusCodes:   []int{http.StatusServiceUnavailable},
+				FailBackAfter: time.Minute,
+				OnSwitch:      recorder.onSwitch,
+			})
+			tc.primary(primary)
+
+			// The request is resent to the secondary with the rest of its URL
+			resp := sendFailover(t, f, primary.URL+"/users")
+			is.Equal(resp.StatusCode, http.StatusOK)
+			is.Equal(secondary.requests.Load(), int32(1))
+			is.Equal(secondary.path.Load(), "/users")
+			is.Equal(len(recorder.switches), 1)
+			is.True(strings.HasPrefix(recorder.switches[0], primary.URL+" -> "+secondary.URL+": "))
+			is.True(strings.Contains(recorder.switches[0], tc.wantReason))
+
+			// Later requests skip the failed primary
+			primaryRequests := primary.requests.Load()
+			sendFailover(t, f, primary.URL+"/users")
+			is.Equal(primary.requests.Load(), primaryRequests)
+			is.Equal(secondary.requests.Load(), int32(2))
+		})
+	}
+}
+
+func TestFailoverRecovers(t *testing.T) {
+	is := is.New(t)
+	primary, secondary := newFailoverEndpoint(t), newFailoverEndpoint(t)
+	var recorder switchRecorder
+	f := newFailover(FailoverConfig{
+		URLs:          []string{primary.URL, secondary.URL},
+		StatusCodes:   []int{http.StatusServiceUnavailable},
+		FailBackAfter: 50 * time.Millisecond,
+		OnSwitch:      recorder.onSwitch,
+	})
+
+	primary.status.Store(http.StatusServiceUnavailable)
+	sendFailover(t, f, primary.URL)
+	is.Equal(secondary.requests.Load(), int32(1))
+
+	// Once FailBackAfter passed the primary is tried again
+	primary.status.Store(http.StatusOK)
+	time.Sleep(100 * time.Millisecond)
+	resp := sendFailover(t, f, primary.URL)
+	is.Equal(resp.StatusCode, http.StatusOK)
+	is.Equal(primary.requests.Load(), int32(2))
+	is.Equal(secondary.requests.Load(), int32(1))
+	is.Equal(recorder.switches[len(recorder.switches)-1], secondary.URL+" -> "+primary.URL)
+}
+
+func TestFailoverAllEndpointsFail(t *testing.T) {
+	is := is.New(t)
+	primary, secondary := newFailoverEndpoint(t), newFailoverEndpoint(t)
+	primary.status.Store(http.StatusServiceUnavailable)
+	secondary.status.Store(http.StatusServiceUnavailable)
+	f := newFailover(FailoverConfig{
+		URLs:          []string{primary.URL, secondary.URL},
+		StatusCodes:   []int{http.StatusServiceUnavailable},
+		FailBackAfter: time.Minute,
+	})
+
+	// The response of the last endpoint tried is returned
+	resp := sendFailover(t, f, primary.URL)
+	is.Equal(resp.StatusCode, http.StatusServiceUnavailable)
+	is.Equal(primary.requests.Load(), int32(1))
+	is.Equal(secondary.requests.Load(), int32(1))
+}
+
+func TestFailoverNoEndpoint(t *testing.T) {
+	is := is.New(t)
+	// A failover without endpoint health, which newFailover never returns
+	f := &failover{config: FailoverConfig{URLs: []string{"https://api.example.com"}}}
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.example.com/users", nil)
+	is.NoErr(err)
+	_, err = f.roundTrip(req, func(*http.Request) (*http.Response, error) {
+		t.Fatal("request sent without endpoint")
+		return nil, nil
+	})
+	is.True(err != nil)
+}
@@ -0,0 +1,217 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrTargetNotAllowed is returned for requests to a host outside the URL
+// allowlist or to an address in a blocked network
+var ErrTargetNotAllowed = errors.New("target not allowed")
+
+// metadataAddrs are cloud metadata endpoints outside the link-local ranges
+var metadataAddrs = []netip.Addr{
+	netip.MustParseAddr("fd00:ec2::254"),   // AWS IMDS over IPv6
+	netip.MustParseAddr("100.100.100.200"), // Alibaba Cloud
+}
+
+// GuardConfig restricts the targets requests may be sent to, e.g. when the URL
+// is derived from records. It applies to every hop of redirects.
+type GuardConfig struct {
+	// Allowlist holds host patterns such as api.example.com or *.example.com,
+	// and CIDRs matched against the addresses connected to. Empty allows all hosts.
+	Allowlist []string
+	// BlockLinkLocal refuses connections to link-local and cloud metadata addresses
+	BlockLinkLocal bool
+	// BlockPrivate refuses connections to loopback, private and unique local addresses
+	BlockPrivate bool
+}
+
+// enabled reports whether the guard restricts any target
+func (cfg GuardConfig) enabled() bool {
+	return len(cfg.Allowlist) > 0 || cfg.BlockLinkLocal || cfg.BlockPrivate
+}
+
+// CheckAllowlist reports invalid entries of a URL allowlist
+func CheckAllowlist(entries []string) error {
+	_, err := newURLGuard(GuardConfig{Allowlist: entries})
+	return err
+}
+
+// urlGuard enforces a GuardConfig on requests and connections
+type urlGuard struct {
+	config   GuardConfig
+	hosts    []string // Lower case, "*." prefixed for subdomains
+	networks []netip.Prefix
+
+	// Requests sent through a proxy connect to the proxy, their target is
+	// resolved and checked before they are sent
+	proxy    func(*http.Request) (*url.URL, error)
+	resolver *net.Resolver
+}
+
+// newURLGuard parses the allowlist of the guard config
+func newURLGuard(cfg GuardConfig) (*urlGuard, error) {
+	g := &urlGuard{config: cfg}
+	for _, entry := range cfg.Allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist entry %q: %w", entry, err)
+			}
+			g.networks = append(g.networks, prefix.Masked())
+		case entry == "" || strings.ContainsAny(entry, ":@?#") && net.ParseIP(entry) == nil:
+			return nil, fmt.Errorf("invalid allowlist entry %q (must be a host name, *.domain or a CIDR)", entry)
+		case strings.Contains(strings.TrimPrefix(entry, "*."), "*"):
+			return nil, fmt.Errorf("invalid allowlist entry %q: only a leading *. wildcard is supported", entry)
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				g.networks = append(g.networks, netip.PrefixFrom(addr, addr.BitLen()))
+				continue
+			}
+			g.hosts = append(g.hosts, entry)
+		}
+	}
+	return g, nil
+}
+
+// hostAllowedKey is the context key marking requests whose host matched a
+// host pattern of the allowlist
+type hostAllowedKey struct{}
+
+// proxiedKey is the context key marking requests sent through a proxy, whose
+// target was checked before connecting to the proxy
+type proxiedKey struct{}
+
+// matchHost reports whether host matches a host pattern of the allowlist
+func (g *urlGuard) matchHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range g.hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAddr reports whether addr is in a network of the allowlist
+func (g *urlGuard) matchAddr(addr netip.Addr) bool {
+	for _, network := range g.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// middleware checks the host of every request against the allowlist. Hosts
+// that only CIDRs can allow are checked when connecting, unless the request
+// is sent through a proxy.
+func (g *urlGuard) middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		host := req.URL.Hostname()
+		if len(g.config.Allowlist) > 0 {
+			switch addr, err := netip.ParseAddr(host); {
+			case g.matchHost(host):
+				req = req.WithContext(context.WithValue(req.Context(), hostAllowedKey{}, true))
+			case err == nil && !g.matchAddr(addr.Unmap()):
+				return nil, fmt.Errorf("%w: %s is not in the URL allowlist", ErrTargetNotAllowed, host)
+			case err != nil && len(g.networks) == 0:
+				return nil, fmt.Errorf("%w: %s is not in the URL allowlist", ErrTargetNotAllowed, host)
+			}
+		}
+
+		if g.proxy == nil {
+			return next.RoundTrip(req)
+		}
+		proxyURL, err := g.proxy(req)
+		if err != nil || proxyURL == nil {
+			return next.RoundTrip(req)
+		}
+		if err := g.checkTarget(req.Context(), host); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(req.WithContext(context.WithValue(req.Context(), proxiedKey{}, true)))
+	})
+}
+
+// checkTarget resolves the host of a request sent through a proxy and checks
+// its addresses, the connection only reaches the proxy
+func (g *urlGuard) checkTarget(ctx context.Context, host string) error {
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		resolver := g.resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		if addrs, err = resolver.LookupNetIP(ctx, "ip", host); err != nil {
+			return fmt.Errorf("%w: failed to resolve %s: %w", ErrTargetNotAllowed, host, err)
+		}
+	}
+	for _, addr := range addrs {
+		if err := g.checkAddr(ctx, addr.Unmap()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// control checks the address of a connection before it is established, so
+// host names resolving to blocked addresses are caught as well. Connections
+// of proxied requests reach the proxy, their target was checked already.
+func (g *urlGuard) control(ctx context.Context, network, address string, _ syscall.RawConn) error {
+	if network == "unix" || network == "unixgram" || network == "unixpacket" {
+		return nil
+	}
+	if proxied, _ := ctx.Value(proxiedKey{}).(bool); proxied {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected address %s", ErrTargetNotAllowed, address)
+	}
+	return g.checkAddr(ctx, addrPort.Addr().Unmap())
+}
+
+// checkAddr checks an address connected to against the blocked networks and
+// the allowlist
+func (g *urlGuard) checkAddr(ctx context.Context, addr netip.Addr) error {
+	if g.config.BlockLinkLocal && (addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || isMetadataAddr(addr)) {
+		return fmt.Errorf("%w: %s is a link-local or metadata address", ErrTargetNotAllowed, addr)
+	}
+	if g.config.BlockPrivate && (addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified()) {
+		return fmt.Errorf("%w: %s is a private address", ErrTargetNotAllowed, addr)
+	}
+
+	if len(g.config.Allowlist) > 0 {
+		if allowed, _ := ctx.Value(hostAllowedKey{}).(bool); !allowed && !g.matchAddr(addr) {
+			return fmt.Errorf("%w: %s is not in the URL allowlist", ErrTargetNotAllowed, addr)
+		}
+	}
+	return nil
+}
+
+// isMetadataAddr reports whether addr is a cloud metadata endpoint
+func isMetadataAddr(addr netip.Addr) bool {
+	for _, metadata := range metadataAddrs {
+		if addr == metadata {
+			return true
+		}
+	}
+	return false
+}
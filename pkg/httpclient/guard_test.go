@@ -0,0 +1,153 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCheckAllowlist(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries []string
+		wantErr bool
+	}{
+		{name: "host", entries: []string{"api.example.com"}},
+		{name: "wildcard", entries: []string{"*.example.com"}},
+		{name: "CIDR", entries: []string{"203.0.113.0/24", "2001:db8::/32"}},
+		{name: "IP address", entries: []string{"203.0.113.7", "2001:db8::1"}},
+		{name: "empty entry", entries: []string{" "}, wantErr: true},
+		{name: "URL", entries: []string{"https://api.example.com"}, wantErr: true},
+		{name: "port", entries: []string{"api.example.com:443"}, wantErr: true},
+		{name: "inner wildcard", entries: []string{"api.*.example.com"}, wantErr: true},
+		{name: "invalid CIDR", entries: []string{"203.0.113.0/33"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			err := CheckAllowlist(tc.entries)
+			is.Equal(err != nil, tc.wantErr)
+		})
+	}
+}
+
+func TestURLGuardMatchHost(t *testing.T) {
+	g, err := newURLGuard(GuardConfig{Allowlist: []string{"API.example.com", "*.cdn.example.com", "10.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		host string
+		want bool
+	}{
+		{host: "api.example.com", want: true},
+		{host: "Api.Example.Com", want: true},
+		{host: "www.example.com", want: false},
+		{host: "img.cdn.example.com", want: true},
+		{host: "a.b.cdn.example.com", want: true},
+		{host: "cdn.example.com", want: false},
+		{host: "evilcdn.example.com", want: false},
+		{host: "api.example.com.evil.com", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(g.matchHost(tc.host), tc.want)
+		})
+	}
+}
+
+func TestURLGuardCheckAddr(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config GuardConfig
+		addr   string
+		want   bool
+	}{
+		{name: "no restrictions", config: GuardConfig{}, addr: "10.0.0.1", want: true},
+		{name: "in CIDR", config: GuardConfig{Allowlist: []string{"203.0.113.0/24"}}, addr: "203.0.113.9", want: true},
+		{name: "outside CIDR", config: GuardConfig{Allowlist: []string{"203.0.113.0/24"}}, addr: "198.51.100.1", want: false},
+		{name: "allowed IP", config: GuardConfig{Allowlist: []string{"2001:db8::1"}}, addr: "2001:db8::1", want: true},
+		{name: "private blocked", config: GuardConfig{BlockPrivate: true}, addr: "192.168.1.1", want: false},
+		{name: "loopback blocked", config: GuardConfig{BlockPrivate: true}, addr: "127.0.0.1", want: false},
+		{name: "unique local blocked", config: GuardConfig{BlockPrivate: true}, addr: "fd12::1", want: false},
+		{name: "public allowed", config: GuardConfig{BlockPrivate: true}, addr: "8.8.8.8", want: true},
+		{name: "link-local blocked", config: GuardConfig{BlockLinkLocal: true}, addr: "169.254.169.254", want: false},
+		{name: "AWS IPv6 metadata blocked", config: GuardConfig{BlockLinkLocal: true}, addr: "fd00:ec2::254", want: false},
+		{name: "Alibaba metadata blocked", config: GuardConfig{BlockLinkLocal: true}, addr: "100.100.100.200", want: false},
+		{name: "private allowed with link-local blocked", config: GuardConfig{BlockLinkLocal: true}, addr: "10.0.0.1", want: true},
+		{
+			name:   "blocked even if allowlisted",
+			config: GuardConfig{Allowlist: []string{"10.0.0.0/8"}, BlockPrivate: true},
+			addr:   "10.0.0.1",
+			want:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			g, err := newURLGuard(tc.config)
+			is.NoErr(err)
+			err = g.checkAddr(context.Background(), netip.MustParseAddr(tc.addr))
+			if tc.want {
+				is.NoErr(err)
+				return
+			}
+			is.True(errors.Is(err, ErrTargetNotAllowed))
+		})
+	}
+}
+
+func TestGuardAppliesToRedirects(t *testing.T) {
+	testCases := []struct {
+		name    string
+		host    string // Host the endpoint redirects to
+		wantErr bool
+	}{
+		{name: "allowed host", host: "localhost"},
+		{name: "refused host", host: "127.0.0.1", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+
+			var redirected bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/target" {
+					redirected = true
+					return
+				}
+				port := r.Host[strings.LastIndex(r.Host, ":"):]
+				http.Redirect(w, r, "http://"+tc.host+port+"/target", http.StatusFound)
+			}))
+			defer srv.Close()
+
+			// Only localhost is allowed, the server is reached through it
+			c := NewClient(Config{
+				Guard:        GuardConfig{Allowlist: []string{"localhost"}},
+				Interceptors: []string{InterceptorHeaders},
+			}, nil, nil, nil)
+			url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+			resp, err := c.Post(context.Background(), url, nil)
+			if tc.wantErr {
+				is.True(errors.Is(err, ErrTargetNotAllowed))
+				is.True(!redirected)
+				return
+			}
+			is.NoErr(err)
+			is.NoErr(resp.Body.Close())
+			is.True(redirected)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// h3BrokenFor is how long requests to a host HTTP/3 failed to connect to are
+// sent over HTTP/2 or HTTP/1.1 before HTTP/3 is tried again
+const h3BrokenFor = 5 * time.Minute
+
+// h3DialError is the error of connecting to a host over QUIC, the request
+// wasn't sent
+type h3DialError struct {
+	err error
+}
+
+func (e *h3DialError) Error() string { return e.err.Error() }
+func (e *h3DialError) Unwrap() error { return e.err }
+
+// h3Transport sends HTTPS requests over HTTP/3 and falls back to next, HTTP/2
+// or HTTP/1.1, for hosts that can't be connected to over QUIC. Plain HTTP
+// requests, proxied requests and requests with bodies that can't be resent
+// are sent with next.
+type h3Transport struct {
+	h3       *http3.Transport
+	next     http.RoundTripper
+	proxy    func(*http.Request) (*url.URL, error)
+	resolver *net.Resolver
+	network  string // ip, ip4 or ip6
+	aliases  hostAliases
+	local    net.IP
+	guard    *urlGuard
+
+	mu     sync.Mutex
+	broken map[string]time.Time // Hosts HTTP/3 failed for, until when they are skipped
+}
+
+// newH3Transport returns a transport sending requests over HTTP/3 with the
+// dial settings of cfg
+func newH3Transport(cfg Config, resolver *net.Resolver, guard *urlGuard, next http.RoundTripper) *h3Transport {
+	t := &h3Transport{
+		next:     next,
+		resolver: resolver,
+		network:  "ip",
+		aliases:  newHostAliases(cfg.HostAliases),
+		local:    cfg.LocalAddr,
+		guard:    guard,
+		broken:   make(map[string]time.Time),
+	}
+	switch familyNetwork(cfg.IPFamily) {
+	case "tcp4":
+		t.network = "ip4"
+	case "tcp6":
+		t.network = "ip6"
+	}
+	if cfg.ProxyFromEnvironment {
+		t.proxy = http.ProxyFromEnvironment
+	}
+	quicConfig := &quic.Config{
+		HandshakeIdleTimeout: cfg.TLSHandshakeTimeout,
+		MaxIdleTimeout:       cfg.IdleConnTimeout,
+		KeepAlivePeriod:      cfg.KeepAlive,
+	}
+	t.h3 = &http3.Transport{
+		QUICConfig: quicConfig,
+		Dial:       t.dial,
+		// Responses are decompressed by decompressTransport
+		DisableCompression: true,
+	}
+	return t
+}
+
+// RoundTrip sends the request over HTTP/3, or over HTTP/2 or HTTP/1.1 if the
+// host can't be connected to over QUIC
+func (t *h3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.usable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	resp, err := t.h3.RoundTrip(req)
+	var dialErr *h3DialError
+	if err == nil || !errors.As(err, &dialErr) || req.Context().Err() != nil {
+		return resp, err
+	}
+
+	t.mu.Lock()
+	t.broken[req.URL.Host] = time.Now().Add(h3BrokenFor)
+	t.mu.Unlock()
+
+	// The failed attempt closed the body, it is resent from the start
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resend request over HTTP/2: %w", err)
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return t.next.RoundTrip(req)
+}
+
+// usable reports whether the request is sent over HTTP/3
+func (t *h3Transport) usable(req *http.Request) bool {
+	if req.URL.Scheme != "https" {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if t.proxy != nil {
+		if proxyURL, err := t.proxy(req); err != nil || proxyURL != nil {
+			return false
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.broken[req.URL.Host]
+	if !ok {
+		return true
+	}
+	if time.Now().Before(until) {
+		return false
+	}
+	delete(t.broken, req.URL.Host)
+	return true
+}
+
+// dial connects to addr over QUIC, at its host aliases or resolved addresses
+// checked by the guard. Targets the guard refuses aren't sent over HTTP/2.
+func (t *h3Transport) dial(ctx context.Context, addr string, tlsConf *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+	conn, err := t.dialAddrs(ctx, addr, tlsConf, cfg)
+	if errors.Is(err, ErrTargetNotAllowed) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, &h3DialError{err: err}
+	}
+	return conn, nil
+}
+
+// dialAddrs connects to the first of the addresses of the host of addr that
+// accepts a QUIC connection
+func (t *h3Transport) dialAddrs(ctx context.Context, addr string, tlsConf *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %s", portStr)
+	}
+	addrs, err := t.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range addrs {
+		if t.guard != nil {
+			if err := t.guard.checkAddr(ctx, ip); err != nil {
+				return nil, err
+			}
+		}
+		conn, err := t.dialAddr(ctx, netip.AddrPortFrom(ip, uint16(port)), tlsConf, cfg)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// lookup returns the addresses of host of the IP family
+func (t *h3Transport) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else if aliases, ok := t.aliases[strings.ToLower(host)]; ok {
+		for _, alias := range aliases {
+			addr, err := netip.ParseAddr(alias)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %s of host %s", alias, host)
+			}
+			addrs = append(addrs, addr)
+		}
+	} else if addrs, err = t.resolver.LookupNetIP(ctx, t.network, host); err != nil {
+		return nil, err
+	}
+
+	family := addrs[:0]
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if t.network == "ip4" && !addr.Is4() || t.network == "ip6" && !addr.Is6() {
+			continue
+		}
+		family = append(family, addr)
+	}
+	if len(family) == 0 {
+		return nil, fmt.Errorf("no %s address of host %s", t.network, host)
+	}
+	return family, nil
+}
+
+// dialAddr connects to addr from a UDP socket of its own, closed with the
+// connection
+func (t *h3Transport) dialAddr(ctx context.Context, addr netip.AddrPort, tlsConf *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: t.local})
+	if err != nil {
+		return nil, err
+	}
+	tr := &quic.Transport{Conn: udpConn}
+	conn, err := tr.DialEarly(ctx, net.UDPAddrFromAddrPort(addr), tlsConf, cfg)
+	if err != nil {
+		tr.Close()
+		udpConn.Close()
+		return nil, err
+	}
+	go func() {
+		<-conn.Context().Done()
+		tr.Close()
+		udpConn.Close()
+	}()
+	return conn, nil
+}
+
+// closeIdleConnections closes the QUIC connections without requests in flight
+func (t *h3Transport) closeIdleConnections() {
+	t.h3.CloseIdleConnections()
+}
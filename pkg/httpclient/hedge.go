@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Hedger sends a second identical request when the first one does not
+// complete within the hedge delay and returns the first successful response.
+// It must only be used with idempotent endpoints.
+type Hedger struct {
+	delay time.Duration
+	rules StatusRules
+}
+
+// NewHedger creates a new hedger with the given latency threshold
+func NewHedger(delay time.Duration, rules StatusRules) *Hedger {
+	return &Hedger{delay: delay, rules: rules}
+}
+
+// hedgeResult is the outcome of a single hedged attempt
+type hedgeResult struct {
+	index int
+	resp  *http.Response
+	err   error
+}
+
+// Do executes fn and, if it hasn't completed after the hedge delay, a second
+// concurrent fn. The losing request is canceled.
+func (h *Hedger) Do(ctx context.Context, fn func(ctx context.Context) (*http.Response, error)) (*http.Response, error) {
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	launch := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := fn(attemptCtx)
+			results <- hedgeResult{index: index, resp: resp, err: err}
+		}()
+	}
+
+	// finish cancels every attempt except the winner and releases the
+	// responses of attempts that are still in flight
+	finish := func(winner int, inFlight int) {
+		for i, cancel := range cancels {
+			if i != winner {
+				cancel()
+			}
+		}
+		go func() {
+			for ; inFlight > 0; inFlight-- {
+				closeBody((<-results).resp)
+			}
+		}()
+	}
+
+	launch()
+	inFlight := 1
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	var failed *hedgeResult
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) == 1 {
+				inFlight++
+				launch()
+			}
+		case res := <-results:
+			inFlight--
+
+			// Return on success, or when no other attempt can succeed anymore
+			if h.isSuccess(res) || inFlight == 0 {
+				if failed != nil {
+					closeBody(failed.resp)
+				}
+				finish(res.index, inFlight)
+				return h.winner(res, cancels[res.index])
+			}
+
+			// Keep the failed result in case the other attempt fails too
+			if failed != nil {
+				closeBody(failed.resp)
+			}
+			failed = &res
+		case <-ctx.Done():
+			if failed != nil {
+				closeBody(failed.resp)
+			}
+			finish(-1, inFlight)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isSuccess reports whether a hedged attempt produced a successful response
+func (h *Hedger) isSuccess(res hedgeResult) bool {
+	if res.err != nil || res.resp == nil {
+		return false
+	}
+	switch h.rules.Classify(res.resp.StatusCode) {
+	case ActionAck, ActionIgnore:
+		return true
+	default:
+		return false
+	}
+}
+
+// winner returns the winning response, releasing its context once the body is closed
+func (h *Hedger) winner(res hedgeResult, cancel context.CancelFunc) (*http.Response, error) {
+	if res.resp == nil || res.resp.Body == nil {
+		cancel()
+		return res.resp, res.err
+	}
+	res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancel}
+	return res.resp, res.err
+}
+
+// cancelOnClose cancels the request context when the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// closeBody closes the body of a response that is not returned to the caller
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
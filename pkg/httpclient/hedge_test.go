@@ -0,0 +1,168 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// trackedBody is a response body recording whether it was closed
+type trackedBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func newTrackedResponse(status int, body string) (*http.Response, *trackedBody) {
+	b := &trackedBody{Reader: strings.NewReader(body)}
+	return &http.Response{StatusCode: status, Body: b}, b
+}
+
+// eventually waits up to a second for cond to hold
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+func TestHedgerFastResponseNotHedged(t *testing.T) {
+	is := is.New(t)
+	h := NewHedger(50*time.Millisecond, nil)
+
+	var calls atomic.Int32
+	resp, err := h.Do(context.Background(), func(context.Context) (*http.Response, error) {
+		calls.Add(1)
+		resp, _ := newTrackedResponse(http.StatusOK, "first")
+		return resp, nil
+	})
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, http.StatusOK)
+	time.Sleep(100 * time.Millisecond)
+	is.Equal(calls.Load(), int32(1))
+}
+
+func TestHedgerCancelsLosingRequest(t *testing.T) {
+	is := is.New(t)
+	h := NewHedger(10*time.Millisecond, nil)
+
+	var calls atomic.Int32
+	loserCanceled := make(chan struct{})
+	resp, err := h.Do(context.Background(), func(ctx context.Context) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			// The first request hangs until it is canceled
+			<-ctx.Done()
+			close(loserCanceled)
+			return nil, ctx.Err()
+		}
+		resp, _ := newTrackedResponse(http.StatusOK, "hedged")
+		return resp, nil
+	})
+	is.NoErr(err)
+	body, err := io.ReadAll(resp.Body)
+	is.NoErr(err)
+	is.Equal(string(body), "hedged")
+	is.NoErr(resp.Body.Close())
+
+	select {
+	case <-loserCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("losing request wasn't canceled")
+	}
+	is.Equal(calls.Load(), int32(2))
+}
+
+func TestHedgerReturnsOneResponse(t *testing.T) {
+	is := is.New(t)
+	h := NewHedger(10*time.Millisecond, nil)
+
+	// Both requests succeed, the slower one ignores the cancellation
+	var calls atomic.Int32
+	bodies := make(chan *trackedBody, 2)
+	resp, err := h.Do(context.Background(), func(context.Context) (*http.Response, error) {
+		n := calls.Add(1)
+		if n == 1 {
+			time.Sleep(30 * time.Millisecond)
+		} else {
+			time.Sleep(60 * time.Millisecond)
+		}
+		resp, body := newTrackedResponse(http.StatusOK, "")
+		bodies <- body
+		return resp, nil
+	})
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, http.StatusOK)
+
+	winner, loser := <-bodies, <-bodies
+	is.True(resp.Body != nil)
+	// The response of the losing request is released, the winner's is the caller's
+	is.True(eventually(loser.closed.Load))
+	is.True(!winner.closed.Load())
+	is.NoErr(resp.Body.Close())
+	is.True(winner.closed.Load())
+}
+
+func TestHedgerFailedRequestWaitsForHedge(t *testing.T) {
+	is := is.New(t)
+	h := NewHedger(10*time.Millisecond, nil)
+
+	var calls atomic.Int32
+	var failed *trackedBody
+	resp, err := h.Do(context.Background(), func(context.Context) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			var resp *http.Response
+			resp, failed = newTrackedResponse(http.StatusServiceUnavailable, "")
+			return resp, nil
+		}
+		time.Sleep(40 * time.Millisecond)
+		resp, _ := newTrackedResponse(http.StatusOK, "")
+		return resp, nil
+	})
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, http.StatusOK)
+	is.True(failed.closed.Load())
+}
+
+func TestHedgerBothFail(t *testing.T) {
+	is := is.New(t)
+	h := NewHedger(5*time.Millisecond, nil)
+
+	errRefused := errors.New("connection refused")
+	_, err := h.Do(context.Background(), func(context.Context) (*http.Response, error) {
+		time.Sleep(10 * time.Millisecond)
+		return nil, errRefused
+	})
+	is.True(errors.Is(err, errRefused))
+}
+
+func TestHedgerContextCanceled(t *testing.T) {
+	is := is.New(t)
+	h := NewHedger(5*time.Millisecond, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var canceled atomic.Int32
+	_, err := h.Do(ctx, func(ctx context.Context) (*http.Response, error) {
+		<-ctx.Done()
+		canceled.Add(1)
+		return nil, ctx.Err()
+	})
+	is.True(errors.Is(err, context.DeadlineExceeded))
+	is.True(eventually(func() bool { return canceled.Load() == 2 }))
+}
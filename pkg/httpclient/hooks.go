@@ -0,0 +1,94 @@
+package httpclient
+
+import (
+	"context"
+	"time"
+)
+
+// RetryHooks observe the attempts of a RetryEngine, e.g. to record metrics,
+// log retries or alert after a number of consecutive failures. Hooks are
+// called on the goroutine sending the request and should return quickly.
+type RetryHooks interface {
+	// OnAttempt is called after each attempt, n counts from 1
+	OnAttempt(ctx context.Context, n int, attempt Attempt)
+	// OnRetryScheduled is called when attempt n failed and is retried after
+	// backoff
+	OnRetryScheduled(ctx context.Context, n int, backoff time.Duration, attempt Attempt)
+	// OnGiveUp is called when the engine returns an error after n attempts,
+	// because it isn't retryable, the retries or their budget are spent, or
+	// ctx is done
+	OnGiveUp(ctx context.Context, n int, err error)
+}
+
+// RetryHookFuncs implements RetryHooks with functions, nil functions are
+// skipped
+type RetryHookFuncs struct {
+	Attempt        func(ctx context.Context, n int, attempt Attempt)
+	RetryScheduled func(ctx context.Context, n int, backoff time.Duration, attempt Attempt)
+	GiveUp         func(ctx context.Context, n int, err error)
+}
+
+// OnAttempt calls Attempt
+func (f RetryHookFuncs) OnAttempt(ctx context.Context, n int, attempt Attempt) {
+	if f.Attempt != nil {
+		f.Attempt(ctx, n, attempt)
+	}
+}
+
+// OnRetryScheduled calls RetryScheduled
+func (f RetryHookFuncs) OnRetryScheduled(ctx context.Context, n int, backoff time.Duration, attempt Attempt) {
+	if f.RetryScheduled != nil {
+		f.RetryScheduled(ctx, n, backoff, attempt)
+	}
+}
+
+// OnGiveUp calls GiveUp
+func (f RetryHookFuncs) OnGiveUp(ctx context.Context, n int, err error) {
+	if f.GiveUp != nil {
+		f.GiveUp(ctx, n, err)
+	}
+}
+
+// MultiRetryHooks returns hooks calling each of hooks in order, nil hooks are
+// skipped
+func MultiRetryHooks(hooks ...RetryHooks) RetryHooks {
+	var multi multiRetryHooks
+	for _, h := range hooks {
+		if h != nil {
+			multi = append(multi, h)
+		}
+	}
+	return multi
+}
+
+// multiRetryHooks calls several hooks in order
+type multiRetryHooks []RetryHooks
+
+func (m multiRetryHooks) OnAttempt(ctx context.Context, n int, attempt Attempt) {
+	for _, h := range m {
+		h.OnAttempt(ctx, n, attempt)
+	}
+}
+
+func (m multiRetryHooks) OnRetryScheduled(ctx context.Context, n int, backoff time.Duration, attempt Attempt) {
+	for _, h := range m {
+		h.OnRetryScheduled(ctx, n, backoff, attempt)
+	}
+}
+
+func (m multiRetryHooks) OnGiveUp(ctx context.Context, n int, err error) {
+	for _, h := range m {
+		h.OnGiveUp(ctx, n, err)
+	}
+}
+
+// statsHooks records the attempts in the stats of the request, if collected
+type statsHooks struct{}
+
+func (statsHooks) OnAttempt(ctx context.Context, _ int, attempt Attempt) {
+	statsFromContext(ctx).addAttempt(attempt)
+}
+
+func (statsHooks) OnRetryScheduled(context.Context, int, time.Duration, Attempt) {}
+
+func (statsHooks) OnGiveUp(context.Context, int, error) {}
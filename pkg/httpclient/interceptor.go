@@ -0,0 +1,246 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dev-in-black/connector-http/pkg/httpclient/auth"
+)
+
+// Names of the built-in interceptors
+const (
+	// InterceptorHeaders sets the static, environment and record headers
+	InterceptorHeaders = "headers"
+	// InterceptorAuth authenticates the request with the auth manager
+	InterceptorAuth = "auth"
+	// InterceptorRequestBuilder runs the request builder plugin
+	InterceptorRequestBuilder = "requestBuilder"
+	// InterceptorSign signs the request body with the signing secret
+	InterceptorSign = "sign"
+	// InterceptorCapture passes requests sent with WithCapture to the capturer
+	InterceptorCapture = "capture"
+	// InterceptorLogging passes every request to the request logger
+	InterceptorLogging = "logging"
+	// InterceptorAudit passes every request and its body to the auditor
+	InterceptorAudit = "audit"
+)
+
+// DefaultInterceptors is the interceptor chain of a client without
+// Config.Interceptors. The request builder sees the authenticated request, the
+// signature covers the body it built and captures show the request as sent.
+var DefaultInterceptors = []string{
+	InterceptorHeaders,
+	InterceptorAuth,
+	InterceptorRequestBuilder,
+	InterceptorSign,
+	InterceptorCapture,
+	InterceptorAudit,
+}
+
+// interceptorNames are the names of all built-in interceptors
+var interceptorNames = []string{
+	InterceptorHeaders,
+	InterceptorAuth,
+	InterceptorRequestBuilder,
+	InterceptorSign,
+	InterceptorCapture,
+	InterceptorLogging,
+	InterceptorAudit,
+}
+
+// RequestLogger receives the requests passing the logging interceptor, with
+// their response or error and how long the rest of the chain took
+type RequestLogger func(ctx context.Context, req *http.Request, resp *http.Response, err error, elapsed time.Duration)
+
+// Auditor receives the requests passing the audit interceptor with their
+// body, and their response or error
+type Auditor func(ctx context.Context, req *http.Request, body []byte, resp *http.Response, err error)
+
+// CheckInterceptors reports unknown and duplicate interceptor names
+func CheckInterceptors(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !slices.Contains(interceptorNames, name) {
+			return fmt.Errorf("unknown interceptor %q (must be one of %s)", name, strings.Join(interceptorNames, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("interceptor %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// interceptors returns the middlewares of the interceptor chain, the first is
+// the outermost. Unknown names are ignored, see CheckInterceptors.
+//
+// Interceptors run once per request, above redirects, unlike Config.Middlewares
+// which wrap the transport. They modify the request in place, it is owned by
+// the client.
+func (c *Client) interceptors(authMgr auth.Manager) []Middleware {
+	names := c.config.Interceptors
+	if names == nil {
+		names = DefaultInterceptors
+	}
+
+	var middlewares []Middleware
+	for _, name := range names {
+		switch name {
+		case InterceptorHeaders:
+			middlewares = append(middlewares, c.headersInterceptor)
+		case InterceptorAuth:
+			middlewares = append(middlewares, authInterceptor(authMgr))
+		case InterceptorRequestBuilder:
+			if c.config.RequestBuilder != nil {
+				middlewares = append(middlewares, requestBuilderInterceptor(c.config.RequestBuilder))
+			}
+		case InterceptorSign:
+			if len(c.config.Signing.Secret) > 0 {
+				middlewares = append(middlewares, signingInterceptor(c.config.Signing))
+			}
+		case InterceptorCapture:
+			if c.config.Capture != nil {
+				middlewares = append(middlewares, c.captureInterceptor)
+			}
+		case InterceptorLogging:
+			if c.config.Logger != nil {
+				middlewares = append(middlewares, loggingInterceptor(c.config.Logger))
+			}
+		case InterceptorAudit:
+			if c.config.Auditor != nil {
+				middlewares = append(middlewares, auditInterceptor(c.config.Auditor))
+			}
+		}
+	}
+	return middlewares
+}
+
+// headersInterceptor applies the static headers, then the environment headers
+// and the headers of the record, each overriding the previous ones
+func (c *Client) headersInterceptor(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		c.setHeaders(req)
+		return next.RoundTrip(req)
+	})
+}
+
+// setHeaders sets the static, environment and context headers of req
+func (c *Client) setHeaders(req *http.Request) {
+	for k, v := range c.staticHeaders {
+		req.Header.Set(k, v)
+	}
+	for k, v := range c.envHeaders {
+		req.Header.Set(k, v)
+	}
+	for k, v := range headersFromContext(req.Context()) {
+		req.Header.Set(k, v)
+	}
+}
+
+// authInterceptor authenticates requests with the auth manager
+func authInterceptor(authMgr auth.Manager) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := authMgr.Authenticate(req.Context(), req); err != nil {
+				return nil, WithCategory(fmt.Errorf("authentication failed: %w", err), ErrAuth)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// requestBuilderInterceptor lets the request builder plugin customize requests
+func requestBuilderInterceptor(builder RequestBuilder) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := builder(req.Context(), req, recordFromContext(req.Context())); err != nil {
+				return nil, fmt.Errorf("request builder failed: %w", err)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// captureInterceptor passes requests sent with WithCapture and their
+// responses to the capturer
+func (c *Client) captureInterceptor(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if !captureFromContext(ctx) {
+			return next.RoundTrip(req)
+		}
+
+		body, err := peekRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		ex := captureRequest(req, body, c.config.CaptureBodySize)
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			ex.Error = err.Error()
+			c.config.Capture(ctx, ex)
+			return nil, err
+		}
+		ex.captureResponse(resp, c.config.CaptureBodySize)
+		c.config.Capture(ctx, ex)
+		return resp, nil
+	})
+}
+
+// loggingInterceptor passes requests, their outcome and duration to the logger
+func loggingInterceptor(logger RequestLogger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			logger(req.Context(), req, resp, err, time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+// auditInterceptor passes requests, their body and outcome to the auditor
+func auditInterceptor(auditor Auditor) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, err := peekRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			auditor(req.Context(), req, body, resp, err)
+			return resp, err
+		})
+	}
+}
+
+// peekRequestBody returns the request body without consuming it. A body set
+// without GetBody, e.g. by a request builder plugin, is read and replaced.
+// Streamed bodies are not read ahead, they have no end yet.
+func peekRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody || streamFromContext(req.Context()) {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// familyNetwork returns the network connections of the IP family, ipv4 or
+// ipv6, are dialed on, empty for both families
+func familyNetwork(family string) string {
+	switch family {
+	case "ipv4":
+		return "tcp4"
+	case "ipv6":
+		return "tcp6"
+	default:
+		return ""
+	}
+}
+
+// restrictFamily returns a dial function dialing TCP connections on network
+// only, tcp4 or tcp6
+func restrictFamily(dial dialFunc, network string) dialFunc {
+	return func(ctx context.Context, n, addr string) (net.Conn, error) {
+		if n == "tcp" {
+			n = network
+		}
+		return dial(ctx, n, addr)
+	}
+}
+
+// LocalIP returns the IP address connections bound to address are made from:
+// address itself if it is an IP address, otherwise the first address of the
+// network interface named address. The address of the interface is of the
+// family, ipv4 or ipv6, or IPv4 if it has one for any family. Link-local
+// addresses are only used if the interface has no other.
+func LocalIP(address, family string) (net.IP, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip, nil
+	}
+	iface, err := net.InterfaceByName(address)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither an IP address nor a network interface: %w", address, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the addresses of %s: %w", address, err)
+	}
+
+	var best net.IP
+	rank := func(ip net.IP) int {
+		r := 0
+		if !ip.IsLinkLocalUnicast() {
+			r += 2
+		}
+		if family == "ipv6" && ip.To4() == nil || family != "ipv6" && ip.To4() != nil {
+			r++
+		}
+		return r
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP
+		if family == "ipv4" && ip.To4() == nil || family == "ipv6" && ip.To4() != nil {
+			continue
+		}
+		if best == nil || rank(ip) > rank(best) {
+			best = ip
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("network interface %s has no suitable address", address)
+	}
+	return best, nil
+}
@@ -0,0 +1,93 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dev-in-black/connector-http/pkg/httpclient/auth"
+)
+
+// Middleware wraps a round tripper, e.g. to add headers, authenticate or
+// retry requests
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps rt with the middlewares. The first middleware is the outermost,
+// it sees requests first and responses last.
+func Chain(rt http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+// Headers returns a middleware setting headers on every request
+func Headers(headers map[string]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Authenticate returns a middleware authenticating every request with the
+// auth manager. Connection-based schemes such as NTLM wrap the transport below.
+func Authenticate(m auth.Manager) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if wrapper, ok := m.(auth.TransportWrapper); ok {
+			next = wrapper.WrapTransport(next)
+		}
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			if err := m.Authenticate(req.Context(), req); err != nil {
+				return nil, fmt.Errorf("authentication failed: %w", err)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Retry returns a middleware retrying requests with the retry engine. Requests
+// with a body must be rewindable (GetBody set, as for requests created with
+// http.NewRequest from a bytes or strings reader). A response is returned even
+// if the retry engine gave up, so its status can be inspected; an error is
+// only returned without response.
+func Retry(engine *RetryEngine) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return nil, fmt.Errorf("request body can't be resent for retries (GetBody is nil)")
+			}
+
+			first := true
+			resp, err := engine.Do(req.Context(), func() (*http.Response, error) {
+				attempt := req
+				if !first && req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, fmt.Errorf("failed to rewind request body: %w", err)
+					}
+					attempt = req.Clone(req.Context())
+					attempt.Body = body
+				}
+				first = false
+				return next.RoundTrip(attempt)
+			})
+			if resp != nil {
+				return resp, nil
+			}
+			return nil, err
+		})
+	}
+}
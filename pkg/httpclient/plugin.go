@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"plugin"
+)
+
+// requestBuilderSymbol is the name of the function a request builder plugin must export
+const requestBuilderSymbol = "BuildRequest"
+
+// RequestBuilder customizes an outgoing request for a record. It may change
+// the method, URL, headers and body of the request. The record is passed in
+// its OpenCDC JSON form.
+type RequestBuilder func(ctx context.Context, req *http.Request, record []byte) error
+
+// LoadRequestBuilder opens a Go plugin exporting
+//
+//	func BuildRequest(ctx context.Context, req *http.Request, record []byte) error
+//
+// Plugins require a connector built with cgo on Linux, FreeBSD or macOS.
+func LoadRequestBuilder(path string) (RequestBuilder, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin %s: %w", path, err)
+	}
+
+	sym, err := p.Lookup(requestBuilderSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s does not export %s: %w", path, requestBuilderSymbol, err)
+	}
+
+	fn, ok := sym.(func(context.Context, *http.Request, []byte) error)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: %s has type %T, want func(context.Context, *http.Request, []byte) error", path, requestBuilderSymbol, sym)
+	}
+
+	return fn, nil
+}
+
+// recordKey is the context key of the record passed to the request builder
+type recordKey struct{}
+
+// WithRecord returns a context carrying the serialized record the request is sent for
+func WithRecord(ctx context.Context, record []byte) context.Context {
+	return context.WithValue(ctx, recordKey{}, record)
+}
+
+// recordFromContext returns the serialized record stored by WithRecord
+func recordFromContext(ctx context.Context) []byte {
+	record, _ := ctx.Value(recordKey{}).([]byte)
+	return record
+}
@@ -0,0 +1,154 @@
+package httpclient
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PoolStats are the connections of the transport to a host. The transport
+// doesn't expose its pool, the connections are counted as they are dialed and
+// closed and requests as they use them.
+type PoolStats struct {
+	Host     string        // host:port of the connections
+	Open     int64         // Connections open
+	InUse    int64         // Requests sent or reading their response body
+	Idle     int64         // Open connections not in use
+	Waits    int64         // Requests that found no idle connection, they dialed one or waited for one
+	WaitTime time.Duration // Total time requests waited for a connection
+}
+
+// connPool counts the connections and requests of a transport per host
+type connPool struct {
+	mu    sync.Mutex
+	hosts map[string]*PoolStats
+}
+
+// newConnPool returns an empty pool
+func newConnPool() *connPool {
+	return &connPool{hosts: make(map[string]*PoolStats)}
+}
+
+// update changes the stats of host with fn
+func (p *connPool) update(host string, fn func(s *PoolStats)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.hosts[host]
+	if !ok {
+		s = &PoolStats{Host: host}
+		p.hosts[host] = s
+	}
+	fn(s)
+}
+
+// wrapDial counts the connections dialed until they are closed
+func (p *connPool) wrapDial(next dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		p.update(addr, func(s *PoolStats) { s.Open++ })
+		return &poolConn{Conn: conn, closed: func() {
+			p.update(addr, func(s *PoolStats) { s.Open-- })
+		}}, nil
+	}
+}
+
+// stats returns the stats of every host connected to, sorted by host
+func (p *connPool) stats() []PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]PoolStats, 0, len(p.hosts))
+	for _, s := range p.hosts {
+		stat := *s
+		stat.Idle = max(stat.Open-stat.InUse, 0)
+		stats = append(stats, stat)
+	}
+	slices.SortFunc(stats, func(a, b PoolStats) int { return cmp.Compare(a.Host, b.Host) })
+	return stats
+}
+
+// poolConn reports when a connection of the pool is closed
+type poolConn struct {
+	net.Conn
+	once   sync.Once
+	closed func()
+}
+
+// Close closes the connection
+func (c *poolConn) Close() error {
+	c.once.Do(c.closed)
+	return c.Conn.Close()
+}
+
+// poolTransport counts the requests using the connections of a pool, until
+// their response body is closed
+type poolTransport struct {
+	next http.RoundTripper
+	pool *connPool
+}
+
+// RoundTrip sends the request, counting it as in use
+func (t poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := canonicalAddr(req.URL)
+	t.pool.update(host, func(s *PoolStats) { s.InUse++ })
+	var once sync.Once
+	release := func() {
+		once.Do(func() { t.pool.update(host, func(s *PoolStats) { s.InUse-- }) })
+	}
+
+	var getConn time.Time
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GetConn: func(string) { getConn = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.WasIdle || getConn.IsZero() {
+				return
+			}
+			waited := time.Since(getConn)
+			t.pool.update(host, func(s *PoolStats) {
+				s.Waits++
+				s.WaitTime += waited
+			})
+		},
+	})
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+	resp.Body = &pooledBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// pooledBody releases the request using a connection once it is closed
+type pooledBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the body
+func (b *pooledBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// canonicalAddr returns the host:port a request to u is dialed at
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
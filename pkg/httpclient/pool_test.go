@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestClientMaxConnsPerHost(t *testing.T) {
+	is := is.New(t)
+	received := make(chan struct{}, 3)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{MaxConnsPerHost: 2, Interceptors: []string{InterceptorHeaders}}, nil, nil, nil)
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Post(context.Background(), srv.URL, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+
+	// The third request waits for one of the two connections
+	for range 2 {
+		<-received
+	}
+	select {
+	case <-received:
+		t.Fatal("request sent over a third connection")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	wg.Wait()
+
+	stats := c.PoolStats()
+	is.Equal(len(stats), 1)
+	is.Equal(stats[0].Open, int64(2))
+}
@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/dev-in-black/connector-http/internal/jsonpath"
+)
+
+// ErrBodyPredicateFailed is returned when a response body does not satisfy
+// the configured success predicate
+var ErrBodyPredicateFailed = errors.New("response body did not match success predicate")
+
+// BodyPredicateConfig holds the configuration of a success body predicate
+type BodyPredicateConfig struct {
+	Path   string
+	Value  string
+	Regex  string
+	Action Action
+}
+
+// BodyPredicate classifies successful responses as failures based on their body
+type BodyPredicate struct {
+	path   *jsonpath.Path
+	value  string
+	regex  *regexp.Regexp
+	action Action
+}
+
+// NewBodyPredicate creates a body predicate, returning nil if neither a path
+// nor a regex is configured
+func NewBodyPredicate(cfg BodyPredicateConfig) (*BodyPredicate, error) {
+	if cfg.Path == "" && cfg.Regex == "" {
+		return nil, nil
+	}
+
+	p := &BodyPredicate{
+		value:  cfg.Value,
+		action: cfg.Action,
+	}
+	if p.action == "" {
+		p.action = ActionRetry
+	}
+	switch p.action {
+	case ActionRetry, ActionFail, ActionDLQ:
+	default:
+		return nil, fmt.Errorf("invalid predicate action %q (must be retry, fail, or dlq)", cfg.Action)
+	}
+
+	if cfg.Path != "" {
+		path, err := jsonpath.Parse(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid predicate path: %w", err)
+		}
+		p.path = path
+	}
+
+	if cfg.Regex != "" {
+		re, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid predicate regex: %w", err)
+		}
+		p.regex = re
+	}
+
+	return p, nil
+}
+
+// Matches reports whether the body satisfies the predicate. When both a path
+// and a regex are configured, both must match.
+func (p *BodyPredicate) Matches(body []byte) bool {
+	if p.path != nil {
+		v, ok := p.path.Lookup(body)
+		if !ok || jsonpath.Stringify(v) != p.value {
+			return false
+		}
+	}
+	if p.regex != nil && !p.regex.Match(body) {
+		return false
+	}
+	return true
+}
+
+// Action returns the action applied when the predicate does not match
+func (p *BodyPredicate) Action() Action {
+	return p.action
+}
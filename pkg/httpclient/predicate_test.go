@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestBodyPredicateMatches(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config BodyPredicateConfig
+		body   string
+		want   bool
+	}{{
+		name:   "path matches",
+		config: BodyPredicateConfig{Path: "$.status", Value: "ok"},
+		body:   `{"status":"ok"}`,
+		want:   true,
+	}, {
+		name:   "path differs",
+		config: BodyPredicateConfig{Path: "$.status", Value: "ok"},
+		body:   `{"status":"error"}`,
+		want:   false,
+	}, {
+		name:   "path missing",
+		config: BodyPredicateConfig{Path: "$.status", Value: "ok"},
+		body:   `{"result":"ok"}`,
+		want:   false,
+	}, {
+		name:   "nested boolean",
+		config: BodyPredicateConfig{Path: "$.result.success", Value: "true"},
+		body:   `{"result":{"success":true}}`,
+		want:   true,
+	}, {
+		name:   "number",
+		config: BodyPredicateConfig{Path: "$.code", Value: "0"},
+		body:   `{"code":0}`,
+		want:   true,
+	}, {
+		name:   "not JSON",
+		config: BodyPredicateConfig{Path: "$.status", Value: "ok"},
+		body:   `status=ok`,
+		want:   false,
+	}, {
+		name:   "regex matches",
+		config: BodyPredicateConfig{Regex: `"errors":\s*\[\]`},
+		body:   `{"errors": []}`,
+		want:   true,
+	}, {
+		name:   "regex differs",
+		config: BodyPredicateConfig{Regex: `"errors":\s*\[\]`},
+		body:   `{"errors": ["invalid"]}`,
+		want:   false,
+	}, {
+		name:   "path and regex both match",
+		config: BodyPredicateConfig{Path: "$.status", Value: "ok", Regex: `"id":\d+`},
+		body:   `{"status":"ok","id":12}`,
+		want:   true,
+	}, {
+		name:   "path matches, regex doesn't",
+		config: BodyPredicateConfig{Path: "$.status", Value: "ok", Regex: `"id":\d+`},
+		body:   `{"status":"ok","id":"12"}`,
+		want:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			p, err := NewBodyPredicate(tc.config)
+			is.NoErr(err)
+			is.Equal(p.Matches([]byte(tc.body)), tc.want)
+		})
+	}
+}
+
+func TestNewBodyPredicate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		config     BodyPredicateConfig
+		wantNil    bool
+		wantAction Action
+		wantErr    bool
+	}{{
+		name:    "unset",
+		config:  BodyPredicateConfig{Value: "ok"},
+		wantNil: true,
+	}, {
+		name:       "retries by default",
+		config:     BodyPredicateConfig{Path: "$.status", Value: "ok"},
+		wantAction: ActionRetry,
+	}, {
+		name:       "dlq",
+		config:     BodyPredicateConfig{Regex: "ok", Action: ActionDLQ},
+		wantAction: ActionDLQ,
+	}, {
+		name:    "ack isn't a failure action",
+		config:  BodyPredicateConfig{Regex: "ok", Action: ActionAck},
+		wantErr: true,
+	}, {
+		name:    "invalid path",
+		config:  BodyPredicateConfig{Path: "status[", Value: "ok"},
+		wantErr: true,
+	}, {
+		name:    "invalid regex",
+		config:  BodyPredicateConfig{Regex: "("},
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			p, err := NewBodyPredicate(tc.config)
+			switch {
+			case tc.wantErr:
+				is.True(err != nil)
+			case tc.wantNil:
+				is.NoErr(err)
+				is.True(p == nil)
+			default:
+				is.NoErr(err)
+				is.Equal(p.Action(), tc.wantAction)
+			}
+		})
+	}
+}
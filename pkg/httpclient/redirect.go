@@ -0,0 +1,70 @@
+package httpclient
+
+import (
+	"net/http"
+	"strings"
+)
+
+// credentialHeaders are removed from requests following a redirect to
+// another origin unless cross-origin auth is allowed
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// RedirectConfig configures how redirect responses are followed. Redirects
+// that aren't followed are returned as the response of the request.
+type RedirectConfig struct {
+	Disabled bool // Never follow redirects
+	Max      int  // Maximum number of redirects followed per request, 0 means 10
+
+	// CrossOriginBody follows 307 and 308 redirects to another origin, which
+	// resend the request body
+	CrossOriginBody bool
+
+	// CrossOriginAuth keeps the credential headers on redirects to another
+	// origin. Otherwise they are removed, along with SensitiveHeaders.
+	CrossOriginAuth  bool
+	SensitiveHeaders []string
+}
+
+// checkRedirect implements http.Client.CheckRedirect. Unlike Go's default
+// policy, which strips credentials only when the domain changes, the origin
+// must match exactly to keep them.
+func (c RedirectConfig) checkRedirect(req *http.Request, via []*http.Request) error {
+	maxRedirects := c.Max
+	if maxRedirects <= 0 {
+		maxRedirects = 10
+	}
+	if c.Disabled || len(via) > maxRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	first := via[0]
+	if sameOrigin(first, req) {
+		return nil
+	}
+
+	if !c.CrossOriginBody && req.Body != nil && req.Body != http.NoBody {
+		return http.ErrUseLastResponse
+	}
+
+	if c.CrossOriginAuth {
+		// Go removes credentials when the domain changes, restore them
+		for _, name := range credentialHeaders {
+			if values, ok := first.Header[name]; ok {
+				req.Header[name] = values
+			}
+		}
+		return nil
+	}
+	for _, name := range credentialHeaders {
+		req.Header.Del(name)
+	}
+	for _, name := range c.SensitiveHeaders {
+		req.Header.Del(name)
+	}
+	return nil
+}
+
+// sameOrigin reports whether two requests have the same scheme, host and port
+func sameOrigin(a, b *http.Request) bool {
+	return strings.EqualFold(a.URL.Scheme, b.URL.Scheme) && strings.EqualFold(a.URL.Host, b.URL.Host)
+}
@@ -0,0 +1,136 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestRedirectMaxHops(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    RedirectConfig
+		wantCalls int32
+	}{
+		{name: "disabled", config: RedirectConfig{Disabled: true}, wantCalls: 1},
+		{name: "max", config: RedirectConfig{Max: 3}, wantCalls: 4},
+		{name: "default max", config: RedirectConfig{}, wantCalls: 11},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+
+			// The server redirects every request to itself
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				http.Redirect(w, r, "/next", http.StatusFound)
+			}))
+			defer srv.Close()
+
+			client := &http.Client{CheckRedirect: tc.config.checkRedirect}
+			resp, err := client.Get(srv.URL)
+			is.NoErr(err)
+			defer resp.Body.Close()
+
+			// The last redirect response is the response of the request
+			is.Equal(resp.StatusCode, http.StatusFound)
+			is.Equal(calls.Load(), tc.wantCalls)
+		})
+	}
+}
+
+func TestRedirectCredentials(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      RedirectConfig
+		crossOrigin bool
+		wantAuth    bool
+	}{
+		{name: "same origin", crossOrigin: false, wantAuth: true},
+		{name: "cross origin", crossOrigin: true, wantAuth: false},
+		{name: "cross origin auth", config: RedirectConfig{CrossOriginAuth: true}, crossOrigin: true, wantAuth: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+
+			var got http.Header
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+			}))
+			defer target.Close()
+
+			var origin *httptest.Server
+			origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/target" {
+					got = r.Header.Clone()
+					return
+				}
+				// Another port on the same host is another origin, which Go's
+				// default policy doesn't strip credentials for
+				location := origin.URL + "/target"
+				if tc.crossOrigin {
+					location = target.URL + "/target"
+				}
+				http.Redirect(w, r, location, http.StatusFound)
+			}))
+			defer origin.Close()
+
+			tc.config.SensitiveHeaders = []string{"X-Api-Key"}
+			client := &http.Client{CheckRedirect: tc.config.checkRedirect}
+			req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+			is.NoErr(err)
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("Cookie", "session=secret")
+			req.Header.Set("X-Api-Key", "secret")
+			req.Header.Set("X-Trace", "1")
+
+			resp, err := client.Do(req)
+			is.NoErr(err)
+			defer resp.Body.Close()
+			is.Equal(resp.StatusCode, http.StatusOK)
+
+			is.Equal(got.Get("Authorization") != "", tc.wantAuth)
+			is.Equal(got.Get("Cookie") != "", tc.wantAuth)
+			is.Equal(got.Get("X-Api-Key") != "", tc.wantAuth)
+			is.Equal(got.Get("X-Trace"), "1")
+		})
+	}
+}
+
+func TestRedirectCrossOriginBody(t *testing.T) {
+	testCases := []struct {
+		name       string
+		config     RedirectConfig
+		wantStatus int
+	}{
+		{name: "not followed", wantStatus: http.StatusTemporaryRedirect},
+		{name: "followed", config: RedirectConfig{CrossOriginBody: true}, wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+
+			target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			defer target.Close()
+			origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+			}))
+			defer origin.Close()
+
+			client := &http.Client{CheckRedirect: tc.config.checkRedirect}
+			resp, err := client.Post(origin.URL, "application/json", strings.NewReader(`{"id":1}`))
+			is.NoErr(err)
+			defer resp.Body.Close()
+			is.Equal(resp.StatusCode, tc.wantStatus)
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryConfig holds retry configuration
+type RetryConfig struct {
+	MaxRetries        int
+	MaxRetryDuration  time.Duration // Wall-clock budget across all attempts, 0 means unlimited
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+	RetryOn5xx        bool
+	RetryOn429        bool
+	RetryOnNetworkErr bool
+	StatusRules       StatusRules
+	BodyPredicate     *BodyPredicate
+	AtMostOnce        bool // Only retry attempts that were never processed by the server
+
+	// Reauth refreshes credentials after a 401 or 403 response, the request
+	// is then retried once without counting as a retry
+	Reauth      func(ctx context.Context) error
+	ReauthOn401 bool
+	ReauthOn403 bool
+
+	// Hooks observe the attempts, e.g. to record metrics or alert
+	Hooks RetryHooks
+}
+
+// RetryEngine handles retry logic with exponential backoff
+type RetryEngine struct {
+	config RetryConfig
+	hooks  RetryHooks
+}
+
+// NewRetryEngine creates a new retry engine
+func NewRetryEngine(cfg RetryConfig) *RetryEngine {
+	// Attempts are recorded in the stats of the request before the hooks
+	// configured see them
+	return &RetryEngine{config: cfg, hooks: MultiRetryHooks(statsHooks{}, cfg.Hooks)}
+}
+
+// Do executes the given function with retry logic
+func (r *RetryEngine) Do(ctx context.Context, fn func() (*http.Response, error)) (resp *http.Response, err error) {
+	var lastErr error
+	var lastResp *http.Response
+	reauthed := false
+	start := time.Now()
+
+	// Every attempt is reported to the hooks, and so is giving up
+	var backoff time.Duration
+	var attemptStart time.Time
+	var last Attempt
+	n := 0
+	record := func(resp *http.Response, err error) {
+		n++
+		last = Attempt{Start: attemptStart, Duration: time.Since(attemptStart), Backoff: backoff}
+		if resp != nil {
+			last.Status = resp.StatusCode
+		}
+		if err != nil {
+			last.Err = err.Error()
+		}
+		r.hooks.OnAttempt(ctx, n, last)
+	}
+	defer func() {
+		if err != nil {
+			r.hooks.OnGiveUp(ctx, n, err)
+		}
+	}()
+
+	for attempt := 0; attempt <= r.config.MaxRetries; attempt++ {
+		// Wait before retry (skip on first attempt)
+		if attempt > 0 {
+			backoff = r.calculateBackoff(attempt)
+
+			// Give up if the next attempt would start after the retry budget is spent
+			if r.config.MaxRetryDuration > 0 && time.Since(start)+backoff > r.config.MaxRetryDuration {
+				return r.budgetExceeded(lastResp, lastErr)
+			}
+			r.hooks.OnRetryScheduled(ctx, n, backoff, last)
+
+			select {
+			case <-time.After(backoff):
+				// Continue to retry
+			case <-ctx.Done():
+				return nil, categorize(ctx.Err(), nil)
+			}
+		}
+
+		// Execute the function
+		attemptStart = time.Now()
+		resp, err := fn()
+
+		// Rejected credentials may have expired, refresh them and try again once
+		if err == nil && !reauthed && r.needsReauth(resp) {
+			record(resp, nil)
+			reauthed = true
+			resp.Body.Close()
+			if err := r.config.Reauth(ctx); err != nil {
+				return nil, WithCategory(fmt.Errorf("re-authentication failed: %w", err), ErrAuth)
+			}
+			backoff, attemptStart = 0, time.Now()
+			resp, err = fn()
+		}
+
+		// Success case: 2xx status or a status explicitly acked by a rule
+		if err == nil && r.isSuccess(resp) {
+			matched, readErr := r.matchBody(resp)
+			if readErr != nil {
+				return nil, fmt.Errorf("failed to read response body: %w", readErr)
+			}
+			if matched {
+				record(resp, nil)
+				return resp, nil
+			}
+
+			// Successful status but the body signals an application-level error,
+			// the request was processed so it isn't resent with AtMostOnce
+			lastErr = fmt.Errorf("%w: status %d", ErrBodyPredicateFailed, resp.StatusCode)
+			lastResp = resp
+			record(resp, lastErr)
+			if r.config.BodyPredicate.Action() != ActionRetry || r.config.AtMostOnce {
+				return resp, lastErr
+			}
+			// The body is buffered by matchBody and stays readable in case this
+			// is the last response
+			continue
+		}
+
+		// Store last error and response
+		lastErr = err
+		lastResp = resp
+		record(resp, err)
+
+		// Check if error is retryable
+		if !r.isRetryable(err, resp) {
+			if resp != nil {
+				err := WithCategory(fmt.Errorf("non-retryable error: status %d", resp.StatusCode), ErrNonRetryableStatus)
+				return resp, categorize(err, resp)
+			}
+			return nil, categorize(fmt.Errorf("non-retryable error: %w", err), nil)
+		}
+
+		// Close response body to reuse connection
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+	}
+
+	// Max retries exceeded
+	if lastResp != nil {
+		if lastErr != nil {
+			return lastResp, categorize(fmt.Errorf("max retries (%d) exceeded, last status: %d: %w", r.config.MaxRetries, lastResp.StatusCode, lastErr), lastResp)
+		}
+		return lastResp, categorize(fmt.Errorf("max retries (%d) exceeded, last status: %d", r.config.MaxRetries, lastResp.StatusCode), lastResp)
+	}
+	return nil, categorize(fmt.Errorf("max retries (%d) exceeded: %w", r.config.MaxRetries, lastErr), nil)
+}
+
+// budgetExceeded builds the error returned when the retry budget is spent,
+// a timeout that also has the category of the last attempt
+func (r *RetryEngine) budgetExceeded(lastResp *http.Response, lastErr error) (*http.Response, error) {
+	var err error
+	switch {
+	case lastResp != nil && lastErr != nil:
+		err = fmt.Errorf("max retry duration (%s) exceeded, last status: %d: %w", r.config.MaxRetryDuration, lastResp.StatusCode, lastErr)
+	case lastResp != nil:
+		err = fmt.Errorf("max retry duration (%s) exceeded, last status: %d", r.config.MaxRetryDuration, lastResp.StatusCode)
+	default:
+		err = fmt.Errorf("max retry duration (%s) exceeded: %w", r.config.MaxRetryDuration, lastErr)
+	}
+	return lastResp, WithCategory(categorize(err, lastResp), ErrTimeout)
+}
+
+// needsReauth reports whether the response rejected the request's credentials
+func (r *RetryEngine) needsReauth(resp *http.Response) bool {
+	if r.config.Reauth == nil {
+		return false
+	}
+	return (r.config.ReauthOn401 && resp.StatusCode == http.StatusUnauthorized) ||
+		(r.config.ReauthOn403 && resp.StatusCode == http.StatusForbidden)
+}
+
+// calculateBackoff calculates exponential backoff duration
+func (r *RetryEngine) calculateBackoff(attempt int) time.Duration {
+	// Exponential backoff: 2^attempt * base
+	backoff := time.Duration(math.Pow(2, float64(attempt))) * r.config.BackoffBase
+
+	// Cap at maximum backoff
+	if backoff > r.config.BackoffMax {
+		backoff = r.config.BackoffMax
+	}
+
+	return backoff
+}
+
+// isSuccess determines if a response is considered successful
+func (r *RetryEngine) isSuccess(resp *http.Response) bool {
+	switch r.config.StatusRules.Classify(resp.StatusCode) {
+	case ActionAck, ActionIgnore:
+		return true
+	default:
+		return false
+	}
+}
+
+// matchBody evaluates the body predicate against the response, buffering the
+// body so it can still be read by the caller
+func (r *RetryEngine) matchBody(resp *http.Response) (bool, error) {
+	if r.config.BodyPredicate == nil || resp.Body == nil {
+		return true, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return false, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return r.config.BodyPredicate.Matches(body), nil
+}
+
+// IsAmbiguous reports whether a failed attempt may have been processed by the
+// server: the request was sent but failed with a server error or without a
+// conclusive response, e.g. a timeout or a connection reset
+func IsAmbiguous(err error, resp *http.Response) bool {
+	if resp != nil {
+		return resp.StatusCode >= 500 && resp.StatusCode < 600
+	}
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		return false
+	}
+
+	// Failing to connect means the request was never sent
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return false
+	}
+	return true
+}
+
+// IsOutage reports whether a failed request indicates that the endpoint is
+// unavailable rather than rejecting the request: it could not be reached,
+// timed out, or answered 429, 502, 503 or 504
+func IsOutage(err error, resp *http.Response) bool {
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// Refused targets fail the same way however often they are retried
+	if errors.Is(err, ErrTargetNotAllowed) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isRetryable determines if an error/response is retryable
+func (r *RetryEngine) isRetryable(err error, resp *http.Response) bool {
+	// Network errors are retryable if configured
+	if err != nil {
+		if errors.Is(err, ErrTargetNotAllowed) {
+			return false
+		}
+		// Without at-least-once delivery, only resend requests that never reached the server
+		if r.config.AtMostOnce && IsAmbiguous(err, resp) {
+			return false
+		}
+		if r.config.RetryOnNetworkErr {
+			// Check for net.Error (includes timeouts, connection errors),
+			// transport errors are wrapped by the client
+			var netErr net.Error
+			if errors.As(err, &netErr) {
+				return true
+			}
+		}
+		// Non-network errors are not retryable
+		return false
+	}
+
+	// HTTP status code based retryability
+	if resp != nil {
+		// Explicit status rules take precedence over the defaults
+		if action, ok := r.config.StatusRules.Match(resp.StatusCode); ok {
+			return action == ActionRetry
+		}
+
+		// 5xx errors (server errors) are retryable if configured, unless the
+		// request may have been processed and must not be resent
+		if r.config.RetryOn5xx && !r.config.AtMostOnce && resp.StatusCode >= 500 && resp.StatusCode < 600 {
+			return true
+		}
+
+		// 429 Too Many Requests is retryable if configured
+		if r.config.RetryOn429 && resp.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+
+		// 4xx errors (except 429) are client errors, not retryable
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return false
+		}
+	}
+
+	// Default: not retryable
+	return false
+}
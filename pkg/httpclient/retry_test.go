@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestRetryEngineIsRetryable(t *testing.T) {
+	// Transport errors reach the retry engine wrapped by the client
+	wrap := func(err error) error {
+		return fmt.Errorf("request failed: %w", &url.Error{Op: "Post", URL: "https://api.example.com", Err: err})
+	}
+	dialErr := wrap(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	readErr := wrap(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET})
+	guardErr := wrap(&net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("%w: 10.0.0.1 is a private address", ErrTargetNotAllowed)})
+	rules := StatusRules{
+		{Min: 409, Max: 409, Action: ActionRetry},
+		{Min: 503, Max: 503, Action: ActionFail},
+	}
+
+	testCases := []struct {
+		name   string
+		config RetryConfig
+		err    error
+		status int
+		want   bool
+	}{{
+		name:   "wrapped dial error",
+		config: RetryConfig{RetryOnNetworkErr: true},
+		err:    dialErr,
+		want:   true,
+	}, {
+		name:   "wrapped read error",
+		config: RetryConfig{RetryOnNetworkErr: true},
+		err:    readErr,
+		want:   true,
+	}, {
+		name:   "network errors disabled",
+		config: RetryConfig{},
+		err:    dialErr,
+		want:   false,
+	}, {
+		name:   "not a network error",
+		config: RetryConfig{RetryOnNetworkErr: true},
+		err:    errors.New("invalid request"),
+		want:   false,
+	}, {
+		name:   "refused target",
+		config: RetryConfig{RetryOnNetworkErr: true},
+		err:    guardErr,
+		want:   false,
+	}, {
+		name:   "at most once dial error",
+		config: RetryConfig{RetryOnNetworkErr: true, AtMostOnce: true},
+		err:    dialErr,
+		want:   true,
+	}, {
+		name:   "at most once read error",
+		config: RetryConfig{RetryOnNetworkErr: true, AtMostOnce: true},
+		err:    readErr,
+		want:   false,
+	}, {
+		name:   "5xx",
+		config: RetryConfig{RetryOn5xx: true},
+		status: http.StatusBadGateway,
+		want:   true,
+	}, {
+		name:   "5xx disabled",
+		config: RetryConfig{},
+		status: http.StatusBadGateway,
+		want:   false,
+	}, {
+		name:   "at most once 5xx",
+		config: RetryConfig{RetryOn5xx: true, AtMostOnce: true},
+		status: http.StatusBadGateway,
+		want:   false,
+	}, {
+		name:   "429",
+		config: RetryConfig{RetryOn429: true},
+		status: http.StatusTooManyRequests,
+		want:   true,
+	}, {
+		name:   "4xx",
+		config: RetryConfig{RetryOn5xx: true, RetryOn429: true},
+		status: http.StatusBadRequest,
+		want:   false,
+	}, {
+		name:   "rule retries 409",
+		config: RetryConfig{StatusRules: rules},
+		status: http.StatusConflict,
+		want:   true,
+	}, {
+		name:   "rule fails 503",
+		config: RetryConfig{RetryOn5xx: true, StatusRules: rules},
+		status: http.StatusServiceUnavailable,
+		want:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			var resp *http.Response
+			if tc.status != 0 {
+				resp = &http.Response{StatusCode: tc.status}
+			}
+			r := NewRetryEngine(tc.config)
+			is.Equal(r.isRetryable(tc.err, resp), tc.want)
+		})
+	}
+}
+
+func TestRetryEngineBodyPredicate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       RetryConfig
+		wantAttempts int
+	}{{
+		name:         "retried",
+		config:       RetryConfig{MaxRetries: 2},
+		wantAttempts: 3,
+	}, {
+		name:         "at most once",
+		config:       RetryConfig{MaxRetries: 2, AtMostOnce: true},
+		wantAttempts: 1,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			predicate, err := NewBodyPredicate(BodyPredicateConfig{Path: "$.status", Value: "ok"})
+			is.NoErr(err)
+			tc.config.BodyPredicate = predicate
+			r := NewRetryEngine(tc.config)
+
+			attempts := 0
+			resp, err := r.Do(context.Background(), func() (*http.Response, error) {
+				attempts++
+				body := fmt.Sprintf(`{"status":"error","attempt":%d}`, attempts)
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+			})
+
+			// The last response is returned with its body for the response sinks
+			is.True(errors.Is(err, ErrBodyPredicateFailed))
+			is.Equal(attempts, tc.wantAttempts)
+			body, err := io.ReadAll(resp.Body)
+			is.NoErr(err)
+			is.Equal(string(body), fmt.Sprintf(`{"status":"error","attempt":%d}`, tc.wantAttempts))
+		})
+	}
+}
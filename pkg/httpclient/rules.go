@@ -0,0 +1,184 @@
+package httpclient
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Action describes how a response with a given status code is handled
+type Action string
+
+const (
+	// ActionAck treats the response as a success
+	ActionAck Action = "ack"
+	// ActionRetry retries the request according to the retry policy
+	ActionRetry Action = "retry"
+	// ActionFail fails the record without retrying
+	ActionFail Action = "fail"
+	// ActionDLQ fails the record so that Conduit routes it to the DLQ
+	ActionDLQ Action = "dlq"
+	// ActionIgnore acks the record without publishing the response
+	ActionIgnore Action = "ignore"
+)
+
+var validActions = map[Action]bool{
+	ActionAck:    true,
+	ActionRetry:  true,
+	ActionFail:   true,
+	ActionDLQ:    true,
+	ActionIgnore: true,
+}
+
+// StatusRule maps an inclusive range of status codes to an action
+type StatusRule struct {
+	Min    int
+	Max    int
+	Action Action
+}
+
+// StatusRules is a set of status code rules, ordered from the most specific
+// (narrowest range) to the least specific
+type StatusRules []StatusRule
+
+// ParseStatusRules parses a map of status code patterns to actions.
+// Supported patterns are a single code ("409"), a class ("4xx") and an
+// inclusive range ("500-504"). Patterns covering the same range, e.g. "4xx"
+// and "400-499", are rejected as neither would take precedence.
+func ParseStatusRules(rules map[string]string) (StatusRules, error) {
+	parsed := make(StatusRules, 0, len(rules))
+	patterns := make(map[StatusRange]string, len(rules))
+	for pattern, action := range rules {
+		minCode, maxCode, err := parseStatusPattern(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, err
+		}
+		rng := StatusRange{Min: minCode, Max: maxCode}
+		if other, ok := patterns[rng]; ok {
+			// Map order is random, name the patterns in a stable order
+			first, second := min(other, pattern), max(other, pattern)
+			return nil, fmt.Errorf("status rules %q and %q cover the same status codes", first, second)
+		}
+		patterns[rng] = pattern
+
+		a := Action(strings.ToLower(strings.TrimSpace(action)))
+		if !validActions[a] {
+			return nil, fmt.Errorf("invalid action %q for status %q (must be ack, retry, fail, dlq, or ignore)", action, pattern)
+		}
+
+		parsed = append(parsed, StatusRule{Min: minCode, Max: maxCode, Action: a})
+	}
+
+	// Narrower ranges take precedence over wider ones, ranges are unique so
+	// the order doesn't depend on the order of the map
+	sort.Slice(parsed, func(i, j int) bool {
+		wi, wj := parsed[i].Max-parsed[i].Min, parsed[j].Max-parsed[j].Min
+		if wi != wj {
+			return wi < wj
+		}
+		return parsed[i].Min < parsed[j].Min
+	})
+
+	return parsed, nil
+}
+
+// Match returns the action of the most specific rule matching the status code
+func (r StatusRules) Match(statusCode int) (Action, bool) {
+	for _, rule := range r {
+		if statusCode >= rule.Min && statusCode <= rule.Max {
+			return rule.Action, true
+		}
+	}
+	return "", false
+}
+
+// Classify returns the action for the status code, falling back to the
+// default behavior (2xx is acked, everything else fails) if no rule matches
+func (r StatusRules) Classify(statusCode int) Action {
+	if action, ok := r.Match(statusCode); ok {
+		return action
+	}
+	if statusCode >= 200 && statusCode < 300 {
+		return ActionAck
+	}
+	return ActionFail
+}
+
+// StatusRange is an inclusive range of status codes
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// StatusRanges is a set of status codes, e.g. to select responses
+type StatusRanges []StatusRange
+
+// ParseStatusRanges parses status code patterns of the forms ParseStatusRules
+// supports
+func ParseStatusRanges(patterns []string) (StatusRanges, error) {
+	ranges := make(StatusRanges, 0, len(patterns))
+	for _, pattern := range patterns {
+		minCode, maxCode, err := parseStatusPattern(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, StatusRange{Min: minCode, Max: maxCode})
+	}
+	return ranges, nil
+}
+
+// Contains reports whether a range contains the status code
+func (r StatusRanges) Contains(statusCode int) bool {
+	for _, rng := range r {
+		if statusCode >= rng.Min && statusCode <= rng.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatusPattern parses a status code pattern into an inclusive range
+func parseStatusPattern(pattern string) (int, int, error) {
+	lower := strings.ToLower(pattern)
+
+	// Status class, e.g. 4xx
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") {
+		class, err := strconv.Atoi(lower[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, fmt.Errorf("invalid status class: %s", pattern)
+		}
+		return class * 100, class*100 + 99, nil
+	}
+
+	// Status range, e.g. 500-504
+	if from, to, ok := strings.Cut(lower, "-"); ok {
+		minCode, err := parseStatusCode(from)
+		if err != nil {
+			return 0, 0, err
+		}
+		maxCode, err := parseStatusCode(to)
+		if err != nil {
+			return 0, 0, err
+		}
+		if minCode > maxCode {
+			return 0, 0, fmt.Errorf("invalid status range: %s", pattern)
+		}
+		return minCode, maxCode, nil
+	}
+
+	code, err := parseStatusCode(lower)
+	if err != nil {
+		return 0, 0, err
+	}
+	return code, code, nil
+}
+
+// parseStatusCode parses a single HTTP status code
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("invalid status code: %s", s)
+	}
+	return code, nil
+}
@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParseStatusRules(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rules   map[string]string
+		want    StatusRules
+		wantErr bool
+	}{{
+		name:  "single code",
+		rules: map[string]string{"409": "ack"},
+		want:  StatusRules{{Min: 409, Max: 409, Action: ActionAck}},
+	}, {
+		name:  "class",
+		rules: map[string]string{"4xx": "fail"},
+		want:  StatusRules{{Min: 400, Max: 499, Action: ActionFail}},
+	}, {
+		name:  "range",
+		rules: map[string]string{"500-504": "retry"},
+		want:  StatusRules{{Min: 500, Max: 504, Action: ActionRetry}},
+	}, {
+		name:  "action and pattern are normalized",
+		rules: map[string]string{" 5XX ": " DLQ "},
+		want:  StatusRules{{Min: 500, Max: 599, Action: ActionDLQ}},
+	}, {
+		name:  "narrowest range first",
+		rules: map[string]string{"4xx": "fail", "404": "ignore", "400-409": "dlq"},
+		want: StatusRules{
+			{Min: 404, Max: 404, Action: ActionIgnore},
+			{Min: 400, Max: 409, Action: ActionDLQ},
+			{Min: 400, Max: 499, Action: ActionFail},
+		},
+	}, {
+		name:  "same width ordered by code",
+		rules: map[string]string{"502-503": "retry", "500-501": "fail", "501-502": "dlq"},
+		want: StatusRules{
+			{Min: 500, Max: 501, Action: ActionFail},
+			{Min: 501, Max: 502, Action: ActionDLQ},
+			{Min: 502, Max: 503, Action: ActionRetry},
+		},
+	}, {
+		name:    "same codes as class and range",
+		rules:   map[string]string{"4xx": "fail", "400-499": "dlq"},
+		wantErr: true,
+	}, {
+		name:    "same code twice",
+		rules:   map[string]string{"409": "ack", " 409": "fail"},
+		wantErr: true,
+	}, {
+		name:    "invalid action",
+		rules:   map[string]string{"409": "skip"},
+		wantErr: true,
+	}, {
+		name:    "invalid class",
+		rules:   map[string]string{"6xx": "ack"},
+		wantErr: true,
+	}, {
+		name:    "code out of range",
+		rules:   map[string]string{"99": "ack"},
+		wantErr: true,
+	}, {
+		name:    "reversed range",
+		rules:   map[string]string{"504-500": "retry"},
+		wantErr: true,
+	}, {
+		name:    "not a code",
+		rules:   map[string]string{"conflict": "ack"},
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			got, err := ParseStatusRules(tc.rules)
+			if tc.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(got, tc.want)
+		})
+	}
+}
+
+func TestStatusRulesClassify(t *testing.T) {
+	rules, err := ParseStatusRules(map[string]string{
+		"409":     "ack",
+		"404":     "ignore",
+		"4xx":     "dlq",
+		"500-503": "retry",
+		"204":     "fail",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		status int
+		want   Action
+	}{
+		{status: 200, want: ActionAck},
+		{status: 204, want: ActionFail},
+		{status: 301, want: ActionFail},
+		{status: 400, want: ActionDLQ},
+		{status: 404, want: ActionIgnore},
+		{status: 409, want: ActionAck},
+		{status: 499, want: ActionDLQ},
+		{status: 500, want: ActionRetry},
+		{status: 503, want: ActionRetry},
+		{status: 504, want: ActionFail},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strconv.Itoa(tc.status), func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(rules.Classify(tc.status), tc.want)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// streamKey is the context key marking streamed requests
+type streamKey struct{}
+
+// Stream sends a request whose body is read from r until it returns io.EOF,
+// chunked over a single long-lived connection. The client timeout doesn't
+// apply, the request lasts until the body ends or ctx is canceled. Streamed
+// bodies can't be captured, audited or retried.
+func (c *Client) Stream(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	ctx = context.WithValue(ctx, streamKey{}, true)
+	return c.roundTrip(ctx, method, url, body)
+}
+
+// streamFromContext reports whether the request is sent with Stream
+func streamFromContext(ctx context.Context) bool {
+	stream, _ := ctx.Value(streamKey{}).(bool)
+	return stream
+}
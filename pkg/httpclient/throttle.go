@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// maxThrottleChunk is the most bytes of a request body read at once while
+// throttled, so bodies are sent evenly rather than in bursts
+const maxThrottleChunk = 32 << 10
+
+// throttleTransport limits the bytes per second of the request bodies of all
+// requests sent, together
+type throttleTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+// newBandwidthLimiter returns a limiter of bytesPerSecond
+func newBandwidthLimiter(bytesPerSecond int64) *rate.Limiter {
+	burst := int(min(bytesPerSecond, maxThrottleChunk))
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// RoundTrip sends the request with its body throttled
+func (t throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.next.RoundTrip(req)
+	}
+	throttled := *req
+	throttled.Body = &throttledBody{ReadCloser: req.Body, ctx: req.Context(), limiter: t.limiter}
+	return t.next.RoundTrip(&throttled)
+}
+
+// throttledBody reads a request body as fast as its limiter allows
+type throttledBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+// Read reads up to a burst of the limiter, once it allows sending it
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > b.limiter.Burst() {
+		p = p[:b.limiter.Burst()]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := b.limiter.WaitN(b.ctx, n); waitErr != nil {
+			return 0, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,150 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Timing is the latency breakdown of a request attempt. Phases that didn't
+// happen, such as connecting on a reused connection, are zero.
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration // From sending the request to the first response byte
+}
+
+// maxAttempts is the number of attempts kept by RequestStats, the oldest
+// are dropped first
+const maxAttempts = 20
+
+// Attempt is an attempt of a request made by the retry engine
+type Attempt struct {
+	Start    time.Time
+	Duration time.Duration
+	Backoff  time.Duration // Waited before the attempt
+	Status   int           // 0 without response
+	Err      string        // Error of an attempt without response or a failed body predicate
+}
+
+// RequestStats collects the timing and the history of the attempts of a request
+type RequestStats struct {
+	mu       sync.Mutex
+	timing   Timing
+	attempts []Attempt
+	dropped  int
+}
+
+// statsKey is the context key of the stats of a request
+type statsKey struct{}
+
+// WithStats returns a context collecting the timing of the requests sent with it
+func WithStats(ctx context.Context) (context.Context, *RequestStats) {
+	stats := &RequestStats{}
+	return context.WithValue(ctx, statsKey{}, stats), stats
+}
+
+// Timing returns the timing of the last attempt that received a response,
+// zero for nil stats
+func (s *RequestStats) Timing() Timing {
+	if s == nil {
+		return Timing{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timing
+}
+
+// record stores the timing of an attempt
+func (s *RequestStats) record(t Timing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timing = t
+}
+
+// Attempts returns the history of the attempts made by the retry engine, up to
+// the last 20, and the number of older attempts that were dropped
+func (s *RequestStats) Attempts() ([]Attempt, int) {
+	if s == nil {
+		return nil, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.attempts), s.dropped
+}
+
+// addAttempt appends an attempt to the history, nil stats ignore it
+func (s *RequestStats) addAttempt(a Attempt) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.attempts) == maxAttempts {
+		s.attempts = slices.Delete(s.attempts, 0, 1)
+		s.dropped++
+	}
+	s.attempts = append(s.attempts, a)
+}
+
+// statsFromContext returns the stats stored by WithStats
+func statsFromContext(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(statsKey{}).(*RequestStats)
+	return stats
+}
+
+// requestTracer measures the phases of a request with httptrace. Its hooks
+// may be called concurrently, e.g. when dialing several addresses.
+type requestTracer struct {
+	mu                  sync.Mutex
+	start               time.Time
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	firstByte           time.Time
+}
+
+// trace returns a context tracing the request into t
+func (t *requestTracer) trace(ctx context.Context) context.Context {
+	at := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn:              func(string) { at(&t.start) },
+		DNSStart:             func(httptrace.DNSStartInfo) { at(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { at(&t.dnsDone) },
+		ConnectStart:         func(string, string) { at(&t.connStart) },
+		ConnectDone:          func(string, string, error) { at(&t.connDone) },
+		TLSHandshakeStart:    func() { at(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { at(&t.tlsDone) },
+		GotFirstResponseByte: func() { at(&t.firstByte) },
+	})
+}
+
+// timing returns the durations of the phases that completed
+func (t *requestTracer) timing() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Timing{
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connStart, t.connDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		TTFB:    between(t.start, t.firstByte),
+	}
+}
+
+// between returns the time from start to end, zero unless both happened
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}